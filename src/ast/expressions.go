@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -21,11 +22,17 @@ func (i *Identifier) String() string       { return i.Value }
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int // Set instead of Value when the literal overflows an int64
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
-func (il *IntegerLiteral) String() string       { return strconv.FormatInt(il.Value, 10) }
+func (il *IntegerLiteral) String() string {
+	if il.Big != nil {
+		return il.Big.String()
+	}
+	return strconv.FormatInt(il.Value, 10)
+}
 
 type FloatLiteral struct {
 	Token token.Token
@@ -36,6 +43,27 @@ func (fl *FloatLiteral) expressionNode()      {}
 func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FloatLiteral) String() string       { return strconv.FormatFloat(fl.Value, 'f', -1, 64) }
 
+// DecimalLiteral is a number written with a 'd' suffix, such as 19.99d.
+// Value holds the digits without the suffix.
+type DecimalLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (dl *DecimalLiteral) expressionNode()      {}
+func (dl *DecimalLiteral) TokenLiteral() string { return dl.Token.Literal }
+func (dl *DecimalLiteral) String() string       { return dl.Value + "d" }
+
+// BytesLiteral is a b"..." literal with its escapes already resolved.
+type BytesLiteral struct {
+	Token token.Token
+	Value []byte
+}
+
+func (bl *BytesLiteral) expressionNode()      {}
+func (bl *BytesLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BytesLiteral) String() string       { return fmt.Sprintf("b%q", bl.Value) }
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -97,6 +125,23 @@ func (ce *CallExpression) String() string {
 }
 
 // NamedArgument represents a keyword argument like `name=value` in function calls
+// SpreadArgument unpacks a value into a call's arguments: `*xs` spreads an
+// iterable as positional arguments, `**opts` spreads a hash as named ones.
+type SpreadArgument struct {
+	Token   token.Token // The '*' or '**' token
+	Value   Expression
+	Keyword bool
+}
+
+func (sa *SpreadArgument) expressionNode()      {}
+func (sa *SpreadArgument) TokenLiteral() string { return sa.Token.Literal }
+func (sa *SpreadArgument) String() string {
+	if sa.Keyword {
+		return "**" + sa.Value.String()
+	}
+	return "*" + sa.Value.String()
+}
+
 type NamedArgument struct {
 	Token token.Token // The IDENT token (parameter name)
 	Name  *Identifier // The parameter name
@@ -123,10 +168,13 @@ func (b *Boolean) TokenLiteral() string {
 }
 func (b *Boolean) String() string { return b.TokenLiteral() }
 
+// FunctionLiteral is an anonymous spell expression, e.g. `spell(x): x * 2`.
 type FunctionLiteral struct {
-	Token      token.Token
-	Parameters []*Identifier
-	Body       *BlockStatement
+	Token       token.Token
+	Parameters  []Expression // Identifier or Parameter nodes
+	ReturnType  Expression
+	Body        *BlockStatement
+	IsGenerator bool
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -140,7 +188,12 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") ")
+	out.WriteString(")")
+	if fl.ReturnType != nil {
+		out.WriteString(" -> ")
+		out.WriteString(fl.ReturnType.String())
+	}
+	out.WriteString(": ")
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
@@ -196,6 +249,7 @@ type SliceExpression struct {
 	Left  Expression
 	Start Expression
 	End   Expression
+	Step  Expression // nil unless written as xs[start:end:step]
 }
 
 func (se *SliceExpression) expressionNode()      {}
@@ -212,6 +266,10 @@ func (se *SliceExpression) String() string {
 	if se.End != nil {
 		out.WriteString(se.End.String())
 	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
 	out.WriteString("])")
 	return out.String()
 }
@@ -219,6 +277,7 @@ func (se *SliceExpression) String() string {
 type HashLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+	Keys  []Expression // keys of Pairs in source order
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -227,8 +286,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -236,6 +295,125 @@ func (hl *HashLiteral) String() string {
 	return out.String()
 }
 
+// SetLiteral represents `{a, b, c}`. An empty `{}` is always a hash.
+type SetLiteral struct {
+	Token    token.Token // The '{' token
+	Elements []Expression
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string {
+	elements := []string{}
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+	return "{" + strings.Join(elements, ", ") + "}"
+}
+
+// ConditionalExpression represents `consequence if condition else alternative`.
+type ConditionalExpression struct {
+	Token       token.Token // The 'if' token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	return fmt.Sprintf("(%s if %s else %s)",
+		ce.Consequence.String(), ce.Condition.String(), ce.Alternative.String())
+}
+
+// ComprehensionClause is one `for target in iterable [if cond ...]` clause
+// of a list or hash comprehension.
+type ComprehensionClause struct {
+	Token      token.Token // The 'for' token
+	Variable   Expression  // Identifier or TupleLiteral of identifiers
+	Iterable   Expression
+	Conditions []Expression
+}
+
+func (cc *ComprehensionClause) String() string {
+	var out bytes.Buffer
+	out.WriteString("for ")
+	out.WriteString(cc.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(cc.Iterable.String())
+	for _, cond := range cc.Conditions {
+		out.WriteString(" if ")
+		out.WriteString(cond.String())
+	}
+	return out.String()
+}
+
+// ListComprehension represents `[element for x in xs if cond]`.
+type ListComprehension struct {
+	Token   token.Token // The '[' token
+	Element Expression
+	Clauses []*ComprehensionClause
+}
+
+func (lc *ListComprehension) expressionNode()      {}
+func (lc *ListComprehension) TokenLiteral() string { return lc.Token.Literal }
+func (lc *ListComprehension) String() string {
+	var out bytes.Buffer
+	out.WriteString("[")
+	out.WriteString(lc.Element.String())
+	for _, clause := range lc.Clauses {
+		out.WriteString(" ")
+		out.WriteString(clause.String())
+	}
+	out.WriteString("]")
+	return out.String()
+}
+
+// HashComprehension represents `{key: value for k, v in pairs(h)}`.
+type HashComprehension struct {
+	Token   token.Token // The '{' token
+	Key     Expression
+	Value   Expression
+	Clauses []*ComprehensionClause
+}
+
+func (hc *HashComprehension) expressionNode()      {}
+func (hc *HashComprehension) TokenLiteral() string { return hc.Token.Literal }
+func (hc *HashComprehension) String() string {
+	var out bytes.Buffer
+	out.WriteString("{")
+	out.WriteString(hc.Key.String())
+	out.WriteString(": ")
+	out.WriteString(hc.Value.String())
+	for _, clause := range hc.Clauses {
+		out.WriteString(" ")
+		out.WriteString(clause.String())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// SetComprehension represents `{element for x in xs if cond}`.
+type SetComprehension struct {
+	Token   token.Token // The '{' token
+	Element Expression
+	Clauses []*ComprehensionClause
+}
+
+func (sc *SetComprehension) expressionNode()      {}
+func (sc *SetComprehension) TokenLiteral() string { return sc.Token.Literal }
+func (sc *SetComprehension) String() string {
+	var out bytes.Buffer
+	out.WriteString("{")
+	out.WriteString(sc.Element.String())
+	for _, clause := range sc.Clauses {
+		out.WriteString(" ")
+		out.WriteString(clause.String())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
 type TupleLiteral struct {
 	Token    token.Token
 	Elements []Expression
@@ -294,6 +472,8 @@ func (ft *FStringText) String() string { return ft.Value }
 
 type FStringExpr struct {
 	Expr Expression
+	// Format holds the specifier after ':' (as in {price:.2f}), or nil.
+	Format *StringExpr
 }
 
 func (fe *FStringExpr) partNode() {}
@@ -374,3 +554,19 @@ type WildcardExpression struct {
 func (we *WildcardExpression) expressionNode()      {}
 func (we *WildcardExpression) TokenLiteral() string { return we.Token.Literal }
 func (we *WildcardExpression) String() string       { return "_" }
+
+// RestPattern captures the remaining elements of a sequence pattern,
+// e.g. `*rest` in `case [first, *rest]:`. Name is nil for `*_`.
+type RestPattern struct {
+	Token token.Token // The '*' token
+	Name  *Identifier
+}
+
+func (rp *RestPattern) expressionNode()      {}
+func (rp *RestPattern) TokenLiteral() string { return rp.Token.Literal }
+func (rp *RestPattern) String() string {
+	if rp.Name == nil {
+		return "*_"
+	}
+	return "*" + rp.Name.String()
+}
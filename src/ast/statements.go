@@ -162,6 +162,8 @@ type Parameter struct {
 	Name         *Identifier
 	TypeHint     Expression
 	DefaultValue Expression
+	Variadic     bool // *args: collects extra positional arguments
+	KwVariadic   bool // **kwargs: collects extra named arguments
 }
 
 func (p *Parameter) expressionNode()      {}
@@ -169,6 +171,11 @@ func (p *Parameter) TokenLiteral() string { return "Parameter" }
 
 func (p *Parameter) String() string {
 	var out strings.Builder
+	if p.Variadic {
+		out.WriteString("*")
+	} else if p.KwVariadic {
+		out.WriteString("**")
+	}
 	out.WriteString(p.Name.String())
 	
 	if p.TypeHint != nil {
@@ -191,6 +198,9 @@ type FunctionDefinition struct {
 	ReturnType Expression
 	Body       *BlockStatement
 	DocString  *StringLiteral
+	Decorators []Expression // `@decorator` lines, outermost first
+	// IsGenerator is set when the body contains a yield statement
+	IsGenerator bool
 }
 
 func (fd *FunctionDefinition) statementNode()       {}
@@ -203,6 +213,9 @@ func (fd *FunctionDefinition) String() string {
 		params = append(params, p.String())
 	}
 
+	for _, d := range fd.Decorators {
+		out.WriteString("@" + d.String() + "\n")
+	}
 	out.WriteString(fd.TokenLiteral() + " ")
 	out.WriteString(fd.Name.String())
 	out.WriteString("(")
@@ -244,6 +257,21 @@ type GrimoireDefinition struct {
 	Methods    []*FunctionDefinition
 	InitMethod *FunctionDefinition
 	DocString  *StringLiteral
+	Members    []*EnumMember // NAME or NAME = value lines, used by enums
+}
+
+// EnumMember declares a member in the body of an enum grimoire. Value is
+// nil for a bare NAME, which is numbered automatically.
+type EnumMember struct {
+	Name  *Identifier
+	Value Expression
+}
+
+func (em *EnumMember) String() string {
+	if em.Value == nil {
+		return em.Name.String()
+	}
+	return em.Name.String() + " = " + em.Value.String()
 }
 
 func (sb *GrimoireDefinition) statementNode()       {}
@@ -254,6 +282,11 @@ func (sb *GrimoireDefinition) String() string {
 	out.WriteString(sb.Name.String())
 	out.WriteString(":\n")
 
+	for _, member := range sb.Members {
+		out.WriteString("    ")
+		out.WriteString(member.String())
+		out.WriteString("\n")
+	}
 	if sb.InitMethod != nil {
 		out.WriteString("    ")
 		out.WriteString(sb.InitMethod.String())
@@ -316,7 +349,8 @@ func (ms *MatchStatement) String() string {
 
 type CaseClause struct {
 	Token     token.Token
-	Condition Expression
+	Condition Expression // The pattern
+	Guard     Expression // Optional `if` guard
 	Body      *BlockStatement
 }
 
@@ -326,6 +360,10 @@ func (cc *CaseClause) String() string {
 	var out bytes.Buffer
 	out.WriteString("case ")
 	out.WriteString(cc.Condition.String())
+	if cc.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(cc.Guard.String())
+	}
 	out.WriteString(":\n")
 	out.WriteString(cc.Body.String())
 	return out.String()
@@ -500,12 +538,16 @@ func (ds *DivergeStatement) String() string {
 }
 
 type ConvergeStatement struct {
-	Token   token.Token   // the 'converge' token
-	Names   []Expression  // optional names of goroutines to wait for
-	Timeout Expression    // optional timeout
+	Token        token.Token  // the 'converge' token
+	Names        []Expression // optional names of goroutines to wait for
+	Timeout      Expression   // optional timeout in seconds
+	AsExpression bool         // true when the results are used as a value
 }
 
+// ConvergeStatement is also an expression so that `results = converge a, b`
+// can collect what the goroutines returned.
 func (cs *ConvergeStatement) statementNode()       {}
+func (cs *ConvergeStatement) expressionNode()      {}
 func (cs *ConvergeStatement) TokenLiteral() string { return cs.Token.Literal }
 func (cs *ConvergeStatement) String() string {
 	var out bytes.Buffer
@@ -519,6 +561,72 @@ func (cs *ConvergeStatement) String() string {
 			out.WriteString(name.String())
 		}
 	}
+	if cs.Timeout != nil {
+		out.WriteString(" timeout ")
+		out.WriteString(cs.Timeout.String())
+	}
+	return out.String()
+}
+
+// SelectStatement waits on several channel operations and runs the body of
+// the first one that can proceed. Default, written `case _:`, runs when no
+// operation is ready, which makes the select non-blocking.
+type SelectStatement struct {
+	Token   token.Token // the 'select' token
+	Cases   []*SelectCase
+	Default *BlockStatement
+}
+
+func (ss *SelectStatement) statementNode()       {}
+func (ss *SelectStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SelectStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("select:\n")
+	for _, c := range ss.Cases {
+		out.WriteString(c.String())
+	}
+	if ss.Default != nil {
+		out.WriteString("case _:\n")
+		out.WriteString(ss.Default.String())
+	}
+	return out.String()
+}
+
+// Kinds of select case
+const (
+	SelectReceive = "receive" // case [target =] ch.receive():
+	SelectSend    = "send"    // case ch.send(value):
+	SelectTimeout = "timeout" // case timeout(seconds):
+)
+
+// SelectCase is one arm of a select statement. Channel is unused for a
+// timeout, whose Value is the number of seconds to wait.
+type SelectCase struct {
+	Token   token.Token // the 'case' token
+	Kind    string
+	Channel Expression
+	Value   Expression
+	Target  Expression // optional binding for a received value
+	Body    *BlockStatement
+}
+
+func (sc *SelectCase) TokenLiteral() string { return sc.Token.Literal }
+func (sc *SelectCase) String() string {
+	var out bytes.Buffer
+	out.WriteString("case ")
+	switch sc.Kind {
+	case SelectReceive:
+		if sc.Target != nil {
+			out.WriteString(sc.Target.String() + " = ")
+		}
+		out.WriteString(sc.Channel.String() + ".receive()")
+	case SelectSend:
+		out.WriteString(sc.Channel.String() + ".send(" + sc.Value.String() + ")")
+	case SelectTimeout:
+		out.WriteString("timeout(" + sc.Value.String() + ")")
+	}
+	out.WriteString(":\n")
+	out.WriteString(sc.Body.String())
 	return out.String()
 }
 
@@ -577,7 +685,7 @@ func (gs *GlobalStatement) String() string {
 type WithStatement struct {
 	Token      token.Token      // The 'autoclose' token
 	Expression Expression       // The expression that returns a resource
-	Variable   *Identifier      // The variable to bind the resource to
+	Variable   *Identifier      // The variable to bind the resource to, if any
 	Body       *BlockStatement  // The body to execute
 }
 
@@ -587,8 +695,10 @@ func (ws *WithStatement) String() string {
 	var out strings.Builder
 	out.WriteString("autoclose ")
 	out.WriteString(ws.Expression.String())
-	out.WriteString(" as ")
-	out.WriteString(ws.Variable.String())
+	if ws.Variable != nil {
+		out.WriteString(" as ")
+		out.WriteString(ws.Variable.String())
+	}
 	out.WriteString(":\n")
 	out.WriteString(ws.Body.String())
 	return out.String()
@@ -617,3 +727,18 @@ func (us *UnpackStatement) String() string {
 	
 	return out.String()
 }
+
+// YieldStatement suspends a generator spell, handing Value to the consumer.
+type YieldStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ys *YieldStatement) statementNode()       {}
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) String() string {
+	if ys.Value == nil {
+		return "yield"
+	}
+	return "yield " + ys.Value.String()
+}
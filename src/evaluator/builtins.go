@@ -2,11 +2,16 @@ package evaluator
 
 import (
 	"bufio"
-	"encoding/json"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"math/big"
 	"os"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/peterh/liner"
 
@@ -42,13 +47,26 @@ var builtins = map[string]*object.Builtin{
 			}
 			switch arg := args[0].(type) {
 			case *object.String:
-				return object.NewInteger(int64(len(arg.Value)))
+				return object.NewInteger(int64(utf8.RuneCountInString(arg.Value)))
 			case *object.Array:
-				return object.NewInteger(int64(len(arg.Elements)))
+				return object.NewInteger(int64(arg.Len()))
 			case *object.Tuple:
 				return object.NewInteger(int64(len(arg.Elements)))
 			case *object.Hash:
-				return object.NewInteger(int64(len(arg.Pairs)))
+				return object.NewInteger(int64(arg.Len()))
+			case *object.Set:
+				return object.NewInteger(int64(arg.Len()))
+			case *object.Bytes:
+				return object.NewInteger(int64(len(arg.Value)))
+			case *object.ByteArray:
+				return object.NewInteger(int64(len(arg.Value)))
+			case *object.Channel:
+				return object.NewInteger(int64(arg.Len()))
+			case *object.Grimoire:
+				if arg.IsEnum {
+					return object.NewInteger(int64(len(arg.EnumMembers)))
+				}
+				return newError("len() not supported for grimoire %s", arg.Name)
 			case *object.Instance:
 				// Handle instances based on their grimoire type
 				switch arg.Grimoire.Name {
@@ -80,7 +98,7 @@ var builtins = map[string]*object.Builtin{
 				case "String":
 					if value, exists := arg.Env.Get("value"); exists {
 						if str, isString := value.(*object.String); isString {
-							return object.NewInteger(int64(len(str.Value)))
+							return object.NewInteger(int64(utf8.RuneCountInString(str.Value)))
 						}
 					}
 					return newError("invalid String instance: missing value")
@@ -146,7 +164,7 @@ var builtins = map[string]*object.Builtin{
 				if hash, ok := args[0].(*object.Hash); ok {
 					// Look for "end" key in the hash
 					endKey := &object.String{Value: "end"}
-					if endPair, exists := hash.Pairs[endKey.HashKey()]; exists {
+					if endPair, exists := hash.Get(endKey); exists {
 						if endStr, ok := extractStringBuiltin(endPair.Value); ok {
 							endChar = endStr
 						}
@@ -154,7 +172,7 @@ var builtins = map[string]*object.Builtin{
 					
 					// Look for "values" key in the hash for the actual values to print
 					valuesKey := &object.String{Value: "values"}
-					if valuesPair, exists := hash.Pairs[valuesKey.HashKey()]; exists {
+					if valuesPair, exists := hash.Get(valuesKey); exists {
 						if array, ok := valuesPair.Value.(*object.Array); ok {
 							printArgs = array.Elements
 						} else {
@@ -230,10 +248,18 @@ var builtins = map[string]*object.Builtin{
 					return &object.String{Value: o.Grimoire.Name}
 				}
 				return &object.String{Value: "instance"}
-			case *object.Integer:
+			case *object.Integer, *object.BigInteger:
 				return &object.String{Value: "Integer"}
 			case *object.Float:
 				return &object.String{Value: "Float"}
+			case *object.Decimal:
+				return &object.String{Value: "Decimal"}
+			case *object.Set:
+				return &object.String{Value: "Set"}
+			case *object.Bytes:
+				return &object.String{Value: "Bytes"}
+			case *object.ByteArray:
+				return &object.String{Value: "ByteArray"}
 			case *object.String:
 				return &object.String{Value: "String"}
 			case *object.Boolean:
@@ -271,20 +297,7 @@ var builtins = map[string]*object.Builtin{
 				}
 			}
 			
-			switch typedArg := arg.(type) {
-			case *object.String:
-				value, err := strconv.Atoi(typedArg.Value)
-				if err != nil {
-					return newError("cannot convert string to int: %s", err)
-				}
-				return object.NewInteger(int64(value))
-			case *object.Float:
-				return object.NewInteger(int64(typedArg.Value))
-			case *object.Integer:
-				return typedArg
-			default:
-				return newError("cannot convert %s to int", arg.Type())
-			}
+			return convertToInt(arg)
 		},
 	},
 	"to_int": {
@@ -301,20 +314,7 @@ var builtins = map[string]*object.Builtin{
 				}
 			}
 			
-			switch typedArg := arg.(type) {
-			case *object.String:
-				value, err := strconv.Atoi(typedArg.Value)
-				if err != nil {
-					return newError("cannot convert string to int: %s", err)
-				}
-				return object.NewInteger(int64(value))
-			case *object.Float:
-				return object.NewInteger(int64(typedArg.Value))
-			case *object.Integer:
-				return typedArg
-			default:
-				return newError("cannot convert %s to int", arg.Type())
-			}
+			return convertToInt(arg)
 		},
 	},
 
@@ -341,6 +341,11 @@ var builtins = map[string]*object.Builtin{
 				return &object.Float{Value: value}
 			case *object.Integer:
 				return &object.Float{Value: float64(typedArg.Value)}
+			case *object.BigInteger:
+				value, _ := new(big.Float).SetInt(typedArg.Value).Float64()
+				return &object.Float{Value: value}
+			case *object.Decimal:
+				return &object.Float{Value: typedArg.Float64()}
 			case *object.Float:
 				return typedArg
 			default:
@@ -356,7 +361,7 @@ var builtins = map[string]*object.Builtin{
 			
 			// For Instance objects, try to get the underlying primitive value first
 			arg := args[0]
-			if instance, ok := arg.(*object.Instance); ok {
+			if instance, ok := arg.(*object.Instance); ok && !instance.Grimoire.IsEnum {
 				if value, exists := instance.Env.Get("value"); exists {
 					// Use the underlying primitive's string representation
 					primitive := &object.String{Value: value.Inspect()}
@@ -419,7 +424,7 @@ var builtins = map[string]*object.Builtin{
 				}
 				return TRUE
 			case *object.Hash:
-				if len(arg.Pairs) == 0 {
+				if arg.Len() == 0 {
 					return FALSE
 				}
 				return TRUE
@@ -437,13 +442,26 @@ var builtins = map[string]*object.Builtin{
 			}
 			switch arg := args[0].(type) {
 			case *object.String:
-				elements := make([]object.Object, len(arg.Value))
-				for i, char := range arg.Value {
-					elements[i] = &object.String{Value: string(char)}
+				elements := make([]object.Object, 0, utf8.RuneCountInString(arg.Value))
+				for _, char := range arg.Value {
+					elements = append(elements, &object.String{Value: string(char)})
 				}
 				return &object.Array{Elements: elements}
 			case *object.Tuple:
 				return &object.Array{Elements: arg.Elements}
+			case *object.Set:
+				return &object.Array{Elements: arg.Items()}
+			case *object.Bytes:
+				return &object.Array{Elements: byteElements(arg.Value)}
+			case *object.ByteArray:
+				return &object.Array{Elements: byteElements(arg.Value)}
+			case *object.Channel:
+				return &object.Array{Elements: drainChannel(arg)}
+			case *object.Grimoire:
+				if arg.IsEnum {
+					return &object.Array{Elements: enumMembers(arg)}
+				}
+				return newError("cannot convert %s to list", arg.Type())
 			default:
 				return newError("cannot convert %s to list", arg.Type())
 			}
@@ -459,6 +477,8 @@ var builtins = map[string]*object.Builtin{
 				return &object.Tuple{Elements: arg.Elements}
 			case *object.Tuple:
 				return arg
+			case *object.Set:
+				return &object.Tuple{Elements: arg.Items()}
 			default:
 				return newError("cannot convert %s to tuple", arg.Type())
 			}
@@ -554,6 +574,163 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 
+	"Decimal": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("Decimal requires exactly one argument, got %d", len(args))
+			}
+			arg := args[0]
+			if instance, ok := arg.(*object.Instance); ok {
+				if value, exists := instance.Env.Get("value"); exists {
+					arg = value
+				}
+			}
+			if str, ok := arg.(*object.String); ok {
+				value, ok := object.ParseDecimal(str.Value)
+				if !ok {
+					return newError("cannot convert string to Decimal: %q", str.Value)
+				}
+				return value
+			}
+			value, ok := object.ToDecimal(arg)
+			if !ok {
+				return newError("cannot convert %s to Decimal", arg.Inspect())
+			}
+			return value
+		},
+	},
+	"bytes": {
+		Fn: func(args ...object.Object) object.Object {
+			data, errObj := byteDataFromArgs("bytes", args)
+			if errObj != nil {
+				return errObj
+			}
+			return &object.Bytes{Value: data}
+		},
+	},
+	"bytearray": {
+		Fn: func(args ...object.Object) object.Object {
+			data, errObj := byteDataFromArgs("bytearray", args)
+			if errObj != nil {
+				return errObj
+			}
+			return &object.ByteArray{Value: data}
+		},
+	},
+	"channel": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return newError("channel takes at most one argument (the buffer capacity), got %d", len(args))
+			}
+			capacity := int64(0)
+			if len(args) == 1 {
+				size, ok := unwrapPrimitive(args[0]).(*object.Integer)
+				if !ok {
+					return newError("channel capacity must be an integer, got %s", args[0].Type())
+				}
+				if size.Value < 0 {
+					return newError("channel capacity cannot be negative, got %d", size.Value)
+				}
+				capacity = size.Value
+			}
+			return object.NewChannel(int(capacity))
+		},
+	},
+	"goroutine": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("goroutine takes exactly one argument (the goroutine name), got %d", len(args))
+			}
+			name, ok := unwrapPrimitive(args[0]).(*object.String)
+			if !ok {
+				return newError("goroutine name must be a string, got %s", args[0].Type())
+			}
+			goroutine, exists := globalGoroutineManager.GetNamedGoroutine(name.Value)
+			if !exists {
+				return newError("goroutine '%s' not found", name.Value)
+			}
+			return goroutine
+		},
+	},
+	"goroutines": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError("goroutines takes no arguments, got %d", len(args))
+			}
+			counts := globalGoroutineManager.StatusCounts()
+			stats := object.NewHash()
+			for _, status := range []string{"running", "completed", "failed", "cancelled"} {
+				stats.Put(&object.String{Value: status}, object.NewInteger(int64(counts[status])))
+			}
+			var names []object.Object
+			for _, name := range globalGoroutineManager.NamedGoroutineNames() {
+				names = append(names, &object.String{Value: name})
+			}
+			stats.Put(&object.String{Value: "names"}, &object.Array{Elements: names})
+			return stats
+		},
+	},
+	"bytesFromHex": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("bytesFromHex requires exactly one argument, got %d", len(args))
+			}
+			str, ok := unwrapPrimitive(args[0]).(*object.String)
+			if !ok {
+				return newError("bytesFromHex argument must be a string, got %s", args[0].Type())
+			}
+			data, err := hex.DecodeString(str.Value)
+			if err != nil {
+				return newError("bytesFromHex: invalid hex string: %s", err)
+			}
+			return &object.Bytes{Value: data}
+		},
+	},
+	"bytesFromBase64": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("bytesFromBase64 requires exactly one argument, got %d", len(args))
+			}
+			str, ok := unwrapPrimitive(args[0]).(*object.String)
+			if !ok {
+				return newError("bytesFromBase64 argument must be a string, got %s", args[0].Type())
+			}
+			data, err := base64.StdEncoding.DecodeString(str.Value)
+			if err != nil {
+				return newError("bytesFromBase64: invalid base64 string: %s", err)
+			}
+			return &object.Bytes{Value: data}
+		},
+	},
+	"decimalContext": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 2 {
+				return newError("decimalContext takes at most 2 arguments, got %d", len(args))
+			}
+			precision, rounding := object.DecimalContext()
+			if len(args) > 0 {
+				p, ok := args[0].(*object.Integer)
+				if !ok {
+					return newError("decimalContext precision must be an integer, got %s", args[0].Type())
+				}
+				precision = int(p.Value)
+			}
+			if len(args) > 1 {
+				mode, ok := args[1].(*object.String)
+				if !ok {
+					return newError("decimalContext rounding must be a string, got %s", args[1].Type())
+				}
+				rounding = mode.Value
+			}
+			if err := object.SetDecimalContext(precision, rounding); err != nil {
+				return newError("decimalContext: %s", err)
+			}
+			return newKeywordHash(map[string]object.Object{
+				"precision": object.NewInteger(int64(precision)),
+				"rounding":  &object.String{Value: rounding},
+			})
+		},
+	},
 	"abs": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
@@ -561,10 +738,17 @@ var builtins = map[string]*object.Builtin{
 			}
 			switch v := args[0].(type) {
 			case *object.Integer:
+				if v.Value == math.MinInt64 {
+					return object.NewBigInteger(new(big.Int).Abs(big.NewInt(v.Value)))
+				}
 				if v.Value < 0 {
 					return object.NewInteger(-v.Value)
 				}
 				return v
+			case *object.BigInteger:
+				return object.NewBigInteger(new(big.Int).Abs(v.Value))
+			case *object.Decimal:
+				return v.Abs()
 			case *object.Float:
 				if v.Value < 0 {
 					return &object.Float{Value: -v.Value}
@@ -722,7 +906,7 @@ var builtins = map[string]*object.Builtin{
 
 			// Iterate over the hash's pairs.
 			var result []object.Object
-			for _, pair := range hashObj.Pairs {
+			for _, pair := range hashObj.Ordered() {
 				switch filter {
 				case "":
 					// Default: return both key and value in a tuple.
@@ -784,10 +968,11 @@ var builtins = map[string]*object.Builtin{
 			if !ok {
 				return newError("ord argument must be STRING, got=%s", args[0].Type())
 			}
-			if len(str.Value) != 1 {
-				return newError("ord expects a single character string, got length %d", len(str.Value))
+			runes := []rune(str.Value)
+			if len(runes) != 1 {
+				return newError("ord expects a single character string, got length %d", len(runes))
 			}
-			return object.NewInteger(int64(str.Value[0]))
+			return object.NewInteger(int64(runes[0]))
 		},
 	},
 	"chr": {
@@ -799,8 +984,8 @@ var builtins = map[string]*object.Builtin{
 			if !ok {
 				return newError("chr argument must be INTEGER, got=%s", args[0].Type())
 			}
-			if num.Value < 0 || num.Value > 255 {
-				return newError("chr argument must be in range 0-255, got=%d", num.Value)
+			if num.Value > unicode.MaxRune || !utf8.ValidRune(rune(num.Value)) {
+				return newError("chr argument must be a valid code point, got=%d", num.Value)
 			}
 			return &object.String{Value: string(rune(num.Value))}
 		},
@@ -891,14 +1076,11 @@ var builtins = map[string]*object.Builtin{
 			}
 
 			// Parse as JSON (similar to httpParseJSON)
-			var data interface{}
-			if err := json.Unmarshal([]byte(hashStr), &data); err != nil {
+			result, err := modules.DecodeJSON([]byte(hashStr), false)
+			if err != nil {
 				// Return a more helpful error message
 				return newError("parseHash: failed to parse string as JSON object: %s", err)
 			}
-
-			// Convert JSON data to Carrion object
-			result := jsonToCarrionObject(data)
 			
 			// Ensure result is a hash
 			if hash, ok := result.(*object.Hash); ok {
@@ -910,6 +1092,70 @@ var builtins = map[string]*object.Builtin{
 	},
 }
 
+// byteDataFromArgs builds the contents of a new Bytes or ByteArray from the
+// arguments to bytes() or bytearray(). Strings are encoded with the optional
+// encoding name, integers give that many zero bytes, and lists give their
+// elements as byte values.
+func byteDataFromArgs(name string, args []object.Object) ([]byte, object.Object) {
+	if len(args) == 0 {
+		return []byte{}, nil
+	}
+	if len(args) > 2 {
+		return nil, newError("%s takes at most 2 arguments, got %d", name, len(args))
+	}
+
+	arg := unwrapPrimitive(args[0])
+	if len(args) == 2 {
+		if _, ok := arg.(*object.String); !ok {
+			return nil, newError("%s encoding argument requires a string source, got %s", name, arg.Type())
+		}
+	}
+
+	switch arg := arg.(type) {
+	case *object.String:
+		encodingName := "utf-8"
+		if len(args) == 2 {
+			enc, ok := unwrapPrimitive(args[1]).(*object.String)
+			if !ok {
+				return nil, newError("%s encoding must be a string, got %s", name, args[1].Type())
+			}
+			encodingName = enc.Value
+		}
+		data, err := modules.EncodeString(arg.Value, encodingName)
+		if err != nil {
+			return nil, newError("%s: %s", name, err)
+		}
+		return data, nil
+	case *object.Integer:
+		if arg.Value < 0 {
+			return nil, newError("%s size cannot be negative", name)
+		}
+		return make([]byte, arg.Value), nil
+	case *object.Bytes:
+		return append([]byte{}, arg.Value...), nil
+	case *object.ByteArray:
+		return append([]byte{}, arg.Value...), nil
+	case *object.Array, *object.Tuple:
+		var elements []object.Object
+		if arr, ok := arg.(*object.Array); ok {
+			elements = arr.Elements
+		} else {
+			elements = arg.(*object.Tuple).Elements
+		}
+		data := make([]byte, len(elements))
+		for i, elem := range elements {
+			value, ok := unwrapPrimitive(elem).(*object.Integer)
+			if !ok || value.Value < 0 || value.Value > 255 {
+				return nil, newError("%s values must be integers in range 0-255, got %s", name, elem.Inspect())
+			}
+			data[i] = byte(value.Value)
+		}
+		return data, nil
+	default:
+		return nil, newError("cannot convert %s to %s", arg.Type(), name)
+	}
+}
+
 // Add OS module functions to builtins when module is loaded
 func init() {
 	// Merge OS module functions into builtins
@@ -928,6 +1174,10 @@ func init() {
 	for name, builtin := range modules.SocketsModule {
 		builtins[name] = builtin
 	}
+	// Merge Sync module functions into builtins
+	for name, builtin := range modules.SyncModule {
+		builtins[name] = builtin
+	}
 	// Merge HTTP module functions into builtins
 	for name, builtin := range modules.HttpModule {
 		builtins[name] = builtin
@@ -940,8 +1190,66 @@ func init() {
 	for name, builtin := range modules.ParserBuiltins {
 		builtins[name] = builtin
 	}
+	// len() defers to __len__ on grimoire instances. Calling back into the
+	// evaluator from the builtins literal would be an initialization cycle,
+	// so the hook is attached here.
+	baseLen := builtins["len"].Fn
+	builtins["len"].Fn = func(args ...object.Object) object.Object {
+		if len(args) == 1 {
+			if inst, ok := args[0].(*object.Instance); ok {
+				if result, ok := callSpecialMethod(inst, "__len__", nil, nil); ok {
+					return result
+				}
+			}
+		}
+		return baseLen(args...)
+	}
+	// Grimoire instances become hash keys through __hash__ and __eq__.
+	object.InstanceHashKey = instanceHashKey
+	object.InstanceEqual = instancesEqual
+	// hash() may call __hash__, so it is registered here as well.
+	builtins["hash"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("hash requires exactly one argument, got %d", len(args))
+			}
+			key, ok := object.HashKeyOf(unwrapPrimitive(args[0]))
+			if !ok {
+				return newError("unhashable type: %s", args[0].Type())
+			}
+			return object.NewInteger(int64(key.Value))
+		},
+	}
+	// set() walks arbitrary iterables through the evaluator. Iterating a
+	// grimoire instance calls its iter() and next(), so it needs the
+	// caller's env and ctx.
+	contextBuiltins["set"] = &contextBuiltin{
+		Fn: func(args []object.Object, env *object.Environment, ctx *CallContext) object.Object {
+			switch len(args) {
+			case 0:
+				return object.NewSet()
+			case 1:
+				return newSetFromIterable(unwrapPrimitive(args[0]), ctx.Node, env, ctx)
+			default:
+				return newError("set takes at most 1 argument, got %d", len(args))
+			}
+		},
+	}
+}
+
+// contextBuiltin is a builtin that calls back into Carrion code and so
+// receives the environment and call context of its caller, which plain
+// builtins do not get.
+type contextBuiltin struct {
+	Fn func(args []object.Object, env *object.Environment, ctx *CallContext) object.Object
 }
 
+func (b *contextBuiltin) Type() object.ObjectType { return object.BUILTIN_OBJ }
+func (b *contextBuiltin) Inspect() string          { return "builtin function" }
+
+// contextBuiltins are looked up by name after builtins.
+var contextBuiltins = map[string]*contextBuiltin{}
+
 // Global reference to the stdlib environment
 var stdlibEnv *object.Environment
 
@@ -994,6 +1302,34 @@ func wrapPrimitiveForBuiltin(obj object.Object) object.Object {
 	return obj
 }
 
+// convertToInt implements int(): strings too large for an int64 and
+// floats beyond its range become big integers.
+func convertToInt(arg object.Object) object.Object {
+	switch typedArg := arg.(type) {
+	case *object.String:
+		value, ok := object.ParseInteger(strings.TrimSpace(typedArg.Value), 10)
+		if !ok {
+			return newError("cannot convert string to int: invalid syntax: %q", typedArg.Value)
+		}
+		return value
+	case *object.Float:
+		if math.IsNaN(typedArg.Value) || math.IsInf(typedArg.Value, 0) {
+			return newError("cannot convert %s to int", typedArg.Inspect())
+		}
+		if typedArg.Value >= math.MinInt64 && typedArg.Value < math.MaxInt64 {
+			return object.NewInteger(int64(typedArg.Value))
+		}
+		value, _ := big.NewFloat(typedArg.Value).Int(nil)
+		return object.NewBigInteger(value)
+	case *object.Decimal:
+		return object.NewBigInteger(typedArg.Int())
+	case *object.Integer, *object.BigInteger:
+		return typedArg
+	default:
+		return newError("cannot convert %s to int", arg.Type())
+	}
+}
+
 // extractIntegerValue extracts an integer value from various object types
 func extractIntegerValue(obj object.Object) (int64, error) {
 	switch v := obj.(type) {
@@ -1017,47 +1353,6 @@ func extractIntegerValue(obj object.Object) (int64, error) {
 	}
 }
 
-// jsonToCarrionObject converts JSON data to Carrion objects
-func jsonToCarrionObject(data interface{}) object.Object {
-	switch v := data.(type) {
-	case nil:
-		return &object.None{}
-	case bool:
-		if v {
-			return &object.Boolean{Value: true}
-		}
-		return &object.Boolean{Value: false}
-	case float64:
-		// Check if it's an integer
-		if v == float64(int64(v)) {
-			return object.NewInteger(int64(v))
-		}
-		return &object.Float{Value: v}
-	case string:
-		return &object.String{Value: v}
-	case []interface{}:
-		elements := make([]object.Object, len(v))
-		for i, elem := range v {
-			elements[i] = jsonToCarrionObject(elem)
-		}
-		return &object.Array{Elements: elements}
-	case map[string]interface{}:
-		result := &object.Hash{
-			Pairs: make(map[object.HashKey]object.HashPair),
-		}
-		for key, value := range v {
-			keyObj := &object.String{Value: key}
-			result.Pairs[keyObj.HashKey()] = object.HashPair{
-				Key:   keyObj,
-				Value: jsonToCarrionObject(value),
-			}
-		}
-		return result
-	default:
-		return &object.Error{Message: fmt.Sprintf("Unsupported JSON type: %T", v)}
-	}
-}
-
 // GetBuiltins returns a copy of the built-ins map for external access
 func GetBuiltins() map[string]*object.Builtin {
 	result := make(map[string]*object.Builtin)
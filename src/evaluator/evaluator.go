@@ -1,19 +1,31 @@
 package evaluator
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"hash/fnv"
+	"maps"
 	"math"
+	"math/big"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/javanhut/TheCarrionLanguage/src/ast"
 	"github.com/javanhut/TheCarrionLanguage/src/debug"
 	"github.com/javanhut/TheCarrionLanguage/src/lexer"
+	"github.com/javanhut/TheCarrionLanguage/src/modules"
 	"github.com/javanhut/TheCarrionLanguage/src/object"
 	"github.com/javanhut/TheCarrionLanguage/src/parser"
 	"github.com/javanhut/TheCarrionLanguage/src/token"
@@ -28,9 +40,12 @@ var (
 	FALSE                       = &object.Boolean{Value: false}
 	importedFiles               = map[string]interface{}{}
 	MAX_CALL_DEPTH              = 1000
-	CurrentContext *CallContext = nil
 )
 
+// stateMu guards importedFiles, which is shared by every goroutine running
+// Carrion code.
+var stateMu sync.Mutex
+
 // CallContext tracks function call state for better error reporting
 type CallContext struct {
 	FunctionName      string
@@ -38,34 +53,30 @@ type CallContext struct {
 	Parent            *CallContext
 	env               *object.Environment
 	depth             int
-	IsDirectExecution bool             // True when file is run directly, false when imported
-	MethodGrimoire    *object.Grimoire // The grimoire that owns the current method
-	SourceFile        string           // The source file path being evaluated (for relative imports)
+	IsDirectExecution bool                  // True when file is run directly, false when imported
+	MethodGrimoire    *object.Grimoire      // The grimoire that owns the current method
+	SourceFile        string                // The source file path being evaluated (for relative imports)
+	Generator         *object.GeneratorBody // Set on the context running a generator body
+	Goroutine         *object.Goroutine     // Set on the context running a diverge body
+	Self              *object.Instance      // Instance the running method was called on
+	Method            *object.Function      // Method running in this context
+	calls             *callDepths           // Active calls on this goroutine, see callDepthsFor
 }
 
-// A map to track call stack depth for recursive functions
-var callStack = make(map[*object.Function]*CallContext)
-
-// Global map to track recursion depth per function (defined later in file)
-var recursionDepths = make(map[*ast.BlockStatement]int)
+// callDepths counts the active calls of each spell and spell body on one
+// goroutine, so that recursion limits are not shared between goroutines
+// running the same spell.
+type callDepths struct {
+	functions map[*object.Function]int
+	bodies    map[*ast.BlockStatement]int
+}
 
 // CleanupGlobalState clears all global state maps to prevent memory leaks
 func CleanupGlobalState() {
+	stateMu.Lock()
 	// Clear imported files
 	importedFiles = make(map[string]interface{})
-
-	// Clear call stack
-	for k := range callStack {
-		delete(callStack, k)
-	}
-
-	// Clear recursion depths
-	for k := range recursionDepths {
-		delete(recursionDepths, k)
-	}
-
-	// Reset current context
-	CurrentContext = nil
+	stateMu.Unlock()
 
 	// Cleanup goroutine manager
 	CleanupGoroutineManager()
@@ -81,7 +92,7 @@ func CleanupGoroutineManager() {
 		// Wait for all named goroutines to finish
 		namedGoroutines := globalGoroutineManager.GetAllNamedGoroutines()
 		for _, goroutine := range namedGoroutines {
-			if goroutine.IsRunning {
+			if goroutine.IsRunning() {
 				select {
 				case <-goroutine.Done:
 					// Goroutine finished normally
@@ -94,7 +105,7 @@ func CleanupGoroutineManager() {
 		// Wait for all anonymous goroutines to finish
 		anonymousGoroutines := globalGoroutineManager.GetAllAnonymousGoroutines()
 		for _, goroutine := range anonymousGoroutines {
-			if goroutine.IsRunning {
+			if goroutine.IsRunning() {
 				select {
 				case <-goroutine.Done:
 					// Goroutine finished normally
@@ -119,20 +130,6 @@ func CleanupGoroutineManager() {
 	globalGoroutineManager.Reset()
 }
 
-// CleanupCallStack removes entries from call stack for specific function
-func CleanupCallStack(fn *object.Function) {
-	if fn != nil {
-		delete(callStack, fn)
-	}
-}
-
-// CleanupRecursionDepth removes recursion tracking for specific AST node
-func CleanupRecursionDepth(node *ast.BlockStatement) {
-	if node != nil {
-		delete(recursionDepths, node)
-	}
-}
-
 func getSourcePosition(node ast.Node) object.SourcePosition {
 	pos := object.SourcePosition{
 		Filename: "unknown",
@@ -193,10 +190,14 @@ func getNodeToken(node ast.Node) *token.Token {
 		return &n.Token
 	case *ast.FunctionDefinition:
 		return &n.Token
+	case *ast.FunctionLiteral:
+		return &n.Token
 	case *ast.CallExpression:
 		return &n.Token
 	case *ast.ReturnStatement:
 		return &n.Token
+	case *ast.YieldStatement:
+		return &n.Token
 	case *ast.AssignStatement:
 		return &n.Token
 	case *ast.DotExpression:
@@ -211,8 +212,20 @@ func getNodeToken(node ast.Node) *token.Token {
 		return &n.Token
 	case *ast.HashLiteral:
 		return &n.Token
+	case *ast.ConditionalExpression:
+		return &n.Token
+	case *ast.ListComprehension:
+		return &n.Token
+	case *ast.HashComprehension:
+		return &n.Token
+	case *ast.SetLiteral:
+		return &n.Token
+	case *ast.SetComprehension:
+		return &n.Token
 	case *ast.TupleLiteral:
 		return &n.Token
+	case *ast.RestPattern:
+		return &n.Token
 	case *ast.MatchStatement:
 		return &n.Token
 	case *ast.GrimoireDefinition:
@@ -235,6 +248,8 @@ func getNodeToken(node ast.Node) *token.Token {
 		return &n.Token
 	case *ast.ConvergeStatement:
 		return &n.Token
+	case *ast.SelectStatement:
+		return &n.Token
 	case *ast.CheckStatement:
 		return &n.Token
 	case *ast.ElseStatement:
@@ -245,6 +260,8 @@ func getNodeToken(node ast.Node) *token.Token {
 		return &n.Token
 	case *ast.WildcardExpression:
 		return &n.Token
+	case *ast.SpreadArgument:
+		return &n.Token
 	case *ast.NamedArgument:
 		return &n.Token
 	default:
@@ -354,16 +371,8 @@ func newCustomErrorWithTrace(
 }
 
 func newError(format string, args ...interface{}) object.Object {
-	msg := fmt.Sprintf(format, args...)
-	err := &object.Error{Message: msg}
-
-	// For better compatibility with stack traces, convert to ErrorWithTrace
-	// when we're inside the evaluator
-	if CurrentContext != nil {
-		return newErrorWithTrace(msg, CurrentContext.Node, CurrentContext)
-	}
-
-	return err
+	// Builtins have no call context; the call site attaches the stack trace
+	return &object.Error{Message: fmt.Sprintf(format, args...)}
 }
 
 func isPrimitiveLiteral(obj object.Object) bool {
@@ -411,7 +420,7 @@ func wrapPrimitiveForMethod(obj object.Object, env *object.Environment) object.O
 	var grimName string
 
 	switch obj.Type() {
-	case object.INTEGER_OBJ:
+	case object.INTEGER_OBJ, object.BIG_INTEGER_OBJ:
 		grimName = "Integer"
 	case object.FLOAT_OBJ:
 		grimName = "Float"
@@ -461,6 +470,10 @@ func wrapPrimitiveForMethod(obj object.Object, env *object.Environment) object.O
 // unwrapPrimitive extracts the primitive value from a wrapped instance if applicable
 func unwrapPrimitive(obj object.Object) object.Object {
 	if instance, ok := obj.(*object.Instance); ok {
+		// Enum members carry a value attribute but are not wrappers
+		if instance.Grimoire.IsEnum {
+			return obj
+		}
 		// Check for primitive values wrapped in "value" field (String, Integer, Float, Boolean)
 		if value, exists := instance.Env.Get("value"); exists {
 			return value
@@ -488,7 +501,10 @@ func unwrapPrimitive(obj object.Object) object.Object {
 
 // isBuiltinFunction checks if a function name is a builtin
 func isBuiltinFunction(name string) bool {
-	_, isBuiltin := builtins[name]
+	if _, isBuiltin := builtins[name]; isBuiltin {
+		return true
+	}
+	_, isBuiltin := contextBuiltins[name]
 	return isBuiltin
 }
 
@@ -572,10 +588,6 @@ func Eval(node ast.Node, env *object.Environment, ctx *CallContext) object.Objec
 		}
 	}
 
-	// Save and restore CurrentContext without defer (avoid closure allocation per Eval call)
-	oldContext := CurrentContext
-	CurrentContext = ctx
-
 	// Create a new call context if node is a function call
 	if callExp, ok := node.(*ast.CallExpression); ok {
 		funcName := ""
@@ -595,9 +607,7 @@ func Eval(node ast.Node, env *object.Environment, ctx *CallContext) object.Objec
 		ctx = newCtx
 	}
 
-	result := evalNode(node, env, ctx)
-	CurrentContext = oldContext
-	return result
+	return evalNode(node, env, ctx)
 }
 
 // evalNode is the inner dispatch loop, separated to avoid defer overhead in Eval.
@@ -622,6 +632,8 @@ func evalNode(node ast.Node, env *object.Environment, ctx *CallContext) object.O
 		return evalDivergeStatement(node, env, ctx)
 	case *ast.ConvergeStatement:
 		return evalConvergeStatement(node, env, ctx)
+	case *ast.SelectStatement:
+		return evalSelectStatement(node, env, ctx)
 	case *ast.CheckStatement:
 		cond := Eval(node.Condition, env, ctx)
 		if isError(cond) {
@@ -740,7 +752,20 @@ func evalNode(node ast.Node, env *object.Environment, ctx *CallContext) object.O
 	case *ast.PostfixExpression:
 		return evalPostfixIncrementDecrement(node.Operator, node, env, ctx)
 
+	case *ast.BytesLiteral:
+		return &object.Bytes{Value: node.Value}
+
+	case *ast.DecimalLiteral:
+		value, ok := object.ParseDecimal(node.Value)
+		if !ok {
+			return newErrorWithTrace("invalid decimal literal: %s", node, ctx, node.Value)
+		}
+		return value
+
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &object.BigInteger{Value: node.Big}
+		}
 		primitive := object.NewInteger(node.Value)
 		return wrapPrimitive(primitive, env, ctx)
 	case *ast.FloatLiteral:
@@ -763,6 +788,8 @@ func evalNode(node ast.Node, env *object.Environment, ctx *CallContext) object.O
 			}
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.YieldStatement:
+		return evalYieldStatement(node, env, ctx)
 	case *ast.Boolean:
 		primitive := nativeBoolToBooleanObject(node.Value)
 		return wrapPrimitive(primitive, env, ctx)
@@ -800,15 +827,49 @@ func evalNode(node ast.Node, env *object.Environment, ctx *CallContext) object.O
 		return evalTupleLiteral(node, env, ctx)
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env, ctx)
+	case *ast.ConditionalExpression:
+		condition := Eval(node.Condition, env, ctx)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return Eval(node.Consequence, env, ctx)
+		}
+		return Eval(node.Alternative, env, ctx)
+	case *ast.ListComprehension:
+		return evalListComprehension(node, env, ctx)
+	case *ast.HashComprehension:
+		return evalHashComprehension(node, env, ctx)
+	case *ast.SetLiteral:
+		return evalSetLiteral(node, env, ctx)
+	case *ast.SetComprehension:
+		return evalSetComprehension(node, env, ctx)
 	case *ast.FunctionDefinition:
 		fnObj := &object.Function{
-			Parameters: node.Parameters,
-			ReturnType: node.ReturnType,
-			Body:       node.Body,
-			Env:        env,
+			Parameters:  node.Parameters,
+			ReturnType:  node.ReturnType,
+			Body:        node.Body,
+			Env:         env,
+			IsGenerator: node.IsGenerator,
+		}
+		if len(node.Decorators) > 0 {
+			decorated := applyDecorators(fnObj, node.Decorators, env, ctx)
+			if isError(decorated) {
+				return decorated
+			}
+			env.Set(node.Name.Value, decorated)
+			return decorated
 		}
 		env.Set(node.Name.Value, fnObj)
 		return fnObj
+	case *ast.FunctionLiteral:
+		return &object.Function{
+			Parameters:  node.Parameters,
+			ReturnType:  node.ReturnType,
+			Body:        node.Body,
+			Env:         env,
+			IsGenerator: node.IsGenerator,
+		}
 	case *ast.DotExpression:
 		return evalDotExpression(node, env, ctx)
 	case *ast.IndexExpression:
@@ -826,20 +887,11 @@ func evalNode(node ast.Node, env *object.Environment, ctx *CallContext) object.O
 		if isError(left) {
 			return left
 		}
-		var start, end object.Object
-		if node.Start != nil {
-			start = Eval(node.Start, env, ctx)
-			if isError(start) {
-				return start
-			}
-		}
-		if node.End != nil {
-			end = Eval(node.End, env, ctx)
-			if isError(end) {
-				return end
-			}
+		start, end, step, err := evalSliceBounds(node, env, ctx)
+		if err != nil {
+			return err
 		}
-		return evalSliceExpression(left, start, end, node, ctx)
+		return evalSliceExpression(left, start, end, step, node, ctx)
 	case *ast.GrimoireDefinition:
 		return evalGrimoireDefinition(node, env, ctx)
 	case *ast.AttemptStatement:
@@ -862,6 +914,16 @@ func evalNode(node ast.Node, env *object.Environment, ctx *CallContext) object.O
 		seenNamed := false
 
 		for _, argExpr := range node.Arguments {
+			if spread, isSpread := argExpr.(*ast.SpreadArgument); isSpread {
+				if !spread.Keyword && seenNamed {
+					return newErrorWithTrace("positional argument follows keyword argument", node, ctx)
+				}
+				if errObj := spreadCallArgument(spread, &positionalArgs, namedArgs, env, ctx); errObj != nil {
+					return errObj
+				}
+				seenNamed = seenNamed || spread.Keyword
+				continue
+			}
 			if namedArg, isNamed := argExpr.(*ast.NamedArgument); isNamed {
 				seenNamed = true
 				val := Eval(namedArg.Value, env, ctx)
@@ -891,6 +953,46 @@ func evalNode(node ast.Node, env *object.Environment, ctx *CallContext) object.O
 	return NONE
 }
 
+// spreadCallArgument expands a `*iterable` argument into positional
+// arguments or a `**hash` argument into named ones.
+func spreadCallArgument(
+	spread *ast.SpreadArgument,
+	positionalArgs *[]object.Object,
+	namedArgs map[string]object.Object,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	val := Eval(spread.Value, env, ctx)
+	if isError(val) {
+		return val
+	}
+
+	if !spread.Keyword {
+		elements, errObj := iterableElements(val, spread, env, ctx)
+		if errObj != nil {
+			return errObj
+		}
+		*positionalArgs = append(*positionalArgs, elements...)
+		return nil
+	}
+
+	hash, ok := unwrapPrimitive(val).(*object.Hash)
+	if !ok {
+		return newErrorWithTrace("argument after ** must be a hash, not %s", spread, ctx, val.Type())
+	}
+	for _, pair := range hash.Ordered() {
+		key, ok := unwrapPrimitive(pair.Key).(*object.String)
+		if !ok {
+			return newErrorWithTrace("keywords must be strings, not %s", spread, ctx, pair.Key.Type())
+		}
+		if _, exists := namedArgs[key.Value]; exists {
+			return newErrorWithTrace("duplicate keyword argument: %s", spread, ctx, key.Value)
+		}
+		namedArgs[key.Value] = pair.Value
+	}
+	return nil
+}
+
 // EvalWithDebug evaluates an AST node with debug output
 func EvalWithDebug(node ast.Node, env *object.Environment, ctx *CallContext, debugConfig *debug.Config) object.Object {
 	// If no context provided, create one for direct execution
@@ -978,6 +1080,13 @@ func formatValue(val object.Object, exprPart *ast.StringExpr) string {
 		} else {
 			formatted = strconv.FormatFloat(obj.Value, 'f', -1, 64)
 		}
+	case *object.Decimal:
+		if exprPart.Precision > 0 {
+			_, rounding := object.DecimalContext()
+			formatted = obj.Round(exprPart.Precision, rounding).String()
+		} else {
+			formatted = obj.String()
+		}
 	case *object.Boolean:
 		formatted = strconv.FormatBool(obj.Value)
 	case *object.String:
@@ -1039,7 +1148,11 @@ func evalFStringLiteral(
 			if isError(val) {
 				return val
 			}
-			sb.WriteString(val.Inspect())
+			if p.Format != nil {
+				sb.WriteString(formatValue(unwrapPrimitive(val), p.Format))
+			} else {
+				sb.WriteString(val.Inspect())
+			}
 		}
 	}
 
@@ -1308,18 +1421,26 @@ func evalWithStatement(
 	// Check if the resource has a close method and call it
 	if resource != nil {
 		if instance, ok := resource.(*object.Instance); ok {
-			if closeMethod, exists := instance.Env.Get("close"); exists {
-				if fn, isFn := closeMethod.(*object.Function); isFn {
-					// Call the close method
-					closeCtx := &CallContext{
-						FunctionName: "close",
-						Node:         ws,
-						Parent:       ctx,
-						env:          instance.Env,
+			closeCtx := &CallContext{
+				FunctionName: "close",
+				Node:         ws,
+				Parent:       ctx,
+				env:          instance.Env,
+			}
+			// Prefer a close spell defined on the grimoire, falling back to
+			// a function stored on the instance itself
+			closed, found := callSpecialMethod(instance, "close", []object.Object{}, closeCtx)
+			if !found {
+				if closeMethod, exists := instance.Env.Get("close"); exists {
+					if fn, isFn := closeMethod.(*object.Function); isFn {
+						closed = evalCallExpression(fn, []object.Object{}, instance.Env, closeCtx)
 					}
-					evalCallExpression(fn, []object.Object{}, instance.Env, closeCtx)
 				}
 			}
+			// A failing close only replaces a successful result
+			if isError(closed) && !isError(result) {
+				return closed
+			}
 		}
 	}
 
@@ -1337,20 +1458,51 @@ func evalMatchStatement(
 	}
 
 	for _, caseClause := range ms.Cases {
-		caseCondition := Eval(caseClause.Condition, env, ctx)
-		if isError(caseCondition) {
-			return caseCondition
+		bindings := map[string]object.Object{}
+		matched, errObj := matchPattern(caseClause.Condition, matchValue, bindings, env, ctx)
+		if errObj != nil {
+			return errObj
+		}
+		if !matched {
+			continue
 		}
 
-		if isEqual(matchValue, caseCondition) {
-			caseCtx := &CallContext{
-				FunctionName: "case",
-				Node:         caseClause.Body,
-				Parent:       ctx,
-				env:          env,
+		// Like an ensnare alias, captures live in the case's own scope and
+		// never overwrite variables of the enclosing one. Other names the
+		// case assigns are copied out once it finishes.
+		caseEnv := env
+		if len(bindings) > 0 {
+			caseEnv = object.NewEnclosedEnvironment(env)
+			for name, value := range bindings {
+				caseEnv.Set(name, value)
+			}
+		}
+
+		if caseClause.Guard != nil {
+			guard := Eval(caseClause.Guard, caseEnv, ctx)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		caseCtx := &CallContext{
+			FunctionName: "case",
+			Node:         caseClause.Body,
+			Parent:       ctx,
+			env:          caseEnv,
+		}
+		result := Eval(caseClause.Body, caseEnv, caseCtx)
+		if caseEnv != env {
+			for name, value := range caseEnv.GetStore() {
+				if _, captured := bindings[name]; !captured {
+					env.SetWithGlobalCheck(name, value)
+				}
 			}
-			return Eval(caseClause.Body, env, caseCtx)
 		}
+		return result
 	}
 
 	if ms.Default != nil {
@@ -1363,7 +1515,257 @@ func evalMatchStatement(
 		return Eval(ms.Default.Body, env, defaultCtx)
 	}
 
-	return NONE
+	details := map[string]object.Object{
+		"errorType": &object.String{Value: "MatchError"},
+		"value":     matchValue,
+	}
+	return newCustomErrorWithTrace("MatchError",
+		fmt.Sprintf("no case matched value %s and there is no '_' case", matchValue.Inspect()),
+		ms, ctx, details)
+}
+
+// matchPattern reports whether value matches a case pattern, recording
+// capture variables in bindings. Patterns are expressions interpreted
+// structurally:
+//
+//	_                 matches anything
+//	name              captures the value
+//	[a, b, *rest]     sequence (array or tuple) destructuring
+//	{"key": p}        hash containing key whose value matches p
+//	Point(x, y=0)     instance of Point (or a subclass) with matching fields
+//	p1 | p2           alternatives
+//
+// Anything else (literals, dotted names, ...) is evaluated and compared
+// for equality.
+func matchPattern(
+	pattern ast.Expression,
+	value object.Object,
+	bindings map[string]object.Object,
+	env *object.Environment,
+	ctx *CallContext,
+) (bool, object.Object) {
+	switch pat := pattern.(type) {
+	case *ast.WildcardExpression:
+		return true, nil
+
+	case *ast.Identifier:
+		bindings[pat.Value] = value
+		return true, nil
+
+	case *ast.InfixExpression:
+		if pat.Operator != "|" {
+			break
+		}
+		for _, alt := range []ast.Expression{pat.Left, pat.Right} {
+			altBindings := map[string]object.Object{}
+			matched, errObj := matchPattern(alt, value, altBindings, env, ctx)
+			if errObj != nil {
+				return false, errObj
+			}
+			if matched {
+				for name, v := range altBindings {
+					bindings[name] = v
+				}
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case *ast.ArrayLiteral:
+		return matchSequencePattern(pat.Elements, value, bindings, env, ctx)
+
+	case *ast.TupleLiteral:
+		return matchSequencePattern(pat.Elements, value, bindings, env, ctx)
+
+	case *ast.HashLiteral:
+		hash, ok := unwrapPrimitive(value).(*object.Hash)
+		if !ok {
+			return false, nil
+		}
+		for keyNode, valuePattern := range pat.Pairs {
+			key := Eval(keyNode, env, ctx)
+			if isError(key) {
+				return false, key
+			}
+			key = unwrapPrimitive(key)
+			hashKey, ok := object.HashKeyOf(key)
+			if !ok {
+				return false, newErrorWithTrace("unusable as hash key in pattern: %s", keyNode, ctx, key.Type())
+			}
+			pair, exists, errObj := hash.Lookup(hashKey, key)
+			if errObj != nil || !exists {
+				return false, errObj
+			}
+			matched, errObj := matchPattern(valuePattern, pair.Value, bindings, env, ctx)
+			if errObj != nil || !matched {
+				return false, errObj
+			}
+		}
+		return true, nil
+
+	case *ast.CallExpression:
+		return matchInstancePattern(pat, value, bindings, env, ctx)
+	}
+
+	expected := Eval(pattern, env, ctx)
+	if isError(expected) {
+		return false, expected
+	}
+	return objectEquals(value, expected)
+}
+
+func matchSequencePattern(
+	patterns []ast.Expression,
+	value object.Object,
+	bindings map[string]object.Object,
+	env *object.Environment,
+	ctx *CallContext,
+) (bool, object.Object) {
+	var items []object.Object
+	switch seq := unwrapPrimitive(value).(type) {
+	case *object.Array:
+		items = seq.Elements
+	case *object.Tuple:
+		items = seq.Elements
+	default:
+		return false, nil
+	}
+
+	restIndex := -1
+	for i, p := range patterns {
+		if _, ok := p.(*ast.RestPattern); ok {
+			if restIndex != -1 {
+				return false, newErrorWithTrace("multiple rest patterns in sequence pattern", p, ctx)
+			}
+			restIndex = i
+		}
+	}
+
+	if restIndex == -1 {
+		if len(items) != len(patterns) {
+			return false, nil
+		}
+		for i, p := range patterns {
+			matched, errObj := matchPattern(p, items[i], bindings, env, ctx)
+			if errObj != nil || !matched {
+				return false, errObj
+			}
+		}
+		return true, nil
+	}
+
+	after := len(patterns) - restIndex - 1
+	if len(items) < restIndex+after {
+		return false, nil
+	}
+	for i := 0; i < restIndex; i++ {
+		matched, errObj := matchPattern(patterns[i], items[i], bindings, env, ctx)
+		if errObj != nil || !matched {
+			return false, errObj
+		}
+	}
+	for i := 0; i < after; i++ {
+		item := items[len(items)-after+i]
+		matched, errObj := matchPattern(patterns[restIndex+1+i], item, bindings, env, ctx)
+		if errObj != nil || !matched {
+			return false, errObj
+		}
+	}
+	if rest := patterns[restIndex].(*ast.RestPattern); rest.Name != nil {
+		captured := make([]object.Object, len(items)-restIndex-after)
+		copy(captured, items[restIndex:len(items)-after])
+		bindings[rest.Name.Value] = &object.Array{Elements: captured}
+	}
+	return true, nil
+}
+
+// matchInstancePattern matches `Grimoire(p1, p2, field=p3)`. Positional
+// sub-patterns are matched against the attributes named by the grimoire's
+// init parameters, in order; keyword sub-patterns name the attribute.
+func matchInstancePattern(
+	pat *ast.CallExpression,
+	value object.Object,
+	bindings map[string]object.Object,
+	env *object.Environment,
+	ctx *CallContext,
+) (bool, object.Object) {
+	target := Eval(pat.Function, env, ctx)
+	if isError(target) {
+		return false, target
+	}
+	grimoire, ok := target.(*object.Grimoire)
+	if !ok {
+		return false, newErrorWithTrace("%s is not a grimoire and cannot be used as a pattern",
+			pat, ctx, pat.Function.String())
+	}
+
+	instance, ok := value.(*object.Instance)
+	if !ok || !grimoireIsA(instance.Grimoire, grimoire) {
+		return false, nil
+	}
+
+	fieldNames := initParameterNames(grimoire)
+	positional := 0
+	for _, arg := range pat.Arguments {
+		var field string
+		var sub ast.Expression
+		if named, ok := arg.(*ast.NamedArgument); ok {
+			field = named.Name.Value
+			sub = named.Value
+		} else {
+			if positional >= len(fieldNames) {
+				return false, newErrorWithTrace("%s() accepts %d positional sub-patterns, got %d",
+					pat, ctx, grimoire.Name, len(fieldNames), positional+1)
+			}
+			field = fieldNames[positional]
+			sub = arg
+			positional++
+		}
+
+		fieldValue, exists := instance.Env.Get(field)
+		if !exists {
+			return false, nil
+		}
+		matched, errObj := matchPattern(sub, fieldValue, bindings, env, ctx)
+		if errObj != nil || !matched {
+			return false, errObj
+		}
+	}
+	return true, nil
+}
+
+// grimoireIsA reports whether g is target or inherits from it.
+func grimoireIsA(g, target *object.Grimoire) bool {
+	for current := g; current != nil; current = current.Inherits {
+		if current == target {
+			return true
+		}
+	}
+	return false
+}
+
+// initParameterNames returns the parameter names of the nearest init method
+// in the grimoire's inheritance chain.
+func initParameterNames(g *object.Grimoire) []string {
+	for current := g; current != nil; current = current.Inherits {
+		if current.InitMethod == nil {
+			continue
+		}
+		names := []string{}
+		for _, p := range current.InitMethod.Parameters {
+			switch param := p.(type) {
+			case *ast.Identifier:
+				names = append(names, param.Value)
+			case *ast.Parameter:
+				if param.Variadic || param.KwVariadic {
+					return names
+				}
+				names = append(names, param.Name.Value)
+			}
+		}
+		return names
+	}
+	return nil
 }
 
 func isEqual(obj1, obj2 object.Object) bool {
@@ -1376,6 +1778,24 @@ func isEqual(obj1, obj2 object.Object) bool {
 		if obj2, ok := obj2.(*object.Integer); ok {
 			return obj1.Value == obj2.Value
 		}
+	case *object.BigInteger:
+		if obj2, ok := obj2.(*object.BigInteger); ok {
+			return obj1.Value.Cmp(obj2.Value) == 0
+		}
+	case *object.Decimal:
+		if obj2, ok := obj2.(*object.Decimal); ok {
+			return obj1.Cmp(obj2) == 0
+		}
+	case *object.Set:
+		if obj2, ok := obj2.(*object.Set); ok {
+			equal, _ := obj1.Equals(obj2)
+			return equal
+		}
+	case *object.Bytes, *object.ByteArray:
+		if data2, ok := object.ByteData(obj2); ok {
+			data1, _ := object.ByteData(obj1)
+			return bytes.Equal(data1, data2)
+		}
 	case *object.String:
 		if obj2, ok := obj2.(*object.String); ok {
 			return obj1.Value == obj2.Value
@@ -1450,10 +1870,25 @@ func evalAssignStatement(
 		if !ok {
 			return newErrorWithTrace("invalid assignment target: %s", target, ctx, left.Type())
 		}
+		if instance.Grimoire.IsEnum {
+			return newErrorWithTrace("cannot assign to '%s': enum member %s is immutable", target, ctx, target.Right.Value, instance.Inspect())
+		}
 		val := Eval(node.Value, env, ctx)
 		if isError(val) {
 			return val
 		}
+		if prop, ok := instance.Grimoire.Properties[target.Right.Value]; ok {
+			if prop.Setter == nil {
+				return newCustomErrorWithTrace("AttributeError",
+					fmt.Sprintf("property '%s' of %s is read-only", target.Right.Value, instance.Grimoire.Name),
+					target, ctx, map[string]object.Object{"errorType": &object.String{Value: "AttributeError"}})
+			}
+			result := evalPropertyAccessor(instance, target.Right.Value, prop.Setter, []object.Object{val}, env, ctx)
+			if isError(result) {
+				return result
+			}
+			return val
+		}
 		instance.Env.Set(target.Right.Value, val)
 		return val
 
@@ -1559,16 +1994,55 @@ func evalAssignStatement(
 
 		return evalIndexAssignment(left, index, val, node, ctx)
 
+	case *ast.SliceExpression:
+		left := Eval(target.Left, env, ctx)
+		if isError(left) {
+			return left
+		}
+
+		start, end, step, err := evalSliceBounds(target, env, ctx)
+		if err != nil {
+			return err
+		}
+
+		val := Eval(node.Value, env, ctx)
+		if isError(val) {
+			return val
+		}
+
+		return evalIndexAssignment(left, &sliceIndex{start: start, end: end, step: step}, val, node, ctx)
+
 	default:
 		return newErrorWithTrace("invalid assignment target: %T", node, ctx, node.Name)
 	}
 }
 
+// sliceIndex is the index of a slice assignment target such as xs[1:3]. It
+// only travels from evalAssignStatement to evalIndexAssignment.
+type sliceIndex struct {
+	start, end, step object.Object
+}
+
+func (si *sliceIndex) Type() object.ObjectType { return "SLICE" }
+func (si *sliceIndex) Inspect() string {
+	parts := []string{"", "", ""}
+	for i, part := range []object.Object{si.start, si.end, si.step} {
+		if part != nil {
+			parts[i] = part.Inspect()
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
 func evalIndexAssignment(
 	array, index, value object.Object,
 	node ast.Node,
 	ctx *CallContext,
 ) object.Object {
+	if slice, ok := index.(*sliceIndex); ok {
+		return evalSliceAssignment(array, slice, value, node, ctx)
+	}
+
 	switch array := array.(type) {
 	case *object.Array:
 		intIndex, ok := index.(*object.Integer)
@@ -1576,6 +2050,8 @@ func evalIndexAssignment(
 			return newErrorWithTrace("array index must be INTEGER, got %s", node, ctx, index.Type())
 		}
 
+		array.Lock()
+		defer array.Unlock()
 		idx := intIndex.Value
 		maxIndex := int64(len(array.Elements) - 1)
 
@@ -1597,19 +2073,46 @@ func evalIndexAssignment(
 		array.Elements[idx] = value
 		return value
 
+	case *object.ByteArray:
+		intIndex, ok := index.(*object.Integer)
+		if !ok {
+			return newErrorWithTrace("bytearray index must be INTEGER, got %s", node, ctx, index.Type())
+		}
+		idx := intIndex.Value
+		if idx < 0 {
+			idx += int64(len(array.Value))
+		}
+		if idx < 0 || idx >= int64(len(array.Value)) {
+			return newErrorWithTrace("bytearray index out of bounds: %d (length: %d)", node, ctx, intIndex.Value, len(array.Value))
+		}
+		byteVal, ok := unwrapPrimitive(value).(*object.Integer)
+		if !ok || byteVal.Value < 0 || byteVal.Value > 255 {
+			return newErrorWithTrace("bytearray values must be integers in range 0-255, got %s", node, ctx, value.Inspect())
+		}
+		array.Value[idx] = byte(byteVal.Value)
+		return value
+
 	case *object.Hash:
 		// Unwrap primitives that may have been wrapped in Instance objects
 		unwrappedIndex := unwrapPrimitive(index)
-		key, ok := unwrappedIndex.(object.Hashable)
+		key, ok := object.HashKeyOf(unwrappedIndex)
 		if !ok {
 			return newErrorWithTrace("unusable as hash key: %s", node, ctx, unwrappedIndex.Type())
 		}
 
 		pair := object.HashPair{Key: unwrappedIndex, Value: value}
-		array.Pairs[key.HashKey()] = pair
+		if errObj := array.Set(key, pair); errObj != nil {
+			return errObj
+		}
 		return value
 
 	case *object.Instance:
+		if result, ok := callSpecialMethod(array, "__setitem__", []object.Object{index, value}, ctx); ok {
+			if isError(result) {
+				return result
+			}
+			return value
+		}
 		// Unwrap Instance-wrapped arrays and hashes
 		unwrapped := unwrapPrimitive(array)
 		if unwrapped != array {
@@ -1635,13 +2138,15 @@ func checkType(val object.Object, expectedType string) bool {
 		return false
 	case "int":
 		// Check both primitive INTEGER and Integer grimoire instances
-		if val.Type() == object.INTEGER_OBJ {
+		if val.Type() == object.INTEGER_OBJ || val.Type() == object.BIG_INTEGER_OBJ {
 			return true
 		}
 		if instance, ok := val.(*object.Instance); ok && instance.Grimoire.Name == "Integer" {
 			return true
 		}
 		return false
+	case "decimal":
+		return val.Type() == object.DECIMAL_OBJ
 	case "float":
 		// Check both primitive FLOAT and Float grimoire instances
 		if val.Type() == object.FLOAT_OBJ {
@@ -1690,10 +2195,14 @@ func evalGrimoireDefinition(
 	ctx *CallContext,
 ) object.Object {
 	methods := map[string]*object.Function{}
+	properties := map[string]*object.Property{}
 
 	var parentGrimoire *object.Grimoire
 	if node.Inherits != nil {
 		parentObj, ok := env.Get(node.Inherits.Value)
+		if !ok && node.Inherits.Value == "Enum" {
+			parentObj, ok = enumGrimoire, true
+		}
 		if !ok {
 			return newErrorWithTrace(
 				"parent grimoire '%s' not found",
@@ -1710,15 +2219,24 @@ func evalGrimoireDefinition(
 		for name, method := range parentGrimoire.Methods {
 			methods[name] = method
 		}
+		for name, prop := range parentGrimoire.Properties {
+			properties[name] = prop
+		}
 	}
 
+	// Environments snapshotted for this grimoire's own methods
+	methodEnvs := []*object.Environment{}
+
 	for _, method := range node.Methods {
 		fn := &object.Function{
 			Parameters: method.Parameters,
 			Body:       method.Body,
 			Env:        env.Clone(),
 		}
-		if strings.HasPrefix(method.Name.Value, "__") {
+		methodEnvs = append(methodEnvs, fn.Env)
+		if isSpecialMethodName(method.Name.Value) {
+			// __name__ special methods are public
+		} else if strings.HasPrefix(method.Name.Value, "__") {
 			fn.IsPrivate = true
 		} else if strings.HasPrefix(method.Name.Value, "_") {
 			fn.IsProtected = true
@@ -1727,6 +2245,21 @@ func evalGrimoireDefinition(
 		if method.Token.Type == token.ARCANESPELL {
 			fn.IsAbstract = true
 		}
+		fn.IsGenerator = method.IsGenerator
+
+		if isProp, errObj := defineProperty(properties, fn, method, ctx); errObj != nil {
+			return errObj
+		} else if isProp {
+			continue
+		}
+
+		if len(method.Decorators) > 0 {
+			decorated, errObj := decorateMethod(fn, method, env, ctx)
+			if errObj != nil {
+				return errObj
+			}
+			fn = decorated
+		}
 		methods[method.Name.Value] = fn
 	}
 
@@ -1749,30 +2282,302 @@ func evalGrimoireDefinition(
 		Env:        env.Clone(),
 		Inherits:   parentGrimoire,
 		IsArcane:   false,
+		Properties: properties,
 	}
 
 	if node.Token.Type == token.ARCANE {
 		grimoire.IsArcane = true
 	}
+	if parentGrimoire != nil && parentGrimoire.IsEnum {
+		if len(parentGrimoire.EnumMembers) > 0 {
+			return newErrorWithTrace("cannot extend enum '%s' because it has members", node, ctx, parentGrimoire.Name)
+		}
+		if node.InitMethod != nil {
+			return newErrorWithTrace("enum '%s' cannot define init", node, ctx, node.Name.Value)
+		}
+		grimoire.IsEnum = true
+	}
 	if node.InitMethod != nil {
 		initFn := &object.Function{
 			Parameters: node.InitMethod.Parameters,
 			Body:       node.InitMethod.Body,
 			Env:        env.Clone(),
 		}
+		methodEnvs = append(methodEnvs, initFn.Env)
+		if len(node.InitMethod.Decorators) > 0 {
+			decorated, errObj := decorateMethod(initFn, node.InitMethod, env, ctx)
+			if errObj != nil {
+				return errObj
+			}
+			initFn = decorated
+		}
 		grimoire.InitMethod = initFn
 	}
 
+	// The snapshots above predate the grimoire itself; bind its name so
+	// methods (operator overloads in particular) can build new instances.
+	grimoire.Env.Set(node.Name.Value, grimoire)
+	for _, methodEnv := range methodEnvs {
+		methodEnv.Set(node.Name.Value, grimoire)
+	}
+
+	if grimoire.IsEnum {
+		if errObj := createEnumMembers(grimoire, node.Members, env, ctx); errObj != nil {
+			return errObj
+		}
+	}
+
 	env.Set(node.Name.Value, grimoire)
 	return grimoire
 }
 
+// defineProperty records a method marked with @property or @name.setter
+// as a property accessor. isProp is false for ordinary methods. A setter
+// must follow its getter and replaces the entry rather than mutating an
+// inherited one.
+func defineProperty(
+	properties map[string]*object.Property,
+	fn *object.Function,
+	method *ast.FunctionDefinition,
+	ctx *CallContext,
+) (bool, object.Object) {
+	var propName string
+	isSetter := false
+	for _, dec := range method.Decorators {
+		switch d := dec.(type) {
+		case *ast.Identifier:
+			if d.Value == "property" {
+				propName = method.Name.Value
+			}
+		case *ast.DotExpression:
+			if target, ok := d.Left.(*ast.Identifier); ok && d.Right.Value == "setter" {
+				propName = target.Value
+				isSetter = true
+			}
+		}
+	}
+	if propName == "" {
+		return false, nil
+	}
+	if len(method.Decorators) > 1 {
+		return true, newErrorWithTrace("property '%s' cannot be combined with other decorators",
+			method, ctx, propName)
+	}
+
+	if !isSetter {
+		if len(method.Parameters) != 0 {
+			return true, newErrorWithTrace("property getter '%s' must not take parameters",
+				method, ctx, propName)
+		}
+		prop := &object.Property{Getter: fn}
+		if inherited, ok := properties[propName]; ok {
+			prop.Setter = inherited.Setter
+		}
+		properties[propName] = prop
+		return true, nil
+	}
+
+	getter, ok := properties[propName]
+	if !ok {
+		return true, newErrorWithTrace("setter for undefined property '%s'", method, ctx, propName)
+	}
+	if len(method.Parameters) != 1 {
+		return true, newErrorWithTrace("property setter '%s' must take exactly one parameter",
+			method, ctx, propName)
+	}
+	properties[propName] = &object.Property{Getter: getter.Getter, Setter: fn}
+	return true, nil
+}
+
+// evalPropertyAccessor calls a property getter (no value) or setter on an
+// instance.
+func evalPropertyAccessor(
+	instance *object.Instance,
+	name string,
+	accessor *object.Function,
+	args []object.Object,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	bound := &object.BoundMethod{Instance: instance, Method: accessor, Name: name}
+	return unwrapReturnValue(evalBoundMethodCall(bound, args, env, ctx))
+}
+
+// enumGrimoire is the built-in Enum base grimoire. Grimoires inheriting from
+// it declare their members as NAME or NAME = value lines.
+var enumGrimoire = &object.Grimoire{
+	Name:     "Enum",
+	Methods:  map[string]*object.Function{},
+	Env:      object.NewEnvironment(),
+	IsArcane: true,
+	IsEnum:   true,
+}
+
+// createEnumMembers builds the members of an enum grimoire in declaration
+// order. A bare NAME takes the value after the previous member's integer
+// value, starting at 1. Names and values must be unique.
+func createEnumMembers(
+	grimoire *object.Grimoire,
+	members []*ast.EnumMember,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	next := int64(1)
+	for _, decl := range members {
+		name := decl.Name.Value
+		if _, exists := grimoire.EnumMember(name); exists {
+			return newErrorWithTrace("duplicate enum member '%s' in %s", decl.Name, ctx, name, grimoire.Name)
+		}
+
+		var value object.Object = object.NewInteger(next)
+		if decl.Value != nil {
+			value = Eval(decl.Value, env, ctx)
+			if isError(value) {
+				return value
+			}
+		}
+		other, errObj := enumMemberByValue(grimoire, value)
+		if errObj != nil {
+			return errObj
+		}
+		if other != nil {
+			return newErrorWithTrace(
+				"enum member '%s' repeats the value %s of %s",
+				decl.Name, ctx, name, value.Inspect(), other.Inspect())
+		}
+		if intValue, ok := value.(*object.Integer); ok {
+			next = intValue.Value + 1
+		}
+
+		member := &object.Instance{
+			Grimoire: grimoire,
+			Env:      object.NewEnclosedEnvironment(grimoire.Env),
+		}
+		member.Env.Set("name", &object.String{Value: name})
+		member.Env.Set("value", value)
+		grimoire.EnumMembers = append(grimoire.EnumMembers, member)
+	}
+	return nil
+}
+
+// enumMemberByValue returns the member of an enum grimoire whose value
+// equals value, or nil, and any error raised while comparing.
+func enumMemberByValue(grimoire *object.Grimoire, value object.Object) (*object.Instance, object.Object) {
+	for _, member := range grimoire.EnumMembers {
+		memberValue, ok := member.Env.Get("value")
+		if !ok {
+			continue
+		}
+		if equal, errObj := objectEquals(memberValue, value); equal || errObj != nil {
+			return member, errObj
+		}
+	}
+	return nil, nil
+}
+
+// evalEnumLookup implements calling an enum grimoire, Color(value), which
+// returns the member with that value rather than creating an instance.
+func evalEnumLookup(grimoire *object.Grimoire, args []object.Object, node ast.Node, ctx *CallContext) object.Object {
+	if len(args) != 1 {
+		return newErrorWithTrace("%s() takes exactly 1 argument, got %d", node, ctx, grimoire.Name, len(args))
+	}
+	if member, ok := args[0].(*object.Instance); ok && member.Grimoire == grimoire {
+		return member
+	}
+	member, errObj := enumMemberByValue(grimoire, args[0])
+	if errObj != nil {
+		return errObj
+	}
+	if member != nil {
+		return member
+	}
+	return newErrorWithTrace("%s is not a valid %s", node, ctx, args[0].Inspect(), grimoire.Name)
+}
+
+// isEnumMember reports whether obj is a member of an enum grimoire.
+func isEnumMember(obj object.Object) bool {
+	instance, ok := obj.(*object.Instance)
+	return ok && instance.Grimoire.IsEnum
+}
+
+// enumMembers returns the members of an enum grimoire as a slice of objects.
+func enumMembers(grimoire *object.Grimoire) []object.Object {
+	members := make([]object.Object, len(grimoire.EnumMembers))
+	for i, member := range grimoire.EnumMembers {
+		members[i] = member
+	}
+	return members
+}
+
+// applyDecorators evaluates the decorator expressions of a spell definition
+// top to bottom and then applies them bottom-up, so the decorator closest to
+// the spell wraps it first.
+func applyDecorators(
+	fn object.Object,
+	decorators []ast.Expression,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	decs := make([]object.Object, len(decorators))
+	for i, d := range decorators {
+		dec := Eval(d, env, ctx)
+		if isError(dec) {
+			return dec
+		}
+		decs[i] = dec
+	}
+
+	result := fn
+	for i := len(decs) - 1; i >= 0; i-- {
+		decCtx := &CallContext{
+			FunctionName: "@" + decorators[i].String(),
+			Node:         decorators[i],
+			Parent:       ctx,
+			env:          env,
+		}
+		result = evalCallExpression(decs[i], []object.Object{result}, env, decCtx)
+		if isError(result) {
+			return result
+		}
+	}
+	return result
+}
+
+// decorateMethod applies a method's decorators. Grimoire methods must stay
+// spells, so a decorator returning anything else is an error.
+func decorateMethod(
+	fn *object.Function,
+	method *ast.FunctionDefinition,
+	env *object.Environment,
+	ctx *CallContext,
+) (*object.Function, object.Object) {
+	decorated := applyDecorators(fn, method.Decorators, env, ctx)
+	if isError(decorated) {
+		return nil, decorated
+	}
+	decoratedFn, ok := decorated.(*object.Function)
+	if !ok {
+		return nil, newErrorWithTrace("decorator on method '%s' must return a spell, got %s",
+			method, ctx, method.Name.Value, decorated.Type())
+	}
+	if decoratedFn != fn {
+		wrapped := *decoratedFn
+		wrapped.IsPrivate = fn.IsPrivate
+		wrapped.IsProtected = fn.IsProtected
+		wrapped.IsAbstract = fn.IsAbstract
+		decoratedFn = &wrapped
+		fn.Wrapper = decoratedFn
+	}
+	return decoratedFn, nil
+}
+
 // evalStaticMethodCall executes a static method call on a grimoire
 // Sets MethodGrimoire to the grimoire itself since static methods belong to the class
 func evalStaticMethodCall(
 	grimoire *object.Grimoire,
 	methodName string,
 	args []object.Object,
+	namedArgs map[string]object.Object,
 	env *object.Environment,
 	ctx *CallContext,
 ) object.Object {
@@ -1815,32 +2620,13 @@ func evalStaticMethodCall(
 		MethodGrimoire: grimoire,
 	}
 
-	// Bind arguments: support simple identifiers or full Parameter nodes
-	// For static methods, we don't skip 'self' parameter
-	argIndex := 0
-	for _, pExpr := range method.Parameters {
-		switch param := pExpr.(type) {
-		case *ast.Identifier:
-			name := param.Value
-			if argIndex < len(args) {
-				methodEnv.Set(name, args[argIndex])
-				argIndex++
-			} else {
-				methodEnv.Set(name, NONE)
-			}
-		case *ast.Parameter:
-			name := param.Name.Value
-			if argIndex < len(args) {
-				methodEnv.Set(name, args[argIndex])
-				argIndex++
-			} else if param.DefaultValue != nil {
-				methodEnv.Set(name, Eval(param.DefaultValue, method.Env, methodCtx))
-			} else {
-				methodEnv.Set(name, NONE)
-			}
-		default:
-			// unsupported parameter type
+	// Bind arguments. For static methods, we don't skip 'self' parameter
+	if len(namedArgs) > 0 {
+		if err := bindMethodParametersWithNamed(method, args, namedArgs, methodEnv, methodCtx, false, ctx.Node); err != nil {
+			return err
 		}
+	} else {
+		bindMethodParameters(method, args, methodEnv, methodCtx, false)
 	}
 
 	// Execute with bounds checking for recursive calls
@@ -1876,6 +2662,15 @@ func bindMethodParameters(
 			if skipSelf && name == "self" {
 				continue
 			}
+			if param.Variadic {
+				methodEnv.Set(name, &object.Array{Elements: remainingArgs(args, argIndex)})
+				argIndex = len(args)
+				continue
+			}
+			if param.KwVariadic {
+				methodEnv.Set(name, newKeywordHash(nil))
+				continue
+			}
 			if argIndex < len(args) {
 				methodEnv.Set(name, args[argIndex])
 				argIndex++
@@ -1936,6 +2731,8 @@ func nativeArrayMethod(instance *object.Instance, methodName string, args []obje
 		if !ok {
 			return nil, false
 		}
+		arr.Lock()
+		defer arr.Unlock()
 		idx := intIdx.Value
 		if idx < 0 {
 			idx = int64(len(arr.Elements)) + idx
@@ -1954,6 +2751,8 @@ func nativeArrayMethod(instance *object.Instance, methodName string, args []obje
 		if !ok {
 			return nil, false
 		}
+		arr.RLock()
+		defer arr.RUnlock()
 		idx := intIdx.Value
 		if idx < 0 {
 			idx = int64(len(arr.Elements)) + idx
@@ -1967,10 +2766,14 @@ func nativeArrayMethod(instance *object.Instance, methodName string, args []obje
 		if len(args) != 1 {
 			return nil, false
 		}
+		arr.Lock()
 		arr.Elements = append(arr.Elements, args[0])
+		arr.Unlock()
 		return NONE, true
 
 	case "pop":
+		arr.Lock()
+		defer arr.Unlock()
 		if len(arr.Elements) == 0 {
 			return NONE, true
 		}
@@ -1979,43 +2782,58 @@ func nativeArrayMethod(instance *object.Instance, methodName string, args []obje
 		return last, true
 
 	case "length":
-		return object.NewInteger(int64(len(arr.Elements))), true
+		return object.NewInteger(int64(arr.Len())), true
 
 	case "is_empty":
-		return nativeBoolToBooleanObject(len(arr.Elements) == 0), true
+		return nativeBoolToBooleanObject(arr.Len() == 0), true
 
 	case "first":
+		arr.RLock()
+		defer arr.RUnlock()
 		if len(arr.Elements) == 0 {
 			return NONE, true
 		}
 		return arr.Elements[0], true
 
 	case "last":
+		arr.RLock()
+		defer arr.RUnlock()
 		if len(arr.Elements) == 0 {
 			return NONE, true
 		}
 		return arr.Elements[len(arr.Elements)-1], true
 
 	case "clear":
+		arr.Lock()
 		arr.Elements = arr.Elements[:0]
+		arr.Unlock()
 		return NONE, true
 
 	case "reverse":
-		n := len(arr.Elements)
+		elements := arr.Snapshot()
+		n := len(elements)
 		result := make([]object.Object, n)
 		for i := 0; i < n; i++ {
-			result[i] = arr.Elements[n-1-i]
+			result[i] = elements[n-1-i]
 		}
 		return wrapArrayInstance(instance.Grimoire, &object.Array{Elements: result}), true
 
 	case "sort":
 		// Create a copy and sort it (non-mutating, matches Carrion semantics)
-		n := len(arr.Elements)
-		sorted := make([]object.Object, n)
-		copy(sorted, arr.Elements)
+		// Stop comparing once a __lt__ raises, and raise that error
+		sorted := arr.Snapshot()
+		var sortErr object.Object
 		sort.SliceStable(sorted, func(i, j int) bool {
-			return objectLess(sorted[i], sorted[j])
+			if sortErr != nil {
+				return false
+			}
+			less, errObj := objectLess(sorted[i], sorted[j])
+			sortErr = errObj
+			return less
 		})
+		if sortErr != nil {
+			return sortErr, true
+		}
 		return wrapArrayInstance(instance.Grimoire, &object.Array{Elements: sorted}), true
 
 	case "contains":
@@ -2023,8 +2841,10 @@ func nativeArrayMethod(instance *object.Instance, methodName string, args []obje
 			return nil, false
 		}
 		target := args[0]
-		for _, elem := range arr.Elements {
-			if objectEquals(elem, target) {
+		for _, elem := range arr.Snapshot() {
+			if equal, errObj := objectEquals(elem, target); errObj != nil {
+				return errObj, true
+			} else if equal {
 				return TRUE, true
 			}
 		}
@@ -2035,8 +2855,10 @@ func nativeArrayMethod(instance *object.Instance, methodName string, args []obje
 			return nil, false
 		}
 		target := args[0]
-		for i, elem := range arr.Elements {
-			if objectEquals(elem, target) {
+		for i, elem := range arr.Snapshot() {
+			if equal, errObj := objectEquals(elem, target); errObj != nil {
+				return errObj, true
+			} else if equal {
 				return object.NewInteger(int64(i)), true
 			}
 		}
@@ -2046,18 +2868,32 @@ func nativeArrayMethod(instance *object.Instance, methodName string, args []obje
 		if len(args) != 1 {
 			return nil, false
 		}
+		// Compare outside the lock, since equality can run user code, and
+		// then remove that element if it is still there.
 		target := args[0]
-		found := false
-		newElements := make([]object.Object, 0, len(arr.Elements))
-		for _, elem := range arr.Elements {
-			if !found && objectEquals(elem, target) {
-				found = true
-				continue
+		var match object.Object
+		for _, elem := range arr.Snapshot() {
+			if equal, errObj := objectEquals(elem, target); errObj != nil {
+				return errObj, true
+			} else if equal {
+				match = elem
+				break
 			}
-			newElements = append(newElements, elem)
 		}
-		arr.Elements = newElements
-		return nativeBoolToBooleanObject(found), true
+		if match == nil {
+			return FALSE, true
+		}
+		arr.Lock()
+		defer arr.Unlock()
+		for i, elem := range arr.Elements {
+			if elem == match {
+				newElements := make([]object.Object, 0, len(arr.Elements)-1)
+				newElements = append(newElements, arr.Elements[:i]...)
+				arr.Elements = append(newElements, arr.Elements[i+1:]...)
+				return TRUE, true
+			}
+		}
+		return FALSE, true
 
 	case "slice":
 		if len(args) != 2 {
@@ -2070,9 +2906,10 @@ func nativeArrayMethod(instance *object.Instance, methodName string, args []obje
 		if !ok1 || !ok2 {
 			return nil, false
 		}
+		elements := arr.Snapshot()
 		start := startInt.Value
 		end := endInt.Value
-		n := int64(len(arr.Elements))
+		n := int64(len(elements))
 		if start < 0 {
 			start = n + start
 		}
@@ -2082,7 +2919,7 @@ func nativeArrayMethod(instance *object.Instance, methodName string, args []obje
 		result := make([]object.Object, 0)
 		for i := start; i < end; i++ {
 			if i >= 0 && i < n {
-				result = append(result, arr.Elements[i])
+				result = append(result, elements[i])
 			}
 		}
 		return &object.Array{Elements: result}, true
@@ -2092,60 +2929,101 @@ func nativeArrayMethod(instance *object.Instance, methodName string, args []obje
 }
 
 // objectLess compares two objects for sort ordering (less-than).
-func objectLess(a, b object.Object) bool {
+func objectLess(a, b object.Object) (bool, object.Object) {
+	if less, err, ok := specialComparison("__lt__", "__gt__", a, b); ok {
+		return less, err
+	}
 	a = unwrapPrimitive(a)
 	b = unwrapPrimitive(b)
 
+	if a.Type() == object.DECIMAL_OBJ || b.Type() == object.DECIMAL_OBJ {
+		if av, ok := object.ToDecimal(a); ok {
+			if bv, ok := object.ToDecimal(b); ok {
+				return av.Cmp(bv) < 0, nil
+			}
+		}
+		return false, nil
+	}
+
+	if a.Type() == object.BIG_INTEGER_OBJ || b.Type() == object.BIG_INTEGER_OBJ {
+		if av, ok := object.ToBigInt(a); ok {
+			if bv, ok := object.ToBigInt(b); ok {
+				return av.Cmp(bv) < 0, nil
+			}
+		}
+		return toFloat(a) < toFloat(b), nil
+	}
+
 	switch av := a.(type) {
 	case *object.Integer:
 		if bv, ok := b.(*object.Integer); ok {
-			return av.Value < bv.Value
+			return av.Value < bv.Value, nil
 		}
 		if bv, ok := b.(*object.Float); ok {
-			return float64(av.Value) < bv.Value
+			return float64(av.Value) < bv.Value, nil
 		}
 	case *object.Float:
 		if bv, ok := b.(*object.Float); ok {
-			return av.Value < bv.Value
+			return av.Value < bv.Value, nil
 		}
 		if bv, ok := b.(*object.Integer); ok {
-			return av.Value < float64(bv.Value)
+			return av.Value < float64(bv.Value), nil
 		}
 	case *object.String:
 		if bv, ok := b.(*object.String); ok {
-			return av.Value < bv.Value
+			return av.Value < bv.Value, nil
 		}
 	}
-	return false
+	return false, nil
 }
 
 // objectEquals compares two objects for equality.
-func objectEquals(a, b object.Object) bool {
+func objectEquals(a, b object.Object) (bool, object.Object) {
+	if equal, err, ok := specialComparison("__eq__", "__eq__", a, b); ok {
+		return equal, err
+	}
 	a = unwrapPrimitive(a)
 	b = unwrapPrimitive(b)
 
 	switch av := a.(type) {
 	case *object.Integer:
 		if bv, ok := b.(*object.Integer); ok {
-			return av.Value == bv.Value
+			return av.Value == bv.Value, nil
 		}
 	case *object.Float:
 		if bv, ok := b.(*object.Float); ok {
-			return av.Value == bv.Value
+			return av.Value == bv.Value, nil
 		}
 	case *object.String:
 		if bv, ok := b.(*object.String); ok {
-			return av.Value == bv.Value
+			return av.Value == bv.Value, nil
+		}
+	case *object.BigInteger:
+		if bv, ok := b.(*object.BigInteger); ok {
+			return av.Value.Cmp(bv.Value) == 0, nil
+		}
+	case *object.Decimal:
+		if bv, ok := b.(*object.Decimal); ok {
+			return av.Cmp(bv) == 0, nil
+		}
+	case *object.Set:
+		if bv, ok := b.(*object.Set); ok {
+			return av.Equals(bv)
+		}
+	case *object.Bytes, *object.ByteArray:
+		if bv, ok := object.ByteData(b); ok {
+			data, _ := object.ByteData(av)
+			return bytes.Equal(data, bv), nil
 		}
 	case *object.Boolean:
 		if bv, ok := b.(*object.Boolean); ok {
-			return av.Value == bv.Value
+			return av.Value == bv.Value, nil
 		}
 	case *object.None:
 		_, ok := b.(*object.None)
-		return ok
+		return ok, nil
 	}
-	return a == b
+	return a == b, nil
 }
 
 func evalGrimoireMethodCall(
@@ -2199,6 +3077,8 @@ func evalGrimoireMethodCall(
 		Parent:         ctx,
 		env:            methodEnv,
 		MethodGrimoire: methodOwner,
+		Self:           instance,
+		Method:         method,
 	}
 
 	// Bind arguments using the common helper function
@@ -2264,6 +3144,8 @@ func evalBoundMethodCall(
 		env:            methodEnv,
 		depth:          ctx.depth + 1,
 		MethodGrimoire: methodOwner,
+		Self:           instance,
+		Method:         method,
 	}
 
 	// Bind arguments using the common helper function
@@ -2273,7 +3155,106 @@ func evalBoundMethodCall(
 	return evalWithRecursionLimit(method.Body, methodEnv, method, methodCtx, 0)
 }
 
-// recursionDepths is defined at the top of the file
+// bindWrappedSelf gives a decorated grimoire method's original spell, when
+// its wrapper calls it, the self that the wrapper was called on. It returns
+// the grimoire owning the method, or nil when fn is not such a spell or is
+// called outside its wrapper.
+func bindWrappedSelf(fn *object.Function, extended *object.Environment, ctx *CallContext) *object.Grimoire {
+	if fn.Wrapper == nil {
+		return nil
+	}
+	for c := ctx; c != nil; c = c.Parent {
+		if c.Method == fn.Wrapper && c.Self != nil {
+			extended.Set("self", c.Self)
+			return c.MethodGrimoire
+		}
+	}
+	return nil
+}
+
+// callDepthsFor returns the call counters of the goroutine running ctx. They
+// live on the goroutine's root context (one with no parent, or one running a
+// diverge body, pool task or generator) and are cached on ctx so nested
+// calls find them without walking the whole chain.
+func callDepthsFor(ctx *CallContext) *callDepths {
+	if ctx == nil {
+		return &callDepths{
+			functions: make(map[*object.Function]int),
+			bodies:    make(map[*ast.BlockStatement]int),
+		}
+	}
+	c := ctx
+	for c.calls == nil {
+		if c.Parent == nil || c.Goroutine != nil || c.Generator != nil {
+			c.calls = &callDepths{
+				functions: make(map[*object.Function]int),
+				bodies:    make(map[*ast.BlockStatement]int),
+			}
+			break
+		}
+		c = c.Parent
+	}
+	ctx.calls = c.calls
+	return ctx.calls
+}
+
+// enterRecursion bumps the depth recorded for body, starting from depth the
+// first time it is seen. It reports false, leaving the depth unchanged, when
+// the limit would be exceeded.
+func enterRecursion(ctx *CallContext, body *ast.BlockStatement, depth int) bool {
+	bodies := callDepthsFor(ctx).bodies
+	currentDepth, exists := bodies[body]
+	if !exists {
+		currentDepth = depth
+	}
+	if currentDepth+1 > MAX_CALL_DEPTH {
+		return false
+	}
+	bodies[body] = currentDepth + 1
+	return true
+}
+
+func leaveRecursion(ctx *CallContext, body *ast.BlockStatement) {
+	bodies := callDepthsFor(ctx).bodies
+	bodies[body]--
+	if bodies[body] <= 0 {
+		delete(bodies, body)
+	}
+}
+
+// enterCall records another active call of fn, reporting false when the
+// call would exceed MAX_CALL_DEPTH.
+func enterCall(ctx *CallContext, fn *object.Function) bool {
+	functions := callDepthsFor(ctx).functions
+	if functions[fn]+1 > MAX_CALL_DEPTH {
+		return false
+	}
+	functions[fn]++
+	return true
+}
+
+func leaveCall(ctx *CallContext, fn *object.Function) {
+	functions := callDepthsFor(ctx).functions
+	functions[fn]--
+	if functions[fn] <= 0 {
+		delete(functions, fn)
+	}
+}
+
+// lookupImport and recordImport give imports running inside diverge blocks
+// safe access to importedFiles.
+func lookupImport(path string) (interface{}, bool) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	cached, ok := importedFiles[path]
+	return cached, ok
+}
+
+func recordImport(path string, value interface{}) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	importedFiles[path] = value
+}
 
 func evalWithRecursionLimit(
 	body *ast.BlockStatement,
@@ -2282,16 +3263,12 @@ func evalWithRecursionLimit(
 	ctx *CallContext,
 	depth int,
 ) object.Object {
-	// Get current depth or start at provided depth
-	currentDepth, exists := recursionDepths[body]
-	if !exists {
-		currentDepth = depth
+	// Generator methods hand back a suspended body instead of running it
+	if method != nil && method.IsGenerator {
+		return newGenerator(ctx.FunctionName, body, env, ctx)
 	}
 
-	// Increment and check
-	recursionDepths[body] = currentDepth + 1
-	if recursionDepths[body] > MAX_CALL_DEPTH {
-		recursionDepths[body]-- // Clean up
+	if !enterRecursion(ctx, body, depth) {
 		return newErrorWithTrace("maximum recursion depth exceeded (limit: %d)",
 			body, ctx, MAX_CALL_DEPTH)
 	}
@@ -2299,15 +3276,32 @@ func evalWithRecursionLimit(
 	// Evaluate with depth tracking
 	result := Eval(body, env, ctx)
 
-	// Clean up
-	recursionDepths[body]--
-	if recursionDepths[body] <= 0 {
-		delete(recursionDepths, body)
-	}
+	leaveRecursion(ctx, body)
 
 	return unwrapReturnValue(result)
 }
 
+// builtinResult attaches the call's stack trace to an error returned by a
+// builtin and wraps results that need grimoire methods.
+func builtinResult(res object.Object, env *object.Environment, ctx *CallContext) object.Object {
+	if err, ok := res.(*object.Error); ok {
+		return newErrorWithTrace(err.Message, ctx.Node, ctx)
+	}
+	// Wrap string results from input functions in String grimoire instances
+	if shouldWrapStringResult(ctx.FunctionName) {
+		if stringObj, isString := res.(*object.String); isString {
+			return wrapPrimitive(stringObj, env, ctx)
+		}
+	}
+	// Wrap array results from pairs() function so they have access to methods
+	if ctx.FunctionName == "pairs" {
+		if arrayObj, isArray := res.(*object.Array); isArray {
+			return wrapPrimitive(arrayObj, env, ctx)
+		}
+	}
+	return res
+}
+
 func evalCallExpression(
 	fn object.Object,
 	args []object.Object,
@@ -2336,20 +3330,14 @@ func evalCallExpression(
 		// use the correctly typed value as the map key
 		fun := fnTyped // alias for brevity
 
-		callCtx, ok := callStack[fun] // was callStack[fn]
-		if !ok {
-			callCtx = &CallContext{depth: 0, env: env}
-			callStack[fun] = callCtx // was callStack[fn]
-		}
-		callCtx.depth++
-		if callCtx.depth > MAX_CALL_DEPTH {
-			callCtx.depth--
+		if !enterCall(ctx, fun) {
 			return newErrorWithTrace(
 				"maximum recursion depth exceeded (%d)", ctx.Node, ctx, MAX_CALL_DEPTH)
 		}
 
 		global := getGlobalEnv(fun.Env, ctx)
 		extended := extendFunctionEnv(fun, args, global, ctx)
+		methodGrimoire := bindWrappedSelf(fun, extended, ctx)
 
 		// we don’t know the spell’s name here; fall back to the caller’s ctx
 		funcName := ctx.FunctionName
@@ -2357,28 +3345,34 @@ func evalCallExpression(
 			funcName = "<anonymous>"
 		}
 
+		if fun.IsGenerator {
+			leaveCall(ctx, fun)
+			return newGenerator(funcName, fun.Body, extended, ctx)
+		}
+
 		fnCtx := &CallContext{
-			FunctionName: funcName,
-			Node:         fun.Body,
-			Parent:       ctx,
-			env:          extended,
+			FunctionName:   funcName,
+			Node:           fun.Body,
+			Parent:         ctx,
+			env:            extended,
+			MethodGrimoire: methodGrimoire,
 		}
 
 		evaluated := Eval(fun.Body, extended, fnCtx)
 
-		callCtx.depth--
-		if callCtx.depth == 0 {
-			delete(callStack, fun) // was delete(callStack, fn)
-		}
+		leaveCall(ctx, fun)
 		return unwrapReturnValue(evaluated)
 	case *object.BoundMethod:
 		// Use a special version of evalGrimoireMethodCall that uses the stored method
 		return evalBoundMethodCall(fnTyped, args, env, ctx)
 
 	case *object.StaticMethod:
-		return evalStaticMethodCall(fnTyped.Grimoire, fnTyped.Name, args, env, ctx)
+		return evalStaticMethodCall(fnTyped.Grimoire, fnTyped.Name, args, nil, env, ctx)
 
 	case *object.Grimoire:
+		if fnTyped.IsEnum {
+			return evalEnumLookup(fnTyped, args, ctx.Node, ctx)
+		}
 		if fnTyped.IsArcane {
 			return newErrorWithTrace(
 				"cannot instantiate arcane grimoire: %s", ctx.Node, ctx, fnTyped.Name)
@@ -2400,6 +3394,8 @@ func evalCallExpression(
 				Parent:         ctx,
 				env:            extended,
 				MethodGrimoire: fnTyped,
+				Self:           instance,
+				Method:         fnTyped.InitMethod,
 			}
 			result := Eval(fnTyped.InitMethod.Body, extended, initCtx)
 			if isError(result) {
@@ -2409,23 +3405,10 @@ func evalCallExpression(
 		return instance
 
 	case *object.Builtin:
-		res := fnTyped.Fn(args...)
-		if err, ok := res.(*object.Error); ok {
-			return newErrorWithTrace(err.Message, ctx.Node, ctx)
-		}
-		// Wrap string results from input functions in String grimoire instances
-		if shouldWrapStringResult(ctx.FunctionName) {
-			if stringObj, isString := res.(*object.String); isString {
-				return wrapPrimitive(stringObj, env, ctx)
-			}
-		}
-		// Wrap array results from pairs() function so they have access to methods
-		if ctx.FunctionName == "pairs" {
-			if arrayObj, isArray := res.(*object.Array); isArray {
-				return wrapPrimitive(arrayObj, env, ctx)
-			}
-		}
-		return res
+		return builtinResult(fnTyped.Fn(args...), env, ctx)
+
+	case *contextBuiltin:
+		return builtinResult(fnTyped.Fn(args, env, ctx), env, ctx)
 
 	default:
 		return newErrorWithTrace(
@@ -2466,14 +3449,7 @@ func evalCallExpressionWithNamed(
 		}
 
 		fun := fnTyped
-		callCtx, ok := callStack[fun]
-		if !ok {
-			callCtx = &CallContext{depth: 0, env: env}
-			callStack[fun] = callCtx
-		}
-		callCtx.depth++
-		if callCtx.depth > MAX_CALL_DEPTH {
-			callCtx.depth--
+		if !enterCall(ctx, fun) {
 			return newErrorWithTrace(
 				"maximum recursion depth exceeded (%d)", ctx.Node, ctx, MAX_CALL_DEPTH)
 		}
@@ -2481,42 +3457,47 @@ func evalCallExpressionWithNamed(
 		global := getGlobalEnv(fun.Env, ctx)
 		extended, err := extendFunctionEnvWithNamed(fun, positionalArgs, namedArgs, global, ctx, node)
 		if err != nil {
-			callCtx.depth--
-			if callCtx.depth == 0 {
-				delete(callStack, fun)
-			}
+			leaveCall(ctx, fun)
 			return err
 		}
+		methodGrimoire := bindWrappedSelf(fun, extended, ctx)
 
 		funcName := ctx.FunctionName
 		if funcName == "" {
 			funcName = "<anonymous>"
 		}
 
+		if fun.IsGenerator {
+			leaveCall(ctx, fun)
+			return newGenerator(funcName, fun.Body, extended, ctx)
+		}
+
 		fnCtx := &CallContext{
-			FunctionName: funcName,
-			Node:         fun.Body,
-			Parent:       ctx,
-			env:          extended,
+			FunctionName:   funcName,
+			Node:           fun.Body,
+			Parent:         ctx,
+			env:            extended,
+			MethodGrimoire: methodGrimoire,
 		}
 
 		evaluated := Eval(fun.Body, extended, fnCtx)
 
-		callCtx.depth--
-		if callCtx.depth == 0 {
-			delete(callStack, fun)
-		}
+		leaveCall(ctx, fun)
 		return unwrapReturnValue(evaluated)
 
 	case *object.BoundMethod:
 		return evalBoundMethodCallWithNamed(fnTyped, positionalArgs, namedArgs, env, ctx, node)
 
 	case *object.StaticMethod:
-		// For static methods, convert named args to positional for now
-		// (could be enhanced later)
-		return evalStaticMethodCall(fnTyped.Grimoire, fnTyped.Name, positionalArgs, env, ctx)
+		return evalStaticMethodCall(fnTyped.Grimoire, fnTyped.Name, positionalArgs, namedArgs, env, ctx)
 
 	case *object.Grimoire:
+		if fnTyped.IsEnum {
+			if len(namedArgs) > 0 {
+				return newErrorWithTrace("%s() does not accept keyword arguments", node, ctx, fnTyped.Name)
+			}
+			return evalEnumLookup(fnTyped, positionalArgs, ctx.Node, ctx)
+		}
 		if fnTyped.IsArcane {
 			return newErrorWithTrace(
 				"cannot instantiate arcane grimoire: %s", ctx.Node, ctx, fnTyped.Name)
@@ -2541,6 +3522,8 @@ func evalCallExpressionWithNamed(
 				Parent:         ctx,
 				env:            extended,
 				MethodGrimoire: fnTyped,
+				Self:           instance,
+				Method:         fnTyped.InitMethod,
 			}
 			result := Eval(fnTyped.InitMethod.Body, extended, initCtx)
 			if isError(result) {
@@ -2551,21 +3534,10 @@ func evalCallExpressionWithNamed(
 
 	case *object.Builtin:
 		// Builtins don't support named arguments currently
-		res := fnTyped.Fn(positionalArgs...)
-		if err, ok := res.(*object.Error); ok {
-			return newErrorWithTrace(err.Message, ctx.Node, ctx)
-		}
-		if shouldWrapStringResult(ctx.FunctionName) {
-			if stringObj, isString := res.(*object.String); isString {
-				return wrapPrimitive(stringObj, env, ctx)
-			}
-		}
-		if ctx.FunctionName == "pairs" {
-			if arrayObj, isArray := res.(*object.Array); isArray {
-				return wrapPrimitive(arrayObj, env, ctx)
-			}
-		}
-		return res
+		return builtinResult(fnTyped.Fn(positionalArgs...), env, ctx)
+
+	case *contextBuiltin:
+		return builtinResult(fnTyped.Fn(positionalArgs, env, ctx), env, ctx)
 
 	default:
 		return newErrorWithTrace(
@@ -2591,9 +3563,11 @@ func extendFunctionEnvWithNamed(
 		index        int
 		hasDefault   bool
 		defaultValue ast.Expression
+		keywordOnly  bool
 	}
 	paramMap := make(map[string]paramInfo)
 	paramOrder := []string{} // Preserve parameter order
+	varargsName, kwargsName := "", ""
 
 	for i, pExpr := range fn.Parameters {
 		var name string
@@ -2605,6 +3579,14 @@ func extendFunctionEnvWithNamed(
 			name = param.Value
 		case *ast.Parameter:
 			name = param.Name.Value
+			if param.Variadic {
+				varargsName = name
+				continue
+			}
+			if param.KwVariadic {
+				kwargsName = name
+				continue
+			}
 			if param.DefaultValue != nil {
 				hasDefault = true
 				defaultValue = param.DefaultValue
@@ -2617,14 +3599,19 @@ func extendFunctionEnvWithNamed(
 			index:        i,
 			hasDefault:   hasDefault,
 			defaultValue: defaultValue,
+			keywordOnly:  varargsName != "",
 		}
 		paramOrder = append(paramOrder, name)
 	}
 
-	// Check for unknown keyword arguments
-	for name := range namedArgs {
+	// Check for unknown keyword arguments; **kwargs collects them instead
+	extraNamed := make(map[string]object.Object)
+	for name, val := range namedArgs {
 		if _, exists := paramMap[name]; !exists {
-			return nil, newErrorWithTrace("unknown keyword argument: %s", node, ctx, name)
+			if kwargsName == "" {
+				return nil, newErrorWithTrace("unknown keyword argument: %s", node, ctx, name)
+			}
+			extraNamed[name] = val
 		}
 	}
 
@@ -2636,13 +3623,13 @@ func extendFunctionEnvWithNamed(
 		// Check if this parameter was passed by name
 		if namedVal, hasNamed := namedArgs[paramName]; hasNamed {
 			// Check if it was also passed positionally
-			if positionalIndex <= info.index && info.index < len(positionalArgs) {
+			if !info.keywordOnly && positionalIndex <= info.index && info.index < len(positionalArgs) {
 				return nil, newErrorWithTrace(
 					"parameter '%s' got multiple values (positional and keyword)",
 					node, ctx, paramName)
 			}
 			env.Set(paramName, namedVal)
-		} else if positionalIndex < len(positionalArgs) {
+		} else if !info.keywordOnly && positionalIndex < len(positionalArgs) {
 			// Use positional argument
 			env.Set(paramName, positionalArgs[positionalIndex])
 			positionalIndex++
@@ -2665,10 +3652,17 @@ func extendFunctionEnvWithNamed(
 		}
 	}
 
+	if varargsName != "" {
+		env.Set(varargsName, &object.Array{Elements: remainingArgs(positionalArgs, positionalIndex)})
+	}
+	if kwargsName != "" {
+		env.Set(kwargsName, newKeywordHash(extraNamed))
+	}
+
 	// Store type hints for parameters if present
 	for _, pExpr := range fn.Parameters {
 		if param, ok := pExpr.(*ast.Parameter); ok {
-			if param.TypeHint != nil {
+			if param.TypeHint != nil && !param.Variadic && !param.KwVariadic {
 				if typeHintIdent, ok := param.TypeHint.(*ast.Identifier); ok {
 					typeHintKey := "__type_hint__" + param.Name.Value
 					env.Set(typeHintKey, &object.String{Value: typeHintIdent.Value})
@@ -2713,6 +3707,8 @@ func evalBoundMethodCallWithNamed(
 		env:            methodEnv,
 		depth:          ctx.depth + 1,
 		MethodGrimoire: methodOwner,
+		Self:           instance,
+		Method:         method,
 	}
 
 	// Bind arguments using the new helper function that handles named args
@@ -2740,9 +3736,11 @@ func bindMethodParametersWithNamed(
 		hasDefault   bool
 		defaultValue ast.Expression
 		isSelf       bool
+		keywordOnly  bool
 	}
 	paramMap := make(map[string]paramInfo)
 	paramOrder := []string{}
+	varargsName, kwargsName := "", ""
 
 	for i, pExpr := range method.Parameters {
 		var name string
@@ -2754,6 +3752,14 @@ func bindMethodParametersWithNamed(
 			name = param.Value
 		case *ast.Parameter:
 			name = param.Name.Value
+			if param.Variadic {
+				varargsName = name
+				continue
+			}
+			if param.KwVariadic {
+				kwargsName = name
+				continue
+			}
 			if param.DefaultValue != nil {
 				hasDefault = true
 				defaultValue = param.DefaultValue
@@ -2767,14 +3773,19 @@ func bindMethodParametersWithNamed(
 			hasDefault:   hasDefault,
 			defaultValue: defaultValue,
 			isSelf:       name == "self",
+			keywordOnly:  varargsName != "",
 		}
 		paramOrder = append(paramOrder, name)
 	}
 
-	// Check for unknown keyword arguments
-	for name := range namedArgs {
+	// Check for unknown keyword arguments; **kwargs collects them instead
+	extraNamed := make(map[string]object.Object)
+	for name, val := range namedArgs {
 		if _, exists := paramMap[name]; !exists {
-			return newErrorWithTrace("unknown keyword argument: %s", node, methodCtx, name)
+			if kwargsName == "" {
+				return newErrorWithTrace("unknown keyword argument: %s", node, methodCtx, name)
+			}
+			extraNamed[name] = val
 		}
 	}
 
@@ -2801,13 +3812,13 @@ func bindMethodParametersWithNamed(
 					}
 				}
 			}
-			if positionalIndex <= effectiveIndex && effectiveIndex < len(positionalArgs) {
+			if !info.keywordOnly && positionalIndex <= effectiveIndex && effectiveIndex < len(positionalArgs) {
 				return newErrorWithTrace(
 					"parameter '%s' got multiple values (positional and keyword)",
 					node, methodCtx, paramName)
 			}
 			methodEnv.Set(paramName, namedVal)
-		} else if positionalIndex < len(positionalArgs) {
+		} else if !info.keywordOnly && positionalIndex < len(positionalArgs) {
 			// Use positional argument
 			methodEnv.Set(paramName, positionalArgs[positionalIndex])
 			positionalIndex++
@@ -2820,13 +3831,41 @@ func bindMethodParametersWithNamed(
 		}
 	}
 
+	if varargsName != "" {
+		methodEnv.Set(varargsName, &object.Array{Elements: remainingArgs(positionalArgs, positionalIndex)})
+	}
+	if kwargsName != "" {
+		methodEnv.Set(kwargsName, newKeywordHash(extraNamed))
+	}
+
 	return nil
 }
 
-func evalDotExpression(
-	node *ast.DotExpression,
-	env *object.Environment,
-	ctx *CallContext,
+// remainingArgs copies the arguments from index start onward; these are
+// what a *args parameter collects.
+func remainingArgs(args []object.Object, start int) []object.Object {
+	rest := []object.Object{}
+	if start < len(args) {
+		rest = append(rest, args[start:]...)
+	}
+	return rest
+}
+
+// newKeywordHash builds the Hash a **kwargs parameter receives. Keywords
+// arrive in a Go map, so they are stored in name order.
+func newKeywordHash(named map[string]object.Object) *object.Hash {
+	hash := object.NewHash()
+	for _, name := range slices.Sorted(maps.Keys(named)) {
+		key := &object.String{Value: name}
+		hash.Set(key.HashKey(), object.HashPair{Key: key, Value: named[name]})
+	}
+	return hash
+}
+
+func evalDotExpression(
+	node *ast.DotExpression,
+	env *object.Environment,
+	ctx *CallContext,
 ) object.Object {
 	// Note: Removed literal blocking code - primitives are now automatically wrapped
 	leftObj := Eval(node.Left, env, ctx)
@@ -2930,6 +3969,9 @@ func evalDotExpression(
 	// Handle static method calls on grimoire classes
 	if grimoire, ok := leftObj.(*object.Grimoire); ok {
 		methodName := node.Right.Value
+		if member, ok := grimoire.EnumMember(methodName); ok {
+			return member
+		}
 		method, exists := grimoire.Methods[methodName]
 		if !exists {
 			// Build suggestion context for helpful error message
@@ -2949,6 +3991,47 @@ func evalDotExpression(
 		}
 	}
 
+	// Handle generator methods
+	if gen, ok := leftObj.(*object.Generator); ok {
+		switch node.Right.Value {
+		case "next":
+			return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+				return generatorNext(gen, node, ctx)
+			}}
+		case "close":
+			return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+				gen.Close()
+				return NONE
+			}}
+		case "iter":
+			return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+				return gen
+			}}
+		default:
+			return newErrorWithTrace("generator has no method: %s", node, ctx, node.Right.Value)
+		}
+	}
+
+	if dec, ok := leftObj.(*object.Decimal); ok {
+		return evalDecimalMethod(dec, node, ctx)
+	}
+
+	if set, ok := leftObj.(*object.Set); ok {
+		return evalSetMethod(set, node, env, ctx)
+	}
+
+	if ch, ok := leftObj.(*object.Channel); ok {
+		return evalChannelMethod(ch, node, ctx)
+	}
+
+	if goroutine, ok := leftObj.(*object.Goroutine); ok {
+		return evalGoroutineMethod(goroutine, node, ctx)
+	}
+
+	if isByteData(leftObj) {
+		return evalBytesMethod(leftObj, node, ctx)
+	}
+
 	// Handle CaughtError access
 	if caughtErr, ok := leftObj.(*object.CaughtError); ok {
 		switch node.Right.Value {
@@ -2973,7 +4056,7 @@ func evalDotExpression(
 
 	// Wrap primitives on demand for method access (e.g., "hello".upper())
 	switch leftObj.Type() {
-	case object.INTEGER_OBJ, object.FLOAT_OBJ, object.STRING_OBJ, object.BOOLEAN_OBJ, object.ARRAY_OBJ:
+	case object.INTEGER_OBJ, object.BIG_INTEGER_OBJ, object.FLOAT_OBJ, object.STRING_OBJ, object.BOOLEAN_OBJ, object.ARRAY_OBJ:
 		leftObj = wrapPrimitiveForMethod(leftObj, env)
 	}
 
@@ -2984,6 +4067,10 @@ func evalDotExpression(
 
 	fieldOrMethodName := node.Right.Value
 
+	if prop, ok := instance.Grimoire.Properties[fieldOrMethodName]; ok {
+		return evalPropertyAccessor(instance, fieldOrMethodName, prop.Getter, nil, env, ctx)
+	}
+
 	if val, found := instance.Env.Get(fieldOrMethodName); found {
 		return val
 	}
@@ -3072,15 +4159,16 @@ func evalHashLiteral(
 	env *object.Environment,
 	ctx *CallContext,
 ) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
-	for keyNode, valueNode := range node.Pairs {
+	hash := object.NewHash()
+	for _, keyNode := range node.Keys {
+		valueNode := node.Pairs[keyNode]
 		key := Eval(keyNode, env, ctx)
 		if isError(key) {
 			return key
 		}
 		// Unwrap primitives that may have been wrapped in Instance objects
 		unwrappedKey := unwrapPrimitive(key)
-		hashKey, ok := unwrappedKey.(object.Hashable)
+		hashKey, ok := object.HashKeyOf(unwrappedKey)
 		if !ok {
 			return newErrorWithTrace("unusable as hash key: %s", node, ctx, unwrappedKey.Type())
 		}
@@ -3088,10 +4176,11 @@ func evalHashLiteral(
 		if isError(value) {
 			return value
 		}
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: unwrappedKey, Value: value}
+		if errObj := hash.Set(hashKey, object.HashPair{Key: unwrappedKey, Value: value}); errObj != nil {
+			return errObj
+		}
 	}
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalTupleLiteral(
@@ -3108,6 +4197,12 @@ func evalTupleLiteral(
 }
 
 func evalIndexExpression(left, index object.Object, node ast.Node, ctx *CallContext) object.Object {
+	if inst, ok := left.(*object.Instance); ok {
+		if result, ok := callSpecialMethod(inst, "__getitem__", []object.Object{index}, ctx); ok {
+			return result
+		}
+	}
+
 	// Unwrap instances to get the underlying primitive values
 	unwrappedLeft := unwrapPrimitive(left)
 	unwrappedIndex := unwrapPrimitive(index)
@@ -3119,6 +4214,8 @@ func evalIndexExpression(left, index object.Object, node ast.Node, ctx *CallCont
 		return evalArrayIndexExpression(unwrappedLeft, unwrappedIndex, node, ctx)
 	case unwrappedLeft.Type() == object.MAP_OBJ:
 		return evalHashIndexExpression(unwrappedLeft, unwrappedIndex, node, ctx)
+	case unwrappedLeft.Type() == object.BYTES_OBJ || unwrappedLeft.Type() == object.BYTE_ARRAY_OBJ:
+		return evalBytesIndexExpression(unwrappedLeft, unwrappedIndex, node, ctx)
 	case unwrappedLeft.Type() == object.STRING_OBJ && unwrappedIndex.Type() == object.INTEGER_OBJ:
 		result := evalStringIndexExpression(unwrappedLeft, unwrappedIndex, node, ctx)
 		// If the original left was an instance, wrap the result back to maintain consistency
@@ -3212,11 +4309,14 @@ func evalHashIndexExpression(
 	}
 	// Unwrap primitives that may have been wrapped in Instance objects
 	unwrappedIndex := unwrapPrimitive(index)
-	key, ok := unwrappedIndex.(object.Hashable)
+	key, ok := object.HashKeyOf(unwrappedIndex)
 	if !ok {
 		return newErrorWithTrace("unusable as hash key: %s", node, ctx, unwrappedIndex.Type())
 	}
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok, errObj := hashObject.Lookup(key, unwrappedIndex)
+	if errObj != nil {
+		return errObj
+	}
 	if !ok {
 		return NONE
 	}
@@ -3238,6 +4338,8 @@ func evalArrayIndexExpression(
 		return newErrorWithTrace("array index must be INTEGER, got %s", node, ctx, index.Type())
 	}
 
+	arrayObject.RLock()
+	defer arrayObject.RUnlock()
 	idx := intIndex.Value
 	maxIndex := int64(len(arrayObject.Elements) - 1)
 
@@ -3273,8 +4375,10 @@ func evalStringIndexExpression(
 		return newErrorWithTrace("string index must be INTEGER, got %s", node, ctx, index.Type())
 	}
 
+	// Strings are indexed by code point, not by byte
+	runes := []rune(stringObj.Value)
 	idx := intIndex.Value
-	strLen := int64(len(stringObj.Value))
+	strLen := int64(len(runes))
 	maxIndex := strLen - 1
 
 	// Handle negative indices like Python
@@ -3292,25 +4396,36 @@ func evalStringIndexExpression(
 	}
 
 	// Return a single-character string
-	return &object.String{Value: string(stringObj.Value[idx])}
+	return &object.String{Value: string(runes[idx])}
+}
+
+// evalSliceBounds evaluates the start, end and step of a slice expression.
+// Omitted parts are returned as nil.
+func evalSliceBounds(node *ast.SliceExpression, env *object.Environment, ctx *CallContext) (start, end, step, err object.Object) {
+	parts := []ast.Expression{node.Start, node.End, node.Step}
+	values := make([]object.Object, len(parts))
+	for i, part := range parts {
+		if part == nil {
+			continue
+		}
+		value := Eval(part, env, ctx)
+		if isError(value) {
+			return nil, nil, nil, value
+		}
+		values[i] = value
+	}
+	return values[0], values[1], values[2], nil
 }
 
-func evalSliceExpression(left, start, end object.Object, node ast.Node, ctx *CallContext) object.Object {
+func evalSliceExpression(left, start, end, step object.Object, node ast.Node, ctx *CallContext) object.Object {
 	// Unwrap instances to get the underlying primitive values
 	unwrappedLeft := unwrapPrimitive(left)
-	// Also unwrap start and end indices in case they are Integer instances
-	if start != nil {
-		start = unwrapPrimitive(start)
-	}
-	if end != nil {
-		end = unwrapPrimitive(end)
-	}
 
-	switch {
-	case unwrappedLeft.Type() == object.STRING_OBJ:
-		result := evalStringSliceExpression(unwrappedLeft, start, end, node, ctx)
+	switch unwrappedLeft.Type() {
+	case object.STRING_OBJ:
+		result := evalStringSliceExpression(unwrappedLeft, start, end, step, node, ctx)
 		// If the original left was an instance, wrap the result back to maintain consistency
-		if left.Type() == object.INSTANCE_OBJ {
+		if left.Type() == object.INSTANCE_OBJ && !isError(result) {
 			var currentEnv *object.Environment
 			if ctx != nil && ctx.env != nil {
 				currentEnv = ctx.env
@@ -3320,116 +4435,249 @@ func evalSliceExpression(left, start, end object.Object, node ast.Node, ctx *Cal
 			return wrapPrimitive(result, currentEnv, ctx)
 		}
 		return result
-	case unwrappedLeft.Type() == object.ARRAY_OBJ:
-		return evalArraySliceExpression(unwrappedLeft, start, end, node, ctx)
+	case object.ARRAY_OBJ, object.TUPLE_OBJ:
+		return evalArraySliceExpression(unwrappedLeft, start, end, step, node, ctx)
+	case object.BYTES_OBJ, object.BYTE_ARRAY_OBJ:
+		return evalBytesSliceExpression(unwrappedLeft, start, end, step, node, ctx)
 	default:
 		return newErrorWithTrace("slice operator not supported: %s", node, ctx, left.Type())
 	}
 }
 
-func evalStringSliceExpression(str, start, end object.Object, node ast.Node, ctx *CallContext) object.Object {
-	stringObj, ok := str.(*object.String)
-	if !ok {
-		return newErrorWithTrace("slice operation not supported on %s", node, ctx, str.Type())
-	}
-
-	strLen := int64(len(stringObj.Value))
-	var startIdx, endIdx int64
-
-	// Handle start index
-	if start != nil {
-		startInt, ok := start.(*object.Integer)
+// sliceIndices resolves the parts of a slice against a sequence of the
+// given length the way Python does: indices are clamped rather than
+// reported out of range, and a negative step walks backwards from the end.
+// It returns the first selected index, the step and the number of elements.
+func sliceIndices(length int64, start, end, step object.Object, node ast.Node, ctx *CallContext) (int64, int64, int64, object.Object) {
+	stepVal := int64(1)
+	if step != nil && step != NONE {
+		step = unwrapPrimitive(step)
+		stepInt, ok := step.(*object.Integer)
 		if !ok {
-			return newErrorWithTrace("slice start index must be INTEGER, got %s", node, ctx, start.Type())
+			return 0, 0, 0, newErrorWithTrace("slice step must be INTEGER, got %s", node, ctx, step.Type())
 		}
-		startIdx = startInt.Value
-		if startIdx < 0 {
-			startIdx = strLen + startIdx
+		if stepInt.Value == 0 {
+			return 0, 0, 0, newErrorWithTrace("slice step cannot be zero", node, ctx)
 		}
-	} else {
-		startIdx = 0
+		stepVal = stepInt.Value
 	}
 
-	// Handle end index
-	if end != nil {
-		endInt, ok := end.(*object.Integer)
+	// Valid positions run from lower to upper; a backwards slice may stop
+	// just before the first element.
+	lower, upper := int64(0), length
+	if stepVal < 0 {
+		lower, upper = -1, length-1
+	}
+	bound := func(obj object.Object, name string, def int64) (int64, object.Object) {
+		if obj == nil || obj == NONE {
+			return def, nil
+		}
+		obj = unwrapPrimitive(obj)
+		intObj, ok := obj.(*object.Integer)
 		if !ok {
-			return newErrorWithTrace("slice end index must be INTEGER, got %s", node, ctx, end.Type())
+			return 0, newErrorWithTrace("slice %s index must be INTEGER, got %s", node, ctx, name, obj.Type())
 		}
-		endIdx = endInt.Value
-		if endIdx < 0 {
-			endIdx = strLen + endIdx
+		idx := intObj.Value
+		if idx < 0 {
+			idx += length
+			if idx < lower {
+				idx = lower
+			}
+		} else if idx > upper {
+			idx = upper
 		}
-	} else {
-		endIdx = strLen
+		return idx, nil
 	}
 
-	// Bounds checking
-	if startIdx < 0 {
-		startIdx = 0
+	startDefault, endDefault := lower, upper
+	if stepVal < 0 {
+		startDefault, endDefault = upper, lower
 	}
-	if endIdx > strLen {
-		endIdx = strLen
+	startIdx, err := bound(start, "start", startDefault)
+	if err != nil {
+		return 0, 0, 0, err
 	}
-	if startIdx > endIdx {
-		startIdx = endIdx
+	endIdx, err := bound(end, "end", endDefault)
+	if err != nil {
+		return 0, 0, 0, err
 	}
 
-	// Extract substring
-	return &object.String{Value: stringObj.Value[startIdx:endIdx]}
+	var count int64
+	if stepVal > 0 && startIdx < endIdx {
+		count = (endIdx-startIdx-1)/stepVal + 1
+	} else if stepVal < 0 && startIdx > endIdx {
+		count = (startIdx-endIdx-1)/(-stepVal) + 1
+	}
+	return startIdx, stepVal, count, nil
 }
 
-func evalArraySliceExpression(arr, start, end object.Object, node ast.Node, ctx *CallContext) object.Object {
-	arrayObj, ok := arr.(*object.Array)
-	if !ok {
-		return newErrorWithTrace("slice operation not supported on %s", node, ctx, arr.Type())
+// evalSliceAssignment replaces the elements selected by a slice of an Array
+// or ByteArray with the elements of value. A simple slice may change the
+// length of the sequence; an extended slice with a step must be given
+// exactly as many elements as it selects.
+func evalSliceAssignment(target object.Object, slice *sliceIndex, value object.Object, node ast.Node, ctx *CallContext) object.Object {
+	var length int64
+	switch seq := target.(type) {
+	case *object.Array:
+		length = int64(seq.Len())
+	case *object.ByteArray:
+		length = int64(len(seq.Value))
+	case *object.Instance:
+		if unwrapped := unwrapPrimitive(seq); unwrapped != target {
+			return evalSliceAssignment(unwrapped, slice, value, node, ctx)
+		}
+		return newErrorWithTrace("slice assignment not supported: %s", node, ctx, target.Type())
+	default:
+		return newErrorWithTrace("slice assignment not supported: %s", node, ctx, target.Type())
+	}
+
+	first, step, count, err := sliceIndices(length, slice.start, slice.end, slice.step, node, ctx)
+	if err != nil {
+		return err
 	}
 
-	arrLen := int64(len(arrayObj.Elements))
-	var startIdx, endIdx int64
+	env := object.NewEnvironment()
+	if ctx != nil && ctx.env != nil {
+		env = ctx.env
+	}
+	items, err := iterableElements(unwrapPrimitive(value), node, env, ctx)
+	if err != nil {
+		return err
+	}
+	if step != 1 && int64(len(items)) != count {
+		return newErrorWithTrace("cannot assign %d elements to extended slice of length %d",
+			node, ctx, len(items), count)
+	}
 
-	// Handle start index
-	if start != nil {
-		startInt, ok := start.(*object.Integer)
-		if !ok {
-			return newErrorWithTrace("slice start index must be INTEGER, got %s", node, ctx, start.Type())
+	switch seq := target.(type) {
+	case *object.Array:
+		seq.Lock()
+		defer seq.Unlock()
+		if int64(len(seq.Elements)) != length {
+			return newErrorWithTrace("array changed size during slice assignment", node, ctx)
 		}
-		startIdx = startInt.Value
-		if startIdx < 0 {
-			startIdx = arrLen + startIdx
+		if step != 1 {
+			for i, item := range items {
+				seq.Elements[first+int64(i)*step] = item
+			}
+			return value
 		}
-	} else {
-		startIdx = 0
-	}
+		elements := make([]object.Object, 0, length-count+int64(len(items)))
+		elements = append(elements, seq.Elements[:first]...)
+		elements = append(elements, items...)
+		seq.Elements = append(elements, seq.Elements[first+count:]...)
 
-	// Handle end index
-	if end != nil {
-		endInt, ok := end.(*object.Integer)
-		if !ok {
-			return newErrorWithTrace("slice end index must be INTEGER, got %s", node, ctx, end.Type())
+	case *object.ByteArray:
+		data := make([]byte, len(items))
+		for i, item := range items {
+			byteVal, ok := unwrapPrimitive(item).(*object.Integer)
+			if !ok || byteVal.Value < 0 || byteVal.Value > 255 {
+				return newErrorWithTrace("bytearray values must be integers in range 0-255, got %s", node, ctx, item.Inspect())
+			}
+			data[i] = byte(byteVal.Value)
 		}
-		endIdx = endInt.Value
-		if endIdx < 0 {
-			endIdx = arrLen + endIdx
+		if step != 1 {
+			for i, b := range data {
+				seq.Value[first+int64(i)*step] = b
+			}
+			return value
 		}
-	} else {
-		endIdx = arrLen
+		updated := make([]byte, 0, length-count+int64(len(data)))
+		updated = append(updated, seq.Value[:first]...)
+		updated = append(updated, data...)
+		seq.Value = append(updated, seq.Value[first+count:]...)
+	}
+	return value
+}
+
+// evalBytesIndexExpression returns the byte at index as an Integer.
+func evalBytesIndexExpression(
+	data, index object.Object,
+	node ast.Node,
+	ctx *CallContext,
+) object.Object {
+	value, _ := object.ByteData(data)
+	intIndex, ok := index.(*object.Integer)
+	if !ok {
+		return newErrorWithTrace("bytes index must be INTEGER, got %s", node, ctx, index.Type())
+	}
+
+	idx := intIndex.Value
+	if idx < 0 {
+		idx += int64(len(value))
+	}
+	if idx < 0 || idx >= int64(len(value)) {
+		return newErrorWithTrace("bytes index out of bounds: %d (length: %d)", node, ctx, intIndex.Value, len(value))
+	}
+	return object.NewInteger(int64(value[idx]))
+}
+
+// evalBytesSliceExpression slices Bytes or ByteArray, returning a copy of
+// the same type.
+func evalBytesSliceExpression(data, start, end, step object.Object, node ast.Node, ctx *CallContext) object.Object {
+	value, _ := object.ByteData(data)
+	first, stepVal, count, err := sliceIndices(int64(len(value)), start, end, step, node, ctx)
+	if err != nil {
+		return err
+	}
+
+	slice := make([]byte, count)
+	for i := range slice {
+		slice[i] = value[first+int64(i)*stepVal]
+	}
+	if data.Type() == object.BYTE_ARRAY_OBJ {
+		return &object.ByteArray{Value: slice}
+	}
+	return &object.Bytes{Value: slice}
+}
+
+func evalStringSliceExpression(str, start, end, step object.Object, node ast.Node, ctx *CallContext) object.Object {
+	stringObj, ok := str.(*object.String)
+	if !ok {
+		return newErrorWithTrace("slice operation not supported on %s", node, ctx, str.Type())
+	}
+
+	runes := []rune(stringObj.Value)
+	first, stepVal, count, err := sliceIndices(int64(len(runes)), start, end, step, node, ctx)
+	if err != nil {
+		return err
+	}
+	if stepVal == 1 {
+		return &object.String{Value: string(runes[first : first+count])}
 	}
 
-	// Bounds checking
-	if startIdx < 0 {
-		startIdx = 0
+	selected := make([]rune, count)
+	for i := range selected {
+		selected[i] = runes[first+int64(i)*stepVal]
 	}
-	if endIdx > arrLen {
-		endIdx = arrLen
+	return &object.String{Value: string(selected)}
+}
+
+// evalArraySliceExpression slices an Array or Tuple, returning a new
+// sequence of the same type.
+func evalArraySliceExpression(arr, start, end, step object.Object, node ast.Node, ctx *CallContext) object.Object {
+	var elements []object.Object
+	switch seq := arr.(type) {
+	case *object.Array:
+		elements = seq.Snapshot()
+	case *object.Tuple:
+		elements = seq.Elements
+	default:
+		return newErrorWithTrace("slice operation not supported on %s", node, ctx, arr.Type())
 	}
-	if startIdx > endIdx {
-		startIdx = endIdx
+
+	first, stepVal, count, err := sliceIndices(int64(len(elements)), start, end, step, node, ctx)
+	if err != nil {
+		return err
 	}
 
-	// Extract subarray
-	newElements := make([]object.Object, endIdx-startIdx)
-	copy(newElements, arrayObj.Elements[startIdx:endIdx])
+	newElements := make([]object.Object, count)
+	for i := range newElements {
+		newElements[i] = elements[first+int64(i)*stepVal]
+	}
+	if arr.Type() == object.TUPLE_OBJ {
+		return &object.Tuple{Elements: newElements}
+	}
 	return &object.Array{Elements: newElements}
 }
 
@@ -3441,19 +4689,31 @@ func extendFunctionEnv(
 ) *object.Environment {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
-	// Bind parameters: support ast.Identifier or ast.Parameter nodes
+	// Bind parameters: support ast.Identifier or ast.Parameter nodes.
+	// Parameters after *args are keyword-only, so they stop taking
+	// positional arguments once it has been bound.
+	limit := len(args)
 	for i, pExpr := range fn.Parameters {
 		switch param := pExpr.(type) {
 		case *ast.Identifier:
 			name := param.Value
-			if i < len(args) {
+			if i < limit {
 				env.Set(name, args[i])
 			} else {
 				env.Set(name, NONE)
 			}
 		case *ast.Parameter:
 			name := param.Name.Value
-			if i < len(args) {
+			if param.Variadic {
+				env.Set(name, &object.Array{Elements: remainingArgs(args, i)})
+				limit = i
+				continue
+			}
+			if param.KwVariadic {
+				env.Set(name, newKeywordHash(nil))
+				continue
+			}
+			if i < limit {
 				env.Set(name, args[i])
 			} else if param.DefaultValue != nil {
 				// Default value may refer to an identifier or an expression
@@ -3533,6 +4793,9 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment, ctx *CallCont
 	if builtin, ok := builtins[node.Value]; ok {
 		return builtin
 	}
+	if builtin, ok := contextBuiltins[node.Value]; ok {
+		return builtin
+	}
 	if node.Value == "None" {
 		return object.NONE
 	}
@@ -3685,6 +4948,9 @@ func evalPrefixExpression(
 			return right
 		}
 		unwrappedRight := unwrapPrimitive(right)
+		if bigOperand, ok := unwrappedRight.(*object.BigInteger); ok {
+			return object.NewBigInteger(new(big.Int).Not(bigOperand.Value))
+		}
 		intOperand, ok := unwrappedRight.(*object.Integer)
 		if !ok {
 			return newErrorWithTrace("unsupported operand type for ~: %s", node, ctx, unwrappedRight.Type())
@@ -3728,6 +4994,11 @@ func evalPrefixExpression(
 		return object.NewInteger(newValDec)
 	case "-":
 		right := Eval(node.Right, env, ctx)
+		if inst, ok := right.(*object.Instance); ok {
+			if result, ok := callSpecialMethod(inst, "__neg__", nil, ctx); ok {
+				return result
+			}
+		}
 		return evalMinusPrefixOperatorExpression(right, env, ctx)
 	default:
 		return newErrorWithTrace("unknown operator: %s%s", node, ctx,
@@ -3760,6 +5031,11 @@ func evalInfixExpression(
 		}
 	}
 
+	// Grimoire instances may overload operators with special methods
+	if result, ok := evalOperatorOverload(operator, left, right, ctx); ok {
+		return result
+	}
+
 	if debugPrimitiveWrapping && operator == "//" {
 		fmt.Fprintf(os.Stderr, "EVAL_INFIX: %s between %T and %T in %s\n", operator, left, right, getContextName(ctx))
 	}
@@ -3790,6 +5066,16 @@ func evalInfixExpression(
 		return result
 	}
 
+	// Enum members are singletons and compare by identity
+	if isEnumMember(left) || isEnumMember(right) {
+		switch operator {
+		case "==":
+			return nativeBoolToBooleanObject(left == right)
+		case "!=":
+			return nativeBoolToBooleanObject(left != right)
+		}
+	}
+
 	switch {
 	case unwrappedLeft.Type() == object.INTEGER_OBJ && unwrappedRight.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, unwrappedLeft, unwrappedRight, node, ctx, env)
@@ -3880,6 +5166,39 @@ func evalInfixExpression(
 			return nativeBoolToBooleanObject(true)
 		}
 		return newErrorWithTrace("operation not supported with None: %s", node, ctx, operator)
+	case isByteData(unwrappedLeft) || isByteData(unwrappedRight):
+		return evalBytesInfixExpression(operator, unwrappedLeft, unwrappedRight, node, ctx)
+	case unwrappedLeft.Type() == object.SET_OBJ && unwrappedRight.Type() == object.SET_OBJ:
+		return evalSetInfixExpression(operator, unwrappedLeft.(*object.Set), unwrappedRight.(*object.Set), node, ctx)
+	case unwrappedLeft.Type() == object.DECIMAL_OBJ || unwrappedRight.Type() == object.DECIMAL_OBJ:
+		// Integers convert exactly and floats by their shortest representation
+		leftDec, leftOk := object.ToDecimal(unwrappedLeft)
+		rightDec, rightOk := object.ToDecimal(unwrappedRight)
+		if leftOk && rightOk {
+			return evalDecimalInfixExpression(operator, leftDec, rightDec, node, ctx)
+		}
+		switch operator {
+		case "==":
+			return FALSE
+		case "!=":
+			return TRUE
+		}
+		return newErrorWithTrace("type mismatch: %s %s %s", node, ctx,
+			unwrappedLeft.Type(), operator, unwrappedRight.Type())
+	case unwrappedLeft.Type() == object.BIG_INTEGER_OBJ || unwrappedRight.Type() == object.BIG_INTEGER_OBJ:
+		leftBig, leftOk := object.ToBigInt(unwrappedLeft)
+		rightBig, rightOk := object.ToBigInt(unwrappedRight)
+		if leftOk && rightOk {
+			return evalBigIntegerInfixExpression(operator, leftBig, rightBig, node, ctx)
+		}
+		// Mixed with a float, a big integer behaves like any other integer
+		if unwrappedLeft.Type() == object.FLOAT_OBJ || unwrappedRight.Type() == object.FLOAT_OBJ {
+			return evalInfixExpression(operator,
+				&object.Float{Value: toFloat(unwrappedLeft)}, &object.Float{Value: toFloat(unwrappedRight)},
+				node, ctx, env)
+		}
+		return newErrorWithTrace("type mismatch: %s %s %s", node, ctx,
+			unwrappedLeft.Type(), operator, unwrappedRight.Type())
 	case left.Type() == object.INSTANCE_OBJ && right.Type() == object.INSTANCE_OBJ:
 		// Handle instance operations specially
 		leftInstance := left.(*object.Instance)
@@ -4035,12 +5354,183 @@ func evalInfixExpression(
 	)
 }
 
+// operatorMethods maps binary operators to the special methods a grimoire
+// can define to overload them, along with the reflected method tried on
+// the right operand when the left one does not handle the operator.
+var operatorMethods = map[string]struct{ method, reflected string }{
+	"+":  {"__add__", "__radd__"},
+	"-":  {"__sub__", "__rsub__"},
+	"*":  {"__mul__", "__rmul__"},
+	"/":  {"__div__", "__rdiv__"},
+	"//": {"__floordiv__", "__rfloordiv__"},
+	"%":  {"__mod__", "__rmod__"},
+	"**": {"__pow__", "__rpow__"},
+	"&":  {"__and__", "__rand__"},
+	"|":  {"__or__", "__ror__"},
+	"^":  {"__xor__", "__rxor__"},
+	"<<": {"__lshift__", "__rlshift__"},
+	">>": {"__rshift__", "__rrshift__"},
+	"==": {"__eq__", "__eq__"},
+	"!=": {"__ne__", "__ne__"},
+	"<":  {"__lt__", "__gt__"},
+	">":  {"__gt__", "__lt__"},
+	"<=": {"__le__", "__ge__"},
+	">=": {"__ge__", "__le__"},
+}
+
+// isSpecialMethodName reports whether a method name has the __name__ form
+// used for operator overloading.
+func isSpecialMethodName(name string) bool {
+	return len(name) > 4 && strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__")
+}
+
+// callSpecialMethod calls a special method on an instance if its grimoire
+// defines one. ok is false when the method does not exist.
+func callSpecialMethod(
+	inst *object.Instance,
+	name string,
+	args []object.Object,
+	ctx *CallContext,
+) (object.Object, bool) {
+	if _, exists := inst.Grimoire.Methods[name]; !exists {
+		return nil, false
+	}
+	if ctx == nil {
+		ctx = &CallContext{FunctionName: inst.Grimoire.Name + "." + name, env: inst.Env}
+	}
+	return evalGrimoireMethodCall(inst, name, args, inst.Env, ctx), true
+}
+
+// instanceHashKey hashes a grimoire instance through its __hash__ method,
+// which must return an integer. Wrapped primitives without one hash like
+// their value; other instances are not hashable.
+func instanceHashKey(inst *object.Instance) (object.HashKey, bool) {
+	if inst.Grimoire.IsEnum {
+		// Enum members are singletons, so hash by grimoire and member name
+		h := fnv.New64a()
+		h.Write([]byte(inst.Inspect()))
+		return object.HashKey{Type: object.INSTANCE_OBJ, Value: h.Sum64()}, true
+	}
+	result, ok := callSpecialMethod(inst, "__hash__", nil, nil)
+	if !ok {
+		if value := unwrapPrimitive(inst); value != object.Object(inst) {
+			return object.HashKeyOf(value)
+		}
+		return object.HashKey{}, false
+	}
+	switch v := unwrapPrimitive(result).(type) {
+	case *object.Integer:
+		return object.HashKey{Type: object.INSTANCE_OBJ, Value: uint64(v.Value)}, true
+	case *object.BigInteger:
+		key := v.HashKey()
+		key.Type = object.INSTANCE_OBJ
+		return key, true
+	}
+	return object.HashKey{}, false
+}
+
+// instancesEqual compares two instances used as hash keys with __eq__,
+// returning the error it raised, if any.
+func instancesEqual(a, b *object.Instance) (bool, object.Object) {
+	if a.Grimoire.IsEnum || b.Grimoire.IsEnum {
+		return a == b, nil
+	}
+	if result, ok := callSpecialMethod(a, "__eq__", []object.Object{b}, nil); ok {
+		if isError(result) {
+			return false, result
+		}
+		return isTruthy(result), nil
+	}
+	left, right := unwrapPrimitive(a), unwrapPrimitive(b)
+	if left == object.Object(a) || right == object.Object(b) {
+		return false, nil
+	}
+	return object.KeysEqual(left, right)
+}
+
+// evalOperatorOverload dispatches a binary operator to the special method
+// of a grimoire instance operand. ok is false when neither operand
+// overloads the operator and normal evaluation should continue.
+func evalOperatorOverload(
+	operator string,
+	left, right object.Object,
+	ctx *CallContext,
+) (object.Object, bool) {
+	if operator == "in" || operator == "not in" {
+		container, ok := right.(*object.Instance)
+		if !ok {
+			return nil, false
+		}
+		result, ok := callSpecialMethod(container, "__contains__", []object.Object{left}, ctx)
+		if !ok || isError(result) {
+			return result, ok
+		}
+		return nativeBoolToBooleanObject(isTruthy(result) == (operator == "in")), true
+	}
+
+	names, ok := operatorMethods[operator]
+	if !ok {
+		return nil, false
+	}
+	if inst, ok := left.(*object.Instance); ok {
+		if result, ok := callSpecialMethod(inst, names.method, []object.Object{right}, ctx); ok {
+			return result, true
+		}
+	}
+	if inst, ok := right.(*object.Instance); ok {
+		if result, ok := callSpecialMethod(inst, names.reflected, []object.Object{left}, ctx); ok {
+			return result, true
+		}
+	}
+
+	// Without __ne__, != is the negation of __eq__
+	if operator == "!=" {
+		if result, ok := evalOperatorOverload("==", left, right, ctx); ok {
+			if isError(result) {
+				return result, true
+			}
+			return nativeBoolToBooleanObject(!isTruthy(result)), true
+		}
+	}
+	return nil, false
+}
+
+// specialComparison evaluates a comparison used by sorting and membership
+// helpers through special methods. ok is false when neither operand
+// defines the method; err is the error the method raised, if any.
+func specialComparison(method, reflected string, a, b object.Object) (result bool, err object.Object, ok bool) {
+	if inst, isInstance := a.(*object.Instance); isInstance {
+		if value, called := callSpecialMethod(inst, method, []object.Object{b}, nil); called {
+			return comparisonResult(value)
+		}
+	}
+	if inst, isInstance := b.(*object.Instance); isInstance {
+		if value, called := callSpecialMethod(inst, reflected, []object.Object{a}, nil); called {
+			return comparisonResult(value)
+		}
+	}
+	return false, nil, false
+}
+
+// comparisonResult interprets the value a comparison method returned.
+func comparisonResult(value object.Object) (bool, object.Object, bool) {
+	if isError(value) {
+		return false, value, true
+	}
+	return isTruthy(value), nil, true
+}
+
 func toFloat(obj object.Object) float64 {
 	switch obj := obj.(type) {
 	case *object.Integer:
 		return float64(obj.Value)
+	case *object.BigInteger:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
 	case *object.Float:
 		return obj.Value
+	case *object.Decimal:
+		return obj.Float64()
 	default:
 		return 0.0
 	}
@@ -4236,18 +5726,26 @@ func evalMinusPrefixOperatorExpression(
 	// Unwrap primitive values from instances if needed
 	unwrapped := unwrapPrimitive(right)
 
-	if unwrapped.Type() != object.INTEGER_OBJ && unwrapped.Type() != object.FLOAT_OBJ {
+	if unwrapped.Type() != object.INTEGER_OBJ && unwrapped.Type() != object.FLOAT_OBJ &&
+		unwrapped.Type() != object.BIG_INTEGER_OBJ && unwrapped.Type() != object.DECIMAL_OBJ {
 		// Unknown operand type for prefix minus
-		return newError("unknown operator: -%s", right.Type())
+		return newErrorWithTrace("unknown operator: -%s", ctx.Node, ctx, right.Type())
 	}
 	switch unwrapped := unwrapped.(type) {
 	case *object.Integer:
+		if unwrapped.Value == math.MinInt64 {
+			return object.NewBigInteger(new(big.Int).Neg(big.NewInt(unwrapped.Value)))
+		}
 		return object.NewInteger(-unwrapped.Value)
+	case *object.BigInteger:
+		return object.NewBigInteger(new(big.Int).Neg(unwrapped.Value))
+	case *object.Decimal:
+		return unwrapped.Neg()
 	case *object.Float:
 		return &object.Float{Value: -unwrapped.Value}
 	default:
 		// Fallback for unexpected types
-		return newError("unknown type for minus operator: %s", unwrapped.Type())
+		return newErrorWithTrace("unknown type for minus operator: %s", ctx.Node, ctx, unwrapped.Type())
 	}
 }
 
@@ -4263,15 +5761,29 @@ func evalIntegerInfixExpression(
 
 	switch operator {
 	case "+":
-		return object.NewInteger(leftVal + rightVal)
+		sum := leftVal + rightVal
+		if (rightVal > 0 && sum < leftVal) || (rightVal < 0 && sum > leftVal) {
+			return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal), node, ctx)
+		}
+		return object.NewInteger(sum)
 	case "-":
-		return object.NewInteger(leftVal - rightVal)
+		diff := leftVal - rightVal
+		if (rightVal > 0 && diff > leftVal) || (rightVal < 0 && diff < leftVal) {
+			return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal), node, ctx)
+		}
+		return object.NewInteger(diff)
 	case "*":
-		return object.NewInteger(leftVal * rightVal)
+		if product, ok := mulInt64(leftVal, rightVal); ok {
+			return object.NewInteger(product)
+		}
+		return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal), node, ctx)
 	case "/":
 		if rightVal == 0 {
 			return newErrorWithTrace("division by zero", node, ctx)
 		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal), node, ctx)
+		}
 		return object.NewInteger(leftVal / rightVal)
 	case "%":
 		if rightVal == 0 {
@@ -4283,6 +5795,12 @@ func evalIntegerInfixExpression(
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
 	case "**":
+		if rightVal >= 0 {
+			if result, ok := powInt64(leftVal, rightVal); ok {
+				return object.NewInteger(result)
+			}
+			return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal), node, ctx)
+		}
 		return object.NewInteger(int64(math.Pow(float64(leftVal), float64(rightVal))))
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
@@ -4293,6 +5811,9 @@ func evalIntegerInfixExpression(
 	case "<=":
 		return nativeBoolToBooleanObject(leftVal <= rightVal)
 	case "<<":
+		if rightVal >= 0 && (rightVal >= 63 || (leftVal<<uint(rightVal))>>uint(rightVal) != leftVal) {
+			return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal), node, ctx)
+		}
 		return object.NewInteger(leftVal << uint(rightVal))
 	case ">>":
 		return object.NewInteger(leftVal >> uint(rightVal))
@@ -4306,6 +5827,9 @@ func evalIntegerInfixExpression(
 		if rightVal == 0 {
 			return newErrorWithTrace("integer division by zero", node, ctx)
 		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal), node, ctx)
+		}
 		return object.NewInteger(leftVal / rightVal)
 	default:
 		return newErrorWithTrace("unknown operator: %s %s %s",
@@ -4313,28 +5837,245 @@ func evalIntegerInfixExpression(
 	}
 }
 
-func evalCompoundAssignment(
-	node *ast.InfixExpression,
-	env *object.Environment,
-	ctx *CallContext,
-) object.Object {
-	rightVal := Eval(node.Right, env, ctx)
-	if isError(rightVal) {
-		return rightVal
+// mulInt64 multiplies two int64 values, reporting false on overflow.
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
 	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
 
-	switch leftNode := node.Left.(type) {
-	case *ast.Identifier:
-		currVal, ok := env.Get(leftNode.Value)
-		if !ok {
-			return newErrorWithTrace("undefined variable: %s", node, ctx, leftNode.Value)
+// powInt64 raises base to a non-negative exponent by squaring, reporting
+// false on overflow.
+func powInt64(base, exp int64) (int64, bool) {
+	result := int64(1)
+	for exp > 0 {
+		var ok bool
+		if exp&1 == 1 {
+			if result, ok = mulInt64(result, base); !ok {
+				return 0, false
+			}
 		}
-
-		newVal := applyCompoundOperator(node.Operator, currVal, rightVal, node, ctx, env)
-		if isError(newVal) {
-			return newVal
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = mulInt64(base, base); !ok {
+				return 0, false
+			}
 		}
-
+	}
+	return result, true
+}
+
+// evalDecimalInfixExpression applies an operator to two decimals. Division
+// by "/" rounds to the decimal context; "//" and "%" truncate toward zero
+// like integer division.
+func evalDecimalInfixExpression(
+	operator string,
+	leftVal, rightVal *object.Decimal,
+	node ast.Node,
+	ctx *CallContext,
+) object.Object {
+	switch operator {
+	case "+":
+		return leftVal.Add(rightVal)
+	case "-":
+		return leftVal.Sub(rightVal)
+	case "*":
+		return leftVal.Mul(rightVal)
+	case "/":
+		result, err := leftVal.Quo(rightVal)
+		if err != nil {
+			return newErrorWithTrace("division by zero", node, ctx)
+		}
+		return result
+	case "//":
+		result, err := leftVal.QuoInt(rightVal)
+		if err != nil {
+			return newErrorWithTrace("integer division by zero", node, ctx)
+		}
+		return result
+	case "%":
+		result, err := leftVal.Rem(rightVal)
+		if err != nil {
+			return newErrorWithTrace("modulo by zero", node, ctx)
+		}
+		return result
+	case "**":
+		exp := rightVal.Int()
+		if !rightVal.IsInteger() || !exp.IsInt64() {
+			return newErrorWithTrace("decimal exponent must be an integer, got %s", node, ctx, rightVal.String())
+		}
+		result, err := leftVal.Pow(exp.Int64())
+		if err != nil {
+			return newErrorWithTrace("division by zero", node, ctx)
+		}
+		return result
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newErrorWithTrace("unknown operator: DECIMAL %s DECIMAL", node, ctx, operator)
+	}
+}
+
+// evalDecimalMethod returns the bound method named by node on a decimal.
+func evalDecimalMethod(dec *object.Decimal, node *ast.DotExpression, ctx *CallContext) object.Object {
+	switch node.Right.Value {
+	case "round":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) > 2 {
+				return newError("round() takes at most 2 arguments, got %d", len(args))
+			}
+			places := 0
+			_, rounding := object.DecimalContext()
+			if len(args) > 0 {
+				p, ok := args[0].(*object.Integer)
+				if !ok {
+					return newError("round() places must be an integer, got %s", args[0].Type())
+				}
+				places = int(p.Value)
+			}
+			if len(args) > 1 {
+				mode, ok := args[1].(*object.String)
+				if !ok || !object.IsRoundingMode(mode.Value) {
+					return newError("round() got an unknown rounding mode: %s", args[1].Inspect())
+				}
+				rounding = mode.Value
+			}
+			return dec.Round(places, rounding)
+		}}
+	case "normalize":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return dec.Normalize()
+		}}
+	case "abs":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return dec.Abs()
+		}}
+	case "is_integer":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return nativeBoolToBooleanObject(dec.IsInteger())
+		}}
+	case "to_int":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return object.NewBigInteger(dec.Int())
+		}}
+	case "to_float":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return &object.Float{Value: dec.Float64()}
+		}}
+	case "to_string":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return &object.String{Value: dec.String()}
+		}}
+	default:
+		return newErrorWithTrace("decimal has no method: %s", node, ctx, node.Right.Value)
+	}
+}
+
+// evalBigIntegerInfixExpression applies an integer operator with arbitrary
+// precision. It follows the same rules as evalIntegerInfixExpression
+// (division truncates toward zero) and demotes results that fit an int64.
+func evalBigIntegerInfixExpression(
+	operator string,
+	leftVal, rightVal *big.Int,
+	node ast.Node,
+	ctx *CallContext,
+) object.Object {
+	switch operator {
+	case "+":
+		return object.NewBigInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return object.NewBigInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return object.NewBigInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/", "//":
+		if rightVal.Sign() == 0 {
+			if operator == "//" {
+				return newErrorWithTrace("integer division by zero", node, ctx)
+			}
+			return newErrorWithTrace("division by zero", node, ctx)
+		}
+		return object.NewBigInteger(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newErrorWithTrace("modulo by zero", node, ctx)
+		}
+		return object.NewBigInteger(new(big.Int).Rem(leftVal, rightVal))
+	case "**":
+		if rightVal.Sign() < 0 {
+			l, _ := new(big.Float).SetInt(leftVal).Float64()
+			r, _ := new(big.Float).SetInt(rightVal).Float64()
+			return object.NewInteger(int64(math.Pow(l, r)))
+		}
+		return object.NewBigInteger(new(big.Int).Exp(leftVal, rightVal, nil))
+	case "<<", ">>":
+		if !rightVal.IsInt64() || rightVal.Sign() < 0 {
+			return newErrorWithTrace("invalid shift count: %s", node, ctx, rightVal.String())
+		}
+		if operator == "<<" {
+			return object.NewBigInteger(new(big.Int).Lsh(leftVal, uint(rightVal.Int64())))
+		}
+		return object.NewBigInteger(new(big.Int).Rsh(leftVal, uint(rightVal.Int64())))
+	case "&":
+		return object.NewBigInteger(new(big.Int).And(leftVal, rightVal))
+	case "|":
+		return object.NewBigInteger(new(big.Int).Or(leftVal, rightVal))
+	case "^":
+		return object.NewBigInteger(new(big.Int).Xor(leftVal, rightVal))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newErrorWithTrace("unknown operator: %s %s %s",
+			node, ctx, object.INTEGER_OBJ, operator, object.INTEGER_OBJ)
+	}
+}
+
+func evalCompoundAssignment(
+	node *ast.InfixExpression,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	rightVal := Eval(node.Right, env, ctx)
+	if isError(rightVal) {
+		return rightVal
+	}
+
+	switch leftNode := node.Left.(type) {
+	case *ast.Identifier:
+		currVal, ok := env.Get(leftNode.Value)
+		if !ok {
+			return newErrorWithTrace("undefined variable: %s", node, ctx, leftNode.Value)
+		}
+
+		newVal := applyCompoundOperator(node.Operator, currVal, rightVal, node, ctx, env)
+		if isError(newVal) {
+			return newVal
+		}
+
 		// If the current value is an Instance, update its wrapped value
 		if instance, ok := currVal.(*object.Instance); ok {
 			instance.Env.Set("value", newVal)
@@ -4390,6 +6131,13 @@ func applyCompoundOperator(
 		return nil, false
 	}
 
+	// Big integers share the infix rules
+	if unwrapPrimitive(leftVal).Type() == object.BIG_INTEGER_OBJ ||
+		unwrapPrimitive(rightVal).Type() == object.BIG_INTEGER_OBJ {
+		return evalInfixExpression(strings.TrimSuffix(operator, "="),
+			unwrapPrimitive(leftVal), unwrapPrimitive(rightVal), node, ctx, env)
+	}
+
 	// Try to extract integers first
 	if lInt, ok := extractInteger(leftVal); ok {
 		rInt, ok := extractInteger(rightVal)
@@ -4398,17 +6146,9 @@ func applyCompoundOperator(
 				node, ctx, rightVal.Type())
 		}
 		switch operator {
-		case "+=":
-			return object.NewInteger(lInt.Value + rInt.Value)
-		case "-=":
-			return object.NewInteger(lInt.Value - rInt.Value)
-		case "*=":
-			return object.NewInteger(lInt.Value * rInt.Value)
-		case "/=":
-			if rInt.Value == 0 {
-				return newErrorWithTrace("division by zero", node, ctx)
-			}
-			return object.NewInteger(lInt.Value / rInt.Value)
+		case "+=", "-=", "*=", "/=":
+			// Promotes to a big integer on overflow like the infix operators
+			return evalIntegerInfixExpression(strings.TrimSuffix(operator, "="), lInt, rInt, node, ctx, env)
 		default:
 			return newErrorWithTrace("unknown operator: %s", node, ctx, operator)
 		}
@@ -4526,6 +6266,114 @@ func isError(obj object.Object) bool {
 		isErrorWithTrace(obj)
 }
 
+// newGenerator wraps the body of a generator spell, already bound to its
+// call environment, in a Generator object. The body only starts running
+// when the first value is requested.
+func newGenerator(
+	name string,
+	body *ast.BlockStatement,
+	env *object.Environment,
+	ctx *CallContext,
+) *object.Generator {
+	// The body outlives the call, so it must not hold on to the caller's
+	// environment
+	parent := detachContext(ctx)
+	return object.NewGenerator(name, func(gen *object.GeneratorBody) object.Object {
+		genCtx := &CallContext{
+			FunctionName: name,
+			Node:         body,
+			Parent:       parent,
+			env:          env,
+			Generator:    gen,
+		}
+		if parent != nil {
+			genCtx.MethodGrimoire = parent.MethodGrimoire
+		}
+
+		result := Eval(body, env, genCtx)
+		if isError(result) && !isGeneratorExit(result) {
+			return result
+		}
+		return nil
+	})
+}
+
+// detachContext copies the context chain without its environments, so a
+// suspended generator body keeps its caller's stack trace but not the
+// caller's variables, which usually include the generator itself.
+func detachContext(ctx *CallContext) *CallContext {
+	if ctx == nil {
+		return nil
+	}
+	detached := *ctx
+	detached.env = nil
+	detached.calls = nil
+	detached.Parent = detachContext(ctx.Parent)
+	return &detached
+}
+
+// evalYieldStatement hands a value to the generator's consumer and waits
+// to be resumed. If the consumer closes the generator instead, a
+// GeneratorExit error unwinds the body (running any resolve blocks).
+func evalYieldStatement(
+	node *ast.YieldStatement,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	var gen *object.GeneratorBody
+	for c := ctx; c != nil; c = c.Parent {
+		if c.Generator != nil {
+			gen = c.Generator
+			break
+		}
+	}
+	if gen == nil {
+		return newErrorWithTrace("'yield' used outside of a generator spell", node, ctx)
+	}
+
+	var value object.Object = NONE
+	if node.Value != nil {
+		value = Eval(node.Value, env, ctx)
+		if isError(value) {
+			return value
+		}
+	}
+
+	if !gen.Yield(value) {
+		details := map[string]object.Object{
+			"errorType": &object.String{Value: "GeneratorExit"},
+		}
+		return newCustomErrorWithTrace("GeneratorExit", "generator closed", node, ctx, details)
+	}
+	return NONE
+}
+
+// generatorNext returns the next value of a generator, the error its body
+// raised, or a StopIteration error once it is exhausted.
+func generatorNext(gen *object.Generator, node ast.Node, ctx *CallContext) object.Object {
+	if value, ok := gen.Next(); ok {
+		return value
+	}
+	if gen.Err != nil {
+		err := gen.Err
+		gen.Err = nil
+		return err
+	}
+	details := map[string]object.Object{
+		"errorType": &object.String{Value: "StopIteration"},
+	}
+	return newCustomErrorWithTrace("StopIteration", "generator exhausted", node, ctx, details)
+}
+
+func isGeneratorExit(obj object.Object) bool {
+	if errWithTrace, ok := obj.(*object.ErrorWithTrace); ok && errWithTrace.CustomDetails != nil {
+		if errorType, ok := errWithTrace.CustomDetails["errorType"].(*object.String); ok {
+			return errorType.Value == "GeneratorExit"
+		}
+	}
+	return false
+}
+
 // isStopIterationError checks if an object represents a StopIteration error
 func isStopIterationError(obj object.Object) bool {
 	switch err := obj.(type) {
@@ -4596,6 +6444,9 @@ func evalWhileStatement(
 	n := len(node.Body.Statements)
 
 	for {
+		if errObj := checkCancelled(node, ctx); errObj != nil {
+			return errObj
+		}
 		condition := Eval(node.Condition, env, whileCtx)
 		if isError(condition) {
 			return condition
@@ -4659,7 +6510,13 @@ func isTruthy(obj object.Object) bool {
 	case *object.Tuple:
 		return len(obj.Elements) > 0
 	case *object.Hash:
-		return len(obj.Pairs) > 0
+		return obj.Len() > 0
+	case *object.Set:
+		return obj.Len() > 0
+	case *object.Bytes:
+		return len(obj.Value) > 0
+	case *object.ByteArray:
+		return len(obj.Value) > 0
 	case *object.None:
 		return false
 	case *object.Instance:
@@ -4693,15 +6550,19 @@ func evalForStatement(
 
 	switch iter := iterable.(type) {
 	case *object.Array:
-		result := processArrayIteration(iter.Elements, fs, env, forCtx, ctx)
+		result := processArrayIteration(iter.Snapshot(), fs, env, forCtx, ctx)
 		if result != NONE {
 			return result
 		}
+	case *object.Generator:
+		return processGeneratorIteration(iter, fs, env, forCtx, ctx)
+	case *object.Channel:
+		return processChannelIteration(iter, fs, env, forCtx, ctx)
 	case *object.String:
 		// Convert string to array of character strings for iteration
-		charElements := make([]object.Object, len(iter.Value))
-		for i, char := range iter.Value {
-			charElements[i] = &object.String{Value: string(char)}
+		charElements := make([]object.Object, 0, utf8.RuneCountInString(iter.Value))
+		for _, char := range iter.Value {
+			charElements = append(charElements, &object.String{Value: string(char)})
 		}
 		result := processArrayIteration(charElements, fs, env, forCtx, ctx)
 		if result != NONE {
@@ -4710,13 +6571,24 @@ func evalForStatement(
 	case *object.Hash:
 		// Iterate over hash keys by default
 		var elements []object.Object
-		for _, pair := range iter.Pairs {
+		for _, pair := range iter.Ordered() {
 			elements = append(elements, pair.Key)
 		}
 		result := processArrayIteration(elements, fs, env, forCtx, ctx)
 		if result != NONE {
 			return result
 		}
+	case *object.Set:
+		result := processArrayIteration(iter.Items(), fs, env, forCtx, ctx)
+		if result != NONE {
+			return result
+		}
+	case *object.Bytes, *object.ByteArray:
+		data, _ := object.ByteData(iter)
+		result := processArrayIteration(byteElements(data), fs, env, forCtx, ctx)
+		if result != NONE {
+			return result
+		}
 	case *object.Instance:
 		// First check if the instance has an iter method
 		if _, ok := iter.Grimoire.Methods["iter"]; ok {
@@ -4725,11 +6597,17 @@ func evalForStatement(
 			if isError(iteratorObj) {
 				return iteratorObj
 			}
+			if gen, ok := iteratorObj.(*object.Generator); ok {
+				return processGeneratorIteration(gen, fs, env, forCtx, ctx)
+			}
 
 			// Use the iterator to iterate
 			if iterator, ok := iteratorObj.(*object.Instance); ok {
 				// Process elements one at a time instead of collecting all first
 				for {
+					if errObj := checkCancelled(fs, ctx); errObj != nil {
+						return errObj
+					}
 					if _, hasNext := iterator.Grimoire.Methods["next"]; hasNext {
 						nextValue := evalGrimoireMethodCall(iterator, "next", []object.Object{}, env, forCtx)
 
@@ -4811,9 +6689,9 @@ func evalForStatement(
 			// Handle String instances by getting the value and converting to characters
 			if valueObj, ok := iter.Env.Get("value"); ok {
 				if str, ok := valueObj.(*object.String); ok {
-					charElements := make([]object.Object, len(str.Value))
-					for i, char := range str.Value {
-						charElements[i] = &object.String{Value: string(char)}
+					charElements := make([]object.Object, 0, utf8.RuneCountInString(str.Value))
+					for _, char := range str.Value {
+						charElements = append(charElements, &object.String{Value: string(char)})
 					}
 					result := processArrayIteration(charElements, fs, env, forCtx, ctx)
 					if result != NONE {
@@ -4824,6 +6702,14 @@ func evalForStatement(
 		} else {
 			return newErrorWithTrace("for loop: %s instance is not iterable", fs, ctx, iter.Grimoire.Name)
 		}
+	case *object.Grimoire:
+		if !iter.IsEnum {
+			return newErrorWithTrace("for loop: grimoire %s is not iterable", fs, ctx, iter.Name)
+		}
+		result := processArrayIteration(enumMembers(iter), fs, env, forCtx, ctx)
+		if result != NONE {
+			return result
+		}
 	default:
 		return newErrorWithTrace("for loop requires an iterable, got %s", fs, ctx, iterable.Type())
 	}
@@ -4847,8 +6733,10 @@ func evalInOperator(
 
 	case *object.Array:
 		// Check if element exists in array
-		for _, elem := range container.Elements {
-			if isObjectEqual(left, elem) {
+		for _, elem := range container.Snapshot() {
+			if equal, errObj := isObjectEqual(left, elem); errObj != nil {
+				return errObj
+			} else if equal {
 				return nativeBoolToBooleanObject(true)
 			}
 		}
@@ -4856,13 +6744,37 @@ func evalInOperator(
 
 	case *object.Hash:
 		// Check if key exists in hash
-		hashKey, ok := left.(object.Hashable)
+		hashKey, ok := object.HashKeyOf(left)
 		if !ok {
 			return newErrorWithTrace("unusable as hash key: %T", node, ctx, left)
 		}
-		_, exists := container.Pairs[hashKey.HashKey()]
+		_, exists, errObj := container.Lookup(hashKey, left)
+		if errObj != nil {
+			return errObj
+		}
 		return nativeBoolToBooleanObject(exists)
 
+	case *object.Bytes, *object.ByteArray:
+		data, _ := object.ByteData(container)
+		if sub, ok := object.ByteData(left); ok {
+			return nativeBoolToBooleanObject(bytes.Contains(data, sub))
+		}
+		if value, ok := left.(*object.Integer); ok {
+			return nativeBoolToBooleanObject(value.Value >= 0 && value.Value <= 255 &&
+				bytes.IndexByte(data, byte(value.Value)) >= 0)
+		}
+		return newErrorWithTrace("'in' operator with bytes requires bytes or an integer on left side, got %s", node, ctx, left.Type())
+
+	case *object.Set:
+		if _, ok := object.HashKeyOf(left); !ok {
+			return newErrorWithTrace("unhashable type in set: %s", node, ctx, left.Type())
+		}
+		found, errObj := container.Contains(left)
+		if errObj != nil {
+			return errObj
+		}
+		return nativeBoolToBooleanObject(found)
+
 	case *object.Instance:
 		// Handle wrapped containers
 		if container.Grimoire.Name == "String" {
@@ -4878,8 +6790,10 @@ func evalInOperator(
 		} else if container.Grimoire.Name == "Array" {
 			if elementsObj, ok := container.Env.Get("elements"); ok {
 				if arr, ok := elementsObj.(*object.Array); ok {
-					for _, elem := range arr.Elements {
-						if isObjectEqual(left, elem) {
+					for _, elem := range arr.Snapshot() {
+						if equal, errObj := isObjectEqual(left, elem); errObj != nil {
+							return errObj
+						} else if equal {
 							return nativeBoolToBooleanObject(true)
 						}
 					}
@@ -4889,99 +6803,926 @@ func evalInOperator(
 		}
 		return newErrorWithTrace("'in' operator not supported for %s instance", node, ctx, container.Grimoire.Name)
 
+	case *object.Grimoire:
+		// Enum membership: Color.RED in Color
+		if container.IsEnum {
+			member, ok := left.(*object.Instance)
+			return nativeBoolToBooleanObject(ok && member.Grimoire == container)
+		}
+		return newErrorWithTrace("'in' operator not supported for grimoire %s", node, ctx, container.Name)
+
 	default:
 		return newErrorWithTrace("'in' operator not supported for %s", node, ctx, right.Type())
 	}
 }
 
-// Helper function to check if two objects are equal
-func isObjectEqual(left, right object.Object) bool {
-	// Unwrap primitives to handle instances
-	unwrappedLeft := unwrapPrimitive(left)
-	unwrappedRight := unwrapPrimitive(right)
-
-	if unwrappedLeft.Type() != unwrappedRight.Type() {
-		return false
+// Helper function to check if two objects are equal
+func isObjectEqual(left, right object.Object) (bool, object.Object) {
+	if equal, err, ok := specialComparison("__eq__", "__eq__", left, right); ok {
+		return equal, err
+	}
+
+	// Unwrap primitives to handle instances
+	unwrappedLeft := unwrapPrimitive(left)
+	unwrappedRight := unwrapPrimitive(right)
+
+	if isByteData(unwrappedLeft) && isByteData(unwrappedRight) {
+		leftData, _ := object.ByteData(unwrappedLeft)
+		rightData, _ := object.ByteData(unwrappedRight)
+		return bytes.Equal(leftData, rightData), nil
+	}
+
+	if unwrappedLeft.Type() != unwrappedRight.Type() {
+		return false, nil
+	}
+
+	switch leftObj := unwrappedLeft.(type) {
+	case *object.Integer:
+		rightObj := unwrappedRight.(*object.Integer)
+		return leftObj.Value == rightObj.Value, nil
+	case *object.BigInteger:
+		rightObj := unwrappedRight.(*object.BigInteger)
+		return leftObj.Value.Cmp(rightObj.Value) == 0, nil
+	case *object.Decimal:
+		rightObj := unwrappedRight.(*object.Decimal)
+		return leftObj.Cmp(rightObj) == 0, nil
+	case *object.Set:
+		rightObj := unwrappedRight.(*object.Set)
+		return leftObj.Equals(rightObj)
+	case *object.Float:
+		rightObj := unwrappedRight.(*object.Float)
+		return leftObj.Value == rightObj.Value, nil
+	case *object.String:
+		rightObj := unwrappedRight.(*object.String)
+		return leftObj.Value == rightObj.Value, nil
+	case *object.Boolean:
+		rightObj := unwrappedRight.(*object.Boolean)
+		return leftObj.Value == rightObj.Value, nil
+	default:
+		// For other types, use pointer comparison as fallback
+		return unwrappedLeft == unwrappedRight, nil
+	}
+}
+
+// Helper function to process array iteration with variable unpacking and loop body execution
+func processArrayIteration(
+	elements []object.Object,
+	fs *ast.ForStatement,
+	env *object.Environment,
+	forCtx *CallContext,
+	ctx *CallContext,
+) object.Object {
+	for _, elem := range elements {
+		if errObj := checkCancelled(fs, ctx); errObj != nil {
+			return errObj
+		}
+		if errObj := bindLoopTarget(fs.Variable, elem, env, fs, ctx); errObj != nil {
+			return errObj
+		}
+
+		if fs.Body != nil {
+			loopResult := Eval(fs.Body, env, forCtx)
+			if loopResult != nil {
+				rt := getObjectType(loopResult)
+				if rt == string(object.STOP.Type()) {
+					break // Exit only this loop, don't propagate STOP
+				}
+				if rt == string(object.SKIP.Type()) {
+					continue // Skip to the next element in the outer loop
+				}
+				if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+					rt == object.CUSTOM_ERROR_OBJ || isErrorWithTrace(loopResult) {
+					return loopResult
+				}
+			}
+		}
+	}
+	return NONE
+}
+
+// processGeneratorIteration runs a for loop over a generator, pulling one
+// value at a time. Leaving the loop early closes the generator.
+func processGeneratorIteration(
+	gen *object.Generator,
+	fs *ast.ForStatement,
+	env *object.Environment,
+	forCtx *CallContext,
+	ctx *CallContext,
+) object.Object {
+	for {
+		if errObj := checkCancelled(fs, ctx); errObj != nil {
+			gen.Close()
+			return errObj
+		}
+		value, ok := gen.Next()
+		if !ok {
+			if gen.Err != nil {
+				return gen.Err
+			}
+			return NONE
+		}
+
+		if errObj := bindLoopTarget(fs.Variable, value, env, fs, ctx); errObj != nil {
+			gen.Close()
+			return errObj
+		}
+
+		if fs.Body == nil {
+			continue
+		}
+		loopResult := Eval(fs.Body, env, forCtx)
+		if loopResult == nil {
+			continue
+		}
+		rt := getObjectType(loopResult)
+		if rt == string(object.STOP.Type()) {
+			gen.Close()
+			return NONE
+		}
+		if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+			rt == object.CUSTOM_ERROR_OBJ || isErrorWithTrace(loopResult) {
+			gen.Close()
+			return loopResult
+		}
+	}
+}
+
+// processChannelIteration runs a for loop over a channel, receiving until
+// the channel is closed and drained.
+func processChannelIteration(
+	ch *object.Channel,
+	fs *ast.ForStatement,
+	env *object.Environment,
+	forCtx *CallContext,
+	ctx *CallContext,
+) object.Object {
+	for {
+		value, ok, err := ch.ReceiveUntil(cancelSignal(ctx))
+		if err != nil {
+			return cancelledError(currentGoroutine(ctx), fs, ctx)
+		}
+		if !ok {
+			return NONE
+		}
+
+		if errObj := bindLoopTarget(fs.Variable, value, env, fs, ctx); errObj != nil {
+			return errObj
+		}
+
+		if fs.Body == nil {
+			continue
+		}
+		loopResult := Eval(fs.Body, env, forCtx)
+		if loopResult == nil {
+			continue
+		}
+		rt := getObjectType(loopResult)
+		if rt == string(object.STOP.Type()) {
+			return NONE
+		}
+		if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+			rt == object.CUSTOM_ERROR_OBJ || isErrorWithTrace(loopResult) {
+			return loopResult
+		}
+	}
+}
+
+// drainChannel receives every value until the channel is closed.
+func drainChannel(ch *object.Channel) []object.Object {
+	var elements []object.Object
+	for {
+		value, ok := ch.Receive()
+		if !ok {
+			return elements
+		}
+		elements = append(elements, value)
+	}
+}
+
+// drainGenerator collects every remaining value of a generator.
+func drainGenerator(gen *object.Generator) ([]object.Object, object.Object) {
+	var elements []object.Object
+	for {
+		value, ok := gen.Next()
+		if !ok {
+			return elements, gen.Err
+		}
+		elements = append(elements, value)
+	}
+}
+
+// bindLoopTarget assigns one iteration value to a loop target, unpacking
+// tuples and arrays for `for a, b in ...` targets. It returns nil on success.
+func bindLoopTarget(
+	target ast.Expression,
+	elem object.Object,
+	env *object.Environment,
+	node ast.Node,
+	ctx *CallContext,
+) object.Object {
+	switch varExpr := target.(type) {
+	case *ast.Identifier:
+		if varExpr.Value != ".." {
+			env.Set(varExpr.Value, elem)
+		}
+	case *ast.TupleLiteral:
+		var items []object.Object
+		if tupObj, ok := elem.(*object.Tuple); ok {
+			items = tupObj.Elements
+		} else if arrObj, ok := elem.(*object.Array); ok {
+			items = arrObj.Elements
+		} else {
+			return newErrorWithTrace("cannot unpack non-iterable element: %s",
+				node, ctx, elem.Type())
+		}
+		if len(varExpr.Elements) != len(items) {
+			return newErrorWithTrace("unpacking mismatch: expected %d values, got %d",
+				node, ctx, len(varExpr.Elements), len(items))
+		}
+		for i, t := range varExpr.Elements {
+			ident, ok := t.(*ast.Identifier)
+			if !ok {
+				return newErrorWithTrace("invalid assignment target in for loop", node, ctx)
+			}
+			if ident.Value != ".." {
+				env.Set(ident.Value, items[i])
+			}
+		}
+	default:
+		env.Set(target.String(), elem)
+	}
+	return nil
+}
+
+// iterableElements materializes the values a for loop would visit when
+// iterating over obj. Instances are iterated through their iter()/next()
+// protocol until StopIteration.
+func iterableElements(
+	obj object.Object,
+	node ast.Node,
+	env *object.Environment,
+	ctx *CallContext,
+) ([]object.Object, object.Object) {
+	switch iter := obj.(type) {
+	case *object.Array:
+		return iter.Snapshot(), nil
+	case *object.Tuple:
+		return iter.Elements, nil
+	case *object.String:
+		var chars []object.Object
+		for _, char := range iter.Value {
+			chars = append(chars, &object.String{Value: string(char)})
+		}
+		return chars, nil
+	case *object.Hash:
+		var keys []object.Object
+		for _, pair := range iter.Ordered() {
+			keys = append(keys, pair.Key)
+		}
+		return keys, nil
+	case *object.Set:
+		return iter.Items(), nil
+	case *object.Bytes:
+		return byteElements(iter.Value), nil
+	case *object.ByteArray:
+		return byteElements(iter.Value), nil
+	case *object.Generator:
+		return drainGenerator(iter)
+	case *object.Channel:
+		return drainChannel(iter), nil
+	case *object.Grimoire:
+		if iter.IsEnum {
+			return enumMembers(iter), nil
+		}
+		return nil, newErrorWithTrace("grimoire %s is not iterable", node, ctx, iter.Name)
+	case *object.Instance:
+		if _, ok := iter.Grimoire.Methods["iter"]; ok {
+			iteratorObj := evalGrimoireMethodCall(iter, "iter", []object.Object{}, env, ctx)
+			if isError(iteratorObj) {
+				return nil, iteratorObj
+			}
+			if gen, ok := iteratorObj.(*object.Generator); ok {
+				return drainGenerator(gen)
+			}
+			iterator, ok := iteratorObj.(*object.Instance)
+			if !ok {
+				return nil, newErrorWithTrace("iter must return an iterator instance", node, ctx)
+			}
+			if _, ok := iterator.Grimoire.Methods["next"]; !ok {
+				return nil, newErrorWithTrace("Iterator must have next method", node, ctx)
+			}
+			var elements []object.Object
+			for {
+				next := evalGrimoireMethodCall(iterator, "next", []object.Object{}, env, ctx)
+				if isStopIterationError(next) {
+					return elements, nil
+				}
+				if isError(next) {
+					return nil, next
+				}
+				elements = append(elements, next)
+			}
+		}
+		if iter.Grimoire.Name == "Array" || iter.Grimoire.Name == "String" {
+			if unwrapped := unwrapPrimitive(iter); unwrapped != obj {
+				return iterableElements(unwrapped, node, env, ctx)
+			}
+		}
+		return nil, newErrorWithTrace("%s instance is not iterable", node, ctx, iter.Grimoire.Name)
+	default:
+		return nil, newErrorWithTrace("%s is not iterable", node, ctx, obj.Type())
+	}
+}
+
+func evalListComprehension(
+	node *ast.ListComprehension,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	compEnv := object.NewEnclosedEnvironment(env)
+	compCtx := &CallContext{
+		FunctionName: "list_comprehension",
+		Node:         node,
+		Parent:       ctx,
+		env:          compEnv,
+	}
+
+	elements := []object.Object{}
+	errObj := evalComprehensionClauses(node.Clauses, compEnv, node, compCtx, func() object.Object {
+		elem := Eval(node.Element, compEnv, compCtx)
+		if isError(elem) {
+			return elem
+		}
+		elements = append(elements, elem)
+		return nil
+	})
+	if errObj != nil {
+		return errObj
+	}
+	return &object.Array{Elements: elements}
+}
+
+func evalHashComprehension(
+	node *ast.HashComprehension,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	compEnv := object.NewEnclosedEnvironment(env)
+	compCtx := &CallContext{
+		FunctionName: "hash_comprehension",
+		Node:         node,
+		Parent:       ctx,
+		env:          compEnv,
+	}
+
+	hash := object.NewHash()
+	errObj := evalComprehensionClauses(node.Clauses, compEnv, node, compCtx, func() object.Object {
+		key := Eval(node.Key, compEnv, compCtx)
+		if isError(key) {
+			return key
+		}
+		unwrappedKey := unwrapPrimitive(key)
+		hashKey, ok := object.HashKeyOf(unwrappedKey)
+		if !ok {
+			return newErrorWithTrace("unusable as hash key: %s", node, compCtx, unwrappedKey.Type())
+		}
+		value := Eval(node.Value, compEnv, compCtx)
+		if isError(value) {
+			return value
+		}
+		return hash.Set(hashKey, object.HashPair{Key: unwrappedKey, Value: value})
+	})
+	if errObj != nil {
+		return errObj
+	}
+	return hash
+}
+
+func evalSetLiteral(
+	node *ast.SetLiteral,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	set := object.NewSet()
+	for _, elemNode := range node.Elements {
+		elem := Eval(elemNode, env, ctx)
+		if isError(elem) {
+			return elem
+		}
+		if errObj := addToSet(set, elem, elemNode, ctx); errObj != nil {
+			return errObj
+		}
+	}
+	return set
+}
+
+// addToSet adds elem to set, returning an error if it is unhashable or
+// its __eq__ raised.
+func addToSet(set *object.Set, elem object.Object, node ast.Node, ctx *CallContext) object.Object {
+	unwrapped := unwrapPrimitive(elem)
+	hashable, errObj := set.Add(unwrapped)
+	if errObj != nil {
+		return errObj
+	}
+	if !hashable {
+		return newErrorWithTrace("unhashable type in set: %s", node, ctx, unwrapped.Type())
+	}
+	return nil
+}
+
+func evalSetComprehension(
+	node *ast.SetComprehension,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	compEnv := object.NewEnclosedEnvironment(env)
+	compCtx := &CallContext{
+		FunctionName: "set_comprehension",
+		Node:         node,
+		Parent:       ctx,
+		env:          compEnv,
+	}
+
+	set := object.NewSet()
+	errObj := evalComprehensionClauses(node.Clauses, compEnv, node, compCtx, func() object.Object {
+		elem := Eval(node.Element, compEnv, compCtx)
+		if isError(elem) {
+			return elem
+		}
+		return addToSet(set, elem, node, compCtx)
+	})
+	if errObj != nil {
+		return errObj
+	}
+	return set
+}
+
+// newSetFromIterable builds a set from the elements of any iterable.
+func newSetFromIterable(
+	obj object.Object,
+	node ast.Node,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	elements, errObj := iterableElements(obj, node, env, ctx)
+	if errObj != nil {
+		return errObj
+	}
+	set := object.NewSet()
+	for _, elem := range elements {
+		if errObj := addToSet(set, elem, node, ctx); errObj != nil {
+			return errObj
+		}
+	}
+	return set
+}
+
+func isByteData(obj object.Object) bool {
+	return obj.Type() == object.BYTES_OBJ || obj.Type() == object.BYTE_ARRAY_OBJ
+}
+
+// evalBytesInfixExpression handles operators with a Bytes or ByteArray
+// operand. Concatenation keeps the type of the left operand.
+func evalBytesInfixExpression(
+	operator string,
+	left, right object.Object,
+	node ast.Node,
+	ctx *CallContext,
+) object.Object {
+	leftData, leftOk := object.ByteData(left)
+	rightData, rightOk := object.ByteData(right)
+
+	if leftOk && rightOk {
+		switch operator {
+		case "+":
+			combined := make([]byte, 0, len(leftData)+len(rightData))
+			combined = append(append(combined, leftData...), rightData...)
+			if left.Type() == object.BYTE_ARRAY_OBJ {
+				return &object.ByteArray{Value: combined}
+			}
+			return &object.Bytes{Value: combined}
+		case "==":
+			return nativeBoolToBooleanObject(bytes.Equal(leftData, rightData))
+		case "!=":
+			return nativeBoolToBooleanObject(!bytes.Equal(leftData, rightData))
+		case "<":
+			return nativeBoolToBooleanObject(bytes.Compare(leftData, rightData) < 0)
+		case ">":
+			return nativeBoolToBooleanObject(bytes.Compare(leftData, rightData) > 0)
+		case "<=":
+			return nativeBoolToBooleanObject(bytes.Compare(leftData, rightData) <= 0)
+		case ">=":
+			return nativeBoolToBooleanObject(bytes.Compare(leftData, rightData) >= 0)
+		}
+		return newErrorWithTrace("unknown operator for bytes: %s", node, ctx, operator)
+	}
+
+	if count, ok := right.(*object.Integer); ok && leftOk && operator == "*" {
+		if count.Value < 0 {
+			return newErrorWithTrace("bytes multiplication count cannot be negative", node, ctx)
+		}
+		repeated := bytes.Repeat(leftData, int(count.Value))
+		if left.Type() == object.BYTE_ARRAY_OBJ {
+			return &object.ByteArray{Value: repeated}
+		}
+		return &object.Bytes{Value: repeated}
+	}
+
+	switch operator {
+	case "==":
+		return FALSE
+	case "!=":
+		return TRUE
+	}
+	return newErrorWithTrace("unsupported operation: %s %s %s", node, ctx, left.Type(), operator, right.Type())
+}
+
+// evalBytesMethod returns the bound method named by node on a Bytes or
+// ByteArray value. The mutating methods exist only on ByteArray.
+func evalBytesMethod(obj object.Object, node *ast.DotExpression, ctx *CallContext) object.Object {
+	name := node.Right.Value
+	data := func() []byte {
+		value, _ := object.ByteData(obj)
+		return value
+	}
+	byteArg := func(args []object.Object) ([]byte, object.Object) {
+		if len(args) != 1 {
+			return nil, newError("%s() takes exactly 1 argument, got %d", name, len(args))
+		}
+		value, ok := object.ByteData(unwrapPrimitive(args[0]))
+		if !ok {
+			return nil, newError("%s() argument must be bytes, got %s", name, args[0].Type())
+		}
+		return value, nil
+	}
+
+	switch name {
+	case "length":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return object.NewInteger(int64(len(data())))
+		}}
+	case "to_hex":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return &object.String{Value: hex.EncodeToString(data())}
+		}}
+	case "to_base64":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return &object.String{Value: base64.StdEncoding.EncodeToString(data())}
+		}}
+	case "decode":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			encodingName := "utf-8"
+			if len(args) > 0 {
+				enc, ok := unwrapPrimitive(args[0]).(*object.String)
+				if !ok {
+					return newError("decode() encoding must be a string, got %s", args[0].Type())
+				}
+				encodingName = enc.Value
+			}
+			raw := data()
+			if name := strings.ToLower(encodingName); name == "utf-8" || name == "utf8" {
+				if offset := invalidUTF8Offset(raw); offset >= 0 {
+					return newError("decode: invalid utf-8 byte 0x%02x at offset %d", raw[offset], offset)
+				}
+			}
+			decoded, err := modules.DecodeBytes(raw, encodingName)
+			if err != nil {
+				return newError("decode: %s", err)
+			}
+			return &object.String{Value: decoded}
+		}}
+	case "to_list":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return &object.Array{Elements: byteElements(data())}
+		}}
+	case "find":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			sub, errObj := byteArg(args)
+			if errObj != nil {
+				return errObj
+			}
+			return object.NewInteger(int64(bytes.Index(data(), sub)))
+		}}
+	case "contains":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			sub, errObj := byteArg(args)
+			if errObj != nil {
+				return errObj
+			}
+			return nativeBoolToBooleanObject(bytes.Contains(data(), sub))
+		}}
+	case "starts_with":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			prefix, errObj := byteArg(args)
+			if errObj != nil {
+				return errObj
+			}
+			return nativeBoolToBooleanObject(bytes.HasPrefix(data(), prefix))
+		}}
+	case "ends_with":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			suffix, errObj := byteArg(args)
+			if errObj != nil {
+				return errObj
+			}
+			return nativeBoolToBooleanObject(bytes.HasSuffix(data(), suffix))
+		}}
+	}
+
+	byteArray, mutable := obj.(*object.ByteArray)
+	if !mutable {
+		return newErrorWithTrace("bytes has no method: %s", node, ctx, name)
+	}
+	switch name {
+	case "append":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("append() takes exactly 1 argument, got %d", len(args))
+			}
+			value, ok := unwrapPrimitive(args[0]).(*object.Integer)
+			if !ok || value.Value < 0 || value.Value > 255 {
+				return newError("append() requires an integer in range 0-255, got %s", args[0].Inspect())
+			}
+			byteArray.Value = append(byteArray.Value, byte(value.Value))
+			return NONE
+		}}
+	case "extend":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			more, errObj := byteArg(args)
+			if errObj != nil {
+				return errObj
+			}
+			byteArray.Value = append(byteArray.Value, more...)
+			return NONE
+		}}
+	case "clear":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			byteArray.Value = byteArray.Value[:0]
+			return NONE
+		}}
+	case "to_bytes":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return &object.Bytes{Value: append([]byte(nil), byteArray.Value...)}
+		}}
+	default:
+		return newErrorWithTrace("bytearray has no method: %s", node, ctx, name)
+	}
+}
+
+// invalidUTF8Offset returns the index of the first byte that does not
+// start a valid UTF-8 sequence, or -1 if data is valid UTF-8.
+func invalidUTF8Offset(data []byte) int {
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
+
+// byteElements returns data as an array of Integer objects.
+func byteElements(data []byte) []object.Object {
+	elements := make([]object.Object, len(data))
+	for i, b := range data {
+		elements[i] = object.NewInteger(int64(b))
+	}
+	return elements
+}
+
+// setOrError and boolOrError return the result of a set operation, or the
+// error an element's __eq__ raised while it ran.
+func setOrError(result *object.Set, errObj object.Object) object.Object {
+	if errObj != nil {
+		return errObj
+	}
+	return result
+}
+
+func boolOrError(result bool, errObj object.Object) object.Object {
+	if errObj != nil {
+		return errObj
+	}
+	return nativeBoolToBooleanObject(result)
+}
+
+// evalSetInfixExpression applies the set algebra and subset comparison
+// operators.
+func evalSetInfixExpression(
+	operator string,
+	left, right *object.Set,
+	node ast.Node,
+	ctx *CallContext,
+) object.Object {
+	switch operator {
+	case "|":
+		return setOrError(left.Union(right))
+	case "&":
+		return setOrError(left.Intersection(right))
+	case "-":
+		return setOrError(left.Difference(right))
+	case "^":
+		return setOrError(left.SymmetricDifference(right))
+	case "==":
+		return boolOrError(left.Equals(right))
+	case "!=":
+		equal, errObj := left.Equals(right)
+		return boolOrError(!equal, errObj)
+	case "<=":
+		return boolOrError(left.IsSubset(right))
+	case "<":
+		if left.Len() >= right.Len() {
+			return FALSE
+		}
+		return boolOrError(left.IsSubset(right))
+	case ">=":
+		return boolOrError(right.IsSubset(left))
+	case ">":
+		if right.Len() >= left.Len() {
+			return FALSE
+		}
+		return boolOrError(right.IsSubset(left))
+	default:
+		return newErrorWithTrace("unknown operator for sets: %s", node, ctx, operator)
+	}
+}
+
+// evalSetMethod returns the bound method named by node on a set.
+func evalSetMethod(
+	set *object.Set,
+	node *ast.DotExpression,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	name := node.Right.Value
+	// Methods taking another iterable accept any iterable, not just sets
+	otherSet := func(args []object.Object) (*object.Set, object.Object) {
+		if len(args) != 1 {
+			return nil, newError("%s() takes exactly 1 argument, got %d", name, len(args))
+		}
+		if other, ok := unwrapPrimitive(args[0]).(*object.Set); ok {
+			return other, nil
+		}
+		result := newSetFromIterable(unwrapPrimitive(args[0]), node, env, ctx)
+		if isError(result) {
+			return nil, result
+		}
+		return result.(*object.Set), nil
+	}
+	element := func(args []object.Object) (object.Object, object.Object) {
+		if len(args) != 1 {
+			return nil, newError("%s() takes exactly 1 argument, got %d", name, len(args))
+		}
+		elem := unwrapPrimitive(args[0])
+		if _, ok := object.HashKeyOf(elem); !ok {
+			return nil, newError("unhashable type in set: %s", elem.Type())
+		}
+		return elem, nil
 	}
 
-	switch leftObj := unwrappedLeft.(type) {
-	case *object.Integer:
-		rightObj := unwrappedRight.(*object.Integer)
-		return leftObj.Value == rightObj.Value
-	case *object.Float:
-		rightObj := unwrappedRight.(*object.Float)
-		return leftObj.Value == rightObj.Value
-	case *object.String:
-		rightObj := unwrappedRight.(*object.String)
-		return leftObj.Value == rightObj.Value
-	case *object.Boolean:
-		rightObj := unwrappedRight.(*object.Boolean)
-		return leftObj.Value == rightObj.Value
+	switch name {
+	case "add":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			elem, errObj := element(args)
+			if errObj != nil {
+				return errObj
+			}
+			if _, errObj := set.Add(elem); errObj != nil {
+				return errObj
+			}
+			return NONE
+		}}
+	case "discard", "remove":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			elem, errObj := element(args)
+			if errObj != nil {
+				return errObj
+			}
+			removed, errObj := set.Remove(elem)
+			if errObj != nil {
+				return errObj
+			}
+			if !removed && name == "remove" {
+				return newCustomErrorWithTrace("KeyError", fmt.Sprintf("%s not in set", elem.Inspect()), node, ctx,
+					map[string]object.Object{"errorType": &object.String{Value: "KeyError"}})
+			}
+			return NONE
+		}}
+	case "contains":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			elem, errObj := element(args)
+			if errObj != nil {
+				return errObj
+			}
+			return boolOrError(set.Contains(elem))
+		}}
+	case "clear":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			set.Clear()
+			return NONE
+		}}
+	case "copy":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return set.Copy()
+		}}
+	case "length":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return object.NewInteger(int64(set.Len()))
+		}}
+	case "to_list":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return &object.Array{Elements: set.Items()}
+		}}
+	case "union", "intersection", "difference", "symmetric_difference",
+		"issubset", "issuperset", "isdisjoint", "update":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			other, errObj := otherSet(args)
+			if errObj != nil {
+				return errObj
+			}
+			switch name {
+			case "union":
+				return setOrError(set.Union(other))
+			case "intersection":
+				return setOrError(set.Intersection(other))
+			case "difference":
+				return setOrError(set.Difference(other))
+			case "symmetric_difference":
+				return setOrError(set.SymmetricDifference(other))
+			case "issubset":
+				return boolOrError(set.IsSubset(other))
+			case "issuperset":
+				return boolOrError(other.IsSubset(set))
+			case "isdisjoint":
+				common, errObj := set.Intersection(other)
+				if errObj != nil {
+					return errObj
+				}
+				return nativeBoolToBooleanObject(common.Len() == 0)
+			default:
+				for _, elem := range other.Items() {
+					if _, errObj := set.Add(elem); errObj != nil {
+						return errObj
+					}
+				}
+				return NONE
+			}
+		}}
 	default:
-		// For other types, use pointer comparison as fallback
-		return unwrappedLeft == unwrappedRight
+		return newErrorWithTrace("set has no method: %s", node, ctx, name)
 	}
 }
 
-// Helper function to process array iteration with variable unpacking and loop body execution
-func processArrayIteration(
-	elements []object.Object,
-	fs *ast.ForStatement,
+// evalComprehensionClauses runs the comprehension clauses as nested loops,
+// calling emit once for every combination of loop values that passes all
+// `if` filters. It returns nil on success or the first error encountered.
+func evalComprehensionClauses(
+	clauses []*ast.ComprehensionClause,
 	env *object.Environment,
-	forCtx *CallContext,
+	node ast.Node,
 	ctx *CallContext,
+	emit func() object.Object,
 ) object.Object {
+	if len(clauses) == 0 {
+		return emit()
+	}
+
+	clause := clauses[0]
+	iterable := Eval(clause.Iterable, env, ctx)
+	if isError(iterable) {
+		return iterable
+	}
+	elements, errObj := iterableElements(iterable, node, env, ctx)
+	if errObj != nil {
+		return errObj
+	}
+
 	for _, elem := range elements {
-		switch varExpr := fs.Variable.(type) {
-		case *ast.Identifier:
-			if varExpr.Value != ".." {
-				env.Set(varExpr.Value, elem)
-			}
-		case *ast.TupleLiteral:
-			var items []object.Object
-			if tupObj, ok := elem.(*object.Tuple); ok {
-				items = tupObj.Elements
-			} else if arrObj, ok := elem.(*object.Array); ok {
-				items = arrObj.Elements
-			} else {
-				return newErrorWithTrace("cannot unpack non-iterable element: %s",
-					fs, ctx, elem.Type())
-			}
-			if len(varExpr.Elements) != len(items) {
-				return newErrorWithTrace("unpacking mismatch: expected %d values, got %d",
-					fs, ctx, len(varExpr.Elements), len(items))
+		if errObj := bindLoopTarget(clause.Variable, elem, env, node, ctx); errObj != nil {
+			return errObj
+		}
+
+		keep := true
+		for _, cond := range clause.Conditions {
+			result := Eval(cond, env, ctx)
+			if isError(result) {
+				return result
 			}
-			for i, target := range varExpr.Elements {
-				ident, ok := target.(*ast.Identifier)
-				if !ok {
-					return newErrorWithTrace("invalid assignment target in for loop", fs, ctx)
-				}
-				if ident.Value != ".." {
-					env.Set(ident.Value, items[i])
-				}
+			if !isTruthy(result) {
+				keep = false
+				break
 			}
-		default:
-			env.Set(fs.Variable.String(), elem)
+		}
+		if !keep {
+			continue
 		}
 
-		if fs.Body != nil {
-			loopResult := Eval(fs.Body, env, forCtx)
-			if loopResult != nil {
-				rt := getObjectType(loopResult)
-				if rt == string(object.STOP.Type()) {
-					break // Exit only this loop, don't propagate STOP
-				}
-				if rt == string(object.SKIP.Type()) {
-					continue // Skip to the next element in the outer loop
-				}
-				if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
-					rt == object.CUSTOM_ERROR_OBJ || isErrorWithTrace(loopResult) {
-					return loopResult
-				}
-			}
+		if errObj := evalComprehensionClauses(clauses[1:], env, node, ctx, emit); errObj != nil {
+			return errObj
 		}
 	}
-	return NONE
+	return nil
 }
 
 // resolveImportPath searches for an import file with smart resolution
@@ -5195,10 +7936,14 @@ func evalImportStatement(
 
 	importPath := node.FilePath.Value
 
-	// Get the source file from context for relative import resolution
+	// Get the source file from context for relative import resolution.
+	// Nested contexts such as diverge bodies inherit it from their parents.
 	sourceFile := ""
-	if ctx != nil {
-		sourceFile = ctx.SourceFile
+	for c := ctx; c != nil; c = c.Parent {
+		if c.SourceFile != "" {
+			sourceFile = c.SourceFile
+			break
+		}
 	}
 
 	// Resolve the import path to an actual file
@@ -5210,7 +7955,7 @@ func evalImportStatement(
 	// Check if file has already been parsed/evaluated
 	// Use resolved path as the cache key to handle relative vs absolute paths correctly
 	var importEnv *object.Environment
-	if cachedEnv, alreadyImported := importedFiles[resolvedPath]; alreadyImported {
+	if cachedEnv, alreadyImported := lookupImport(resolvedPath); alreadyImported {
 		// File already imported, reuse the cached environment
 		// This allows multiple selective imports from the same file
 		if envObj, ok := cachedEnv.(*object.Environment); ok {
@@ -5255,7 +8000,7 @@ func evalImportStatement(
 		}
 
 		// Cache the environment for future imports from this file
-		importedFiles[resolvedPath] = importEnv
+		recordImport(resolvedPath, importEnv)
 	}
 
 	namespace := &object.Namespace{Env: importEnv}
@@ -5342,7 +8087,7 @@ func evalGrimoireImport(
 		// Check each file for the grimoire
 		for _, filePath := range files {
 			// Skip if already imported
-			if _, alreadyImported := importedFiles[filePath]; alreadyImported {
+			if _, alreadyImported := lookupImport(filePath); alreadyImported {
 				continue
 			}
 
@@ -5396,7 +8141,7 @@ func evalGrimoireImport(
 			}
 
 			// Skip if already imported
-			if _, alreadyImported := importedFiles[mainFile]; alreadyImported {
+			if _, alreadyImported := lookupImport(mainFile); alreadyImported {
 				continue
 			}
 
@@ -5445,7 +8190,7 @@ func evalGrimoireImport(
 	}
 
 	// Mark the file as imported
-	importedFiles[foundInFile] = true
+	recordImport(foundInFile, true)
 
 	// Bind the grimoire to the environment
 	if node.Alias != nil {
@@ -5729,7 +8474,7 @@ func unpackMap(
 	keys := &object.Array{Elements: []object.Object{}}
 	values := &object.Array{Elements: []object.Object{}}
 
-	for _, pair := range hash.Pairs {
+	for _, pair := range hash.Ordered() {
 		keys.Elements = append(keys.Elements, pair.Key)
 		values.Elements = append(values.Elements, pair.Value)
 	}
@@ -5801,10 +8546,12 @@ func getObjectTypeString(obj object.Object) string {
 	case *object.Instance:
 		// For instances, return the grimoire name (which is the type)
 		return o.Grimoire.Name
-	case *object.Integer:
+	case *object.Integer, *object.BigInteger:
 		return "Integer"
 	case *object.Float:
 		return "Float"
+	case *object.Decimal:
+		return "Decimal"
 	case *object.String:
 		return "String"
 	case *object.Boolean:
@@ -5813,6 +8560,12 @@ func getObjectTypeString(obj object.Object) string {
 		return "Array"
 	case *object.Hash:
 		return "Map"
+	case *object.Set:
+		return "Set"
+	case *object.Bytes:
+		return "Bytes"
+	case *object.ByteArray:
+		return "ByteArray"
 	case *object.Tuple:
 		return "Tuple"
 	case *object.Function:
@@ -5874,6 +8627,9 @@ func normalizeTypeName(typeName string) string {
 
 func checkParameterTypes(fn *object.Function, args []object.Object, ctx *CallContext) object.Object {
 	for i, pExpr := range fn.Parameters {
+		if param, ok := pExpr.(*ast.Parameter); ok && (param.Variadic || param.KwVariadic) {
+			break
+		}
 		if param, ok := pExpr.(*ast.Parameter); ok && param.TypeHint != nil {
 			if i < len(args) {
 				expectedType := getTypeString(param.TypeHint)
@@ -5892,39 +8648,52 @@ func checkParameterTypes(fn *object.Function, args []object.Object, ctx *CallCon
 // Global goroutine manager
 var globalGoroutineManager = object.NewGoroutineManager()
 
+// GetGoroutineManager returns the manager tracking goroutines started by
+// diverge and by worker pools, so embedders can configure its limits.
+func GetGoroutineManager() *object.GoroutineManager {
+	return globalGoroutineManager
+}
+
 func evalDivergeStatement(
 	node *ast.DivergeStatement,
 	env *object.Environment,
 	ctx *CallContext,
 ) object.Object {
 	// Create a new goroutine
-	goroutine := &object.Goroutine{
-		Done:      make(chan bool, 1),
-		IsRunning: true,
+	name := ""
+	if node.Name != nil {
+		name = node.Name.Value
 	}
+	goroutine := object.NewGoroutine(name)
 
-	// Set name if provided
-	if node.Name != nil {
-		goroutine.Name = node.Name.Value
-		globalGoroutineManager.AddNamedGoroutine(goroutine.Name, goroutine)
+	// Register it with the manager, which refuses once a limit set with
+	// SetMaxLimits is reached
+	var err error
+	if name != "" {
+		err = globalGoroutineManager.AddNamedGoroutine(name, goroutine)
 	} else {
-		globalGoroutineManager.AddAnonymousGoroutine(goroutine)
+		err = globalGoroutineManager.AddAnonymousGoroutine(goroutine)
+	}
+	if err != nil {
+		return newErrorWithTrace("cannot diverge: %s", node, ctx, err)
 	}
 
 	// Start the goroutine
 	go func() {
+		var result, errObj object.Object
 		defer func() {
 			// Recover from any panic to prevent deadlock
 			if r := recover(); r != nil {
 				// Convert panic to error and store it
-				goroutine.Error = &object.Error{
+				errObj = &object.Error{
 					Message: fmt.Sprintf("Goroutine panic: %v", r),
 				}
 			}
 
-			// Always ensure Done channel receives a value
-			goroutine.IsRunning = false
-			goroutine.Done <- true
+			// Always close Done so every waiter is released
+			if goroutine.Finish(result, errObj) {
+				pendingCancels.Add(-1)
+			}
 		}()
 
 		// Create a new environment for the goroutine
@@ -5936,92 +8705,467 @@ func evalDivergeStatement(
 			Node:         node.Body,
 			Parent:       ctx,
 			env:          goroutineEnv,
+			Goroutine:    goroutine,
 		}
 
 		// Execute the body
-		result := Eval(node.Body, goroutineEnv, goroutineCtx)
+		evaluated := unwrapReturnValue(Eval(node.Body, goroutineEnv, goroutineCtx))
 
 		// Store the result or error
-		if isError(result) {
-			goroutine.Error = result
+		if isError(evaluated) {
+			errObj = evaluated
 		} else {
-			goroutine.Result = result
+			result = evaluated
 		}
 	}()
 
 	return goroutine
 }
 
+// pendingCancels counts goroutines that have been cancelled but not yet
+// finished, so loops only look for a cancelled goroutine while one exists.
+var pendingCancels atomic.Int64
+
+// currentGoroutine returns the goroutine running ctx, or nil on the main
+// goroutine.
+func currentGoroutine(ctx *CallContext) *object.Goroutine {
+	for c := ctx; c != nil; c = c.Parent {
+		if c.Goroutine != nil {
+			return c.Goroutine
+		}
+	}
+	return nil
+}
+
+// cancelSignal returns the channel closed when the goroutine running ctx is
+// cancelled. On the main goroutine it is nil, which never fires.
+func cancelSignal(ctx *CallContext) <-chan struct{} {
+	if goroutine := currentGoroutine(ctx); goroutine != nil {
+		return goroutine.Cancelled()
+	}
+	return nil
+}
+
+func cancelGoroutine(goroutine *object.Goroutine) bool {
+	if !goroutine.Cancel() {
+		return false
+	}
+	pendingCancels.Add(1)
+	return true
+}
+
+// checkCancelled returns a CancelledError once the goroutine running ctx
+// has been cancelled. Loops call it before every iteration.
+func checkCancelled(node ast.Node, ctx *CallContext) object.Object {
+	if pendingCancels.Load() == 0 {
+		return nil
+	}
+	if goroutine := currentGoroutine(ctx); goroutine != nil && goroutine.IsCancelled() {
+		return cancelledError(goroutine, node, ctx)
+	}
+	return nil
+}
+
+func cancelledError(goroutine *object.Goroutine, node ast.Node, ctx *CallContext) object.Object {
+	details := map[string]object.Object{
+		"errorType": &object.String{Value: "CancelledError"},
+	}
+	return newCustomErrorWithTrace("CancelledError", goroutine.Inspect()+" was cancelled", node, ctx, details)
+}
+
+func goroutineTimeoutError(goroutine *object.Goroutine, node ast.Node, ctx *CallContext) object.Object {
+	details := map[string]object.Object{
+		"errorType": &object.String{Value: "TimeoutError"},
+	}
+	return newCustomErrorWithTrace("TimeoutError", "timed out waiting for "+goroutine.Inspect(), node, ctx, details)
+}
+
+// evalTimeout turns a timeout in seconds into a channel that fires when it
+// expires. Without a timeout the channel is nil and never fires.
+func evalTimeout(expr ast.Expression, env *object.Environment, ctx *CallContext, what string) (<-chan time.Time, object.Object) {
+	if expr == nil {
+		return nil, nil
+	}
+	return timeoutAfter(Eval(expr, env, ctx), expr, ctx, what)
+}
+
+func timeoutAfter(value object.Object, node ast.Node, ctx *CallContext, what string) (<-chan time.Time, object.Object) {
+	seconds := unwrapPrimitive(value)
+	if isError(seconds) {
+		return nil, seconds
+	}
+	if seconds.Type() != object.INTEGER_OBJ && seconds.Type() != object.FLOAT_OBJ {
+		return nil, newErrorWithTrace("%s timeout must be a number of seconds, got %s", node, ctx, what, seconds.Type())
+	}
+	return time.After(time.Duration(toFloat(seconds) * float64(time.Second))), nil
+}
+
+// awaitGoroutine blocks until goroutine finishes. It gives up with a
+// TimeoutError when expired fires first, and with a CancelledError when the
+// goroutine doing the waiting is cancelled.
+func awaitGoroutine(goroutine *object.Goroutine, expired <-chan time.Time, node ast.Node, ctx *CallContext) object.Object {
+	if goroutine.IsCompleted() {
+		return nil
+	}
+	select {
+	case <-goroutine.Done:
+		return nil
+	case <-expired:
+		return goroutineTimeoutError(goroutine, node, ctx)
+	case <-cancelSignal(ctx):
+		return cancelledError(currentGoroutine(ctx), node, ctx)
+	}
+}
+
+// goroutineOutcome is what waiting on a finished goroutine evaluates to: its
+// error, re-raised in the waiter, or its result.
+func goroutineOutcome(goroutine *object.Goroutine) object.Object {
+	if goroutine.Error != nil {
+		return goroutine.Error
+	}
+	if goroutine.Result == nil {
+		return NONE
+	}
+	return goroutine.Result
+}
+
+// evalGoroutineMethod returns the attribute or bound method named by node on
+// a goroutine handle.
+func evalGoroutineMethod(goroutine *object.Goroutine, node *ast.DotExpression, ctx *CallContext) object.Object {
+	name := node.Right.Value
+	noArgs := func(fn func() object.Object) object.Object {
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError("%s() takes no arguments, got %d", name, len(args))
+			}
+			return fn()
+		}}
+	}
+	// wait and result take an optional timeout in seconds
+	withTimeout := func(fn func(expired <-chan time.Time) object.Object) object.Object {
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return newError("%s() takes at most one argument (the timeout), got %d", name, len(args))
+			}
+			var expired <-chan time.Time
+			if len(args) == 1 {
+				var errObj object.Object
+				if expired, errObj = timeoutAfter(args[0], node, ctx, name+"()"); errObj != nil {
+					return errObj
+				}
+			}
+			return fn(expired)
+		}}
+	}
+
+	switch name {
+	case "name":
+		if goroutine.Name == "" {
+			return NONE
+		}
+		return &object.String{Value: goroutine.Name}
+	case "status":
+		return noArgs(func() object.Object {
+			return &object.String{Value: goroutine.Status()}
+		})
+	case "is_running":
+		return noArgs(func() object.Object {
+			return nativeBoolToBooleanObject(goroutine.IsRunning())
+		})
+	case "is_cancelled":
+		return noArgs(func() object.Object {
+			return nativeBoolToBooleanObject(goroutine.IsCancelled())
+		})
+	case "cancel":
+		return noArgs(func() object.Object {
+			return nativeBoolToBooleanObject(cancelGoroutine(goroutine))
+		})
+	case "wait":
+		return withTimeout(func(expired <-chan time.Time) object.Object {
+			errObj := awaitGoroutine(goroutine, expired, node, ctx)
+			if isTimeoutError(errObj) {
+				return FALSE
+			}
+			if errObj != nil {
+				return errObj
+			}
+			return TRUE
+		})
+	case "result":
+		return withTimeout(func(expired <-chan time.Time) object.Object {
+			if errObj := awaitGoroutine(goroutine, expired, node, ctx); errObj != nil {
+				return errObj
+			}
+			return goroutineOutcome(goroutine)
+		})
+	default:
+		return newErrorWithTrace("goroutine has no method: %s", node, ctx, name)
+	}
+}
+
+func isTimeoutError(obj object.Object) bool {
+	if errWithTrace, ok := obj.(*object.ErrorWithTrace); ok && errWithTrace.CustomDetails != nil {
+		if errorType, ok := errWithTrace.CustomDetails["errorType"].(*object.String); ok {
+			return errorType.Value == "TimeoutError"
+		}
+	}
+	return false
+}
+
+// evalChannelMethod returns the bound method named by node on a channel.
+func evalChannelMethod(ch *object.Channel, node *ast.DotExpression, ctx *CallContext) object.Object {
+	name := node.Right.Value
+	arity := func(args []object.Object, want int) object.Object {
+		if len(args) != want {
+			return newError("%s() takes exactly %d argument(s), got %d", name, want, len(args))
+		}
+		return nil
+	}
+
+	switch name {
+	case "send":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if errObj := arity(args, 1); errObj != nil {
+				return errObj
+			}
+			if err := ch.SendUntil(args[0], cancelSignal(ctx)); err == object.ErrInterrupted {
+				return cancelledError(currentGoroutine(ctx), node, ctx)
+			} else if err != nil {
+				return newError("send on closed channel")
+			}
+			return NONE
+		}}
+	case "receive":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if errObj := arity(args, 0); errObj != nil {
+				return errObj
+			}
+			value, ok, err := ch.ReceiveUntil(cancelSignal(ctx))
+			if err != nil {
+				return cancelledError(currentGoroutine(ctx), node, ctx)
+			}
+			if ok {
+				return value
+			}
+			return NONE
+		}}
+	case "close":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if errObj := arity(args, 0); errObj != nil {
+				return errObj
+			}
+			if err := ch.Close(); err != nil {
+				return newError("close of closed channel")
+			}
+			return NONE
+		}}
+	case "is_closed":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if errObj := arity(args, 0); errObj != nil {
+				return errObj
+			}
+			return nativeBoolToBooleanObject(ch.IsClosed())
+		}}
+	case "capacity":
+		return object.NewInteger(int64(ch.Capacity))
+	default:
+		return newErrorWithTrace("channel has no method: %s", node, ctx, name)
+	}
+}
+
+// selectArm ties a reflect.SelectCase back to its select case. closed marks
+// the extra arm that fires when the case's channel is closed.
+type selectArm struct {
+	sc     *ast.SelectCase
+	ch     *object.Channel
+	closed bool
+}
+
+func evalSelectStatement(
+	node *ast.SelectStatement,
+	env *object.Environment,
+	ctx *CallContext,
+) object.Object {
+	var cases []reflect.SelectCase
+	var arms []selectArm
+
+	for _, sc := range node.Cases {
+		if sc.Kind == ast.SelectTimeout {
+			seconds := unwrapPrimitive(Eval(sc.Value, env, ctx))
+			if isError(seconds) {
+				return seconds
+			}
+			if seconds.Type() != object.INTEGER_OBJ && seconds.Type() != object.FLOAT_OBJ {
+				return newErrorWithTrace("select timeout must be a number of seconds, got %s", sc.Value, ctx, seconds.Type())
+			}
+			timer := time.After(time.Duration(toFloat(seconds) * float64(time.Second)))
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer)})
+			arms = append(arms, selectArm{sc: sc})
+			continue
+		}
+
+		chObj := Eval(sc.Channel, env, ctx)
+		if isError(chObj) {
+			return chObj
+		}
+		ch, ok := chObj.(*object.Channel)
+		if !ok {
+			return newErrorWithTrace("select case needs a channel, got %s", sc.Channel, ctx, chObj.Type())
+		}
+
+		if sc.Kind == ast.SelectSend {
+			value := Eval(sc.Value, env, ctx)
+			if isError(value) {
+				return value
+			}
+			// On a closed buffered channel with room, the send and closed
+			// arms would both be ready and Go picks one at random
+			if ch.IsClosed() {
+				return newErrorWithTrace("send on closed channel", sc.Channel, ctx)
+			}
+			cases = append(cases, reflect.SelectCase{
+				Dir:  reflect.SelectSend,
+				Chan: reflect.ValueOf(ch.Values()),
+				Send: reflect.ValueOf(&value).Elem(),
+			})
+		} else {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.Values())})
+		}
+		arms = append(arms, selectArm{sc: sc, ch: ch})
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.Done())})
+		arms = append(arms, selectArm{sc: sc, ch: ch, closed: true})
+	}
+
+	// A cancelled goroutine stops waiting; the arm has no select case
+	if stop := cancelSignal(ctx); stop != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(stop)})
+		arms = append(arms, selectArm{})
+	}
+
+	if node.Default != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+
+	chosen, received, _ := reflect.Select(cases)
+	if chosen == len(arms) {
+		return Eval(node.Default, env, ctx)
+	}
+
+	arm := arms[chosen]
+	if arm.sc == nil {
+		return cancelledError(currentGoroutine(ctx), node, ctx)
+	}
+	switch arm.sc.Kind {
+	case ast.SelectSend:
+		if arm.closed {
+			return newErrorWithTrace("send on closed channel", arm.sc.Channel, ctx)
+		}
+	case ast.SelectReceive:
+		var value object.Object = NONE
+		if !arm.closed {
+			value = received.Interface().(object.Object)
+		} else if buffered, ok := arm.ch.Drain(); ok {
+			value = buffered
+		}
+		if arm.sc.Target != nil {
+			env.SetWithGlobalCheck(arm.sc.Target.(*ast.Identifier).Value, value)
+		}
+	}
+
+	caseCtx := &CallContext{
+		FunctionName: "select_case",
+		Node:         arm.sc.Body,
+		Parent:       ctx,
+		env:          env,
+	}
+	return Eval(arm.sc.Body, env, caseCtx)
+}
+
+// evalConvergeStatement waits for goroutines to finish. As a statement,
+// errors raised inside the goroutines stay contained; as an expression it
+// re-raises the first error, or evaluates to the result of the named
+// goroutine (a tuple of results for several names).
 func evalConvergeStatement(
 	node *ast.ConvergeStatement,
 	env *object.Environment,
 	ctx *CallContext,
 ) object.Object {
+	expired, errObj := evalTimeout(node.Timeout, env, ctx, "converge")
+	if errObj != nil {
+		return errObj
+	}
+	self := currentGoroutine(ctx)
+
 	if len(node.Names) == 0 {
-		// Wait for all goroutines
+		// Wait for all goroutines, except the one doing the waiting
 
 		// Wait for named goroutines
 		namedGoroutines := globalGoroutineManager.GetAllNamedGoroutines()
 		for _, goroutine := range namedGoroutines {
-			// Wait for goroutine completion with race condition protection
-			select {
-			case <-goroutine.Done:
-				// Goroutine completed
-			default:
-				// If Done channel doesn't have a value yet, wait for it
-				if goroutine.IsRunning {
-					<-goroutine.Done
-				}
+			if goroutine == self {
+				continue
+			}
+			if errObj := awaitGoroutine(goroutine, expired, node, ctx); errObj != nil {
+				return errObj
 			}
 		}
 
 		// Wait for anonymous goroutines
 		anonymousGoroutines := globalGoroutineManager.GetAllAnonymousGoroutines()
 		for _, goroutine := range anonymousGoroutines {
-			// Wait for goroutine completion with race condition protection
-			select {
-			case <-goroutine.Done:
-				// Goroutine completed
-			default:
-				// If Done channel doesn't have a value yet, wait for it
-				if goroutine.IsRunning {
-					<-goroutine.Done
-				}
+			if goroutine == self {
+				continue
+			}
+			if errObj := awaitGoroutine(goroutine, expired, node, ctx); errObj != nil {
+				return errObj
 			}
 		}
 
 		// Clear all goroutines
 		globalGoroutineManager.ClearAll()
+		return object.NONE
+	}
 
-	} else {
-		// Wait for specific named goroutines
-		for _, nameExpr := range node.Names {
-			nameIdent, ok := nameExpr.(*ast.Identifier)
-			if !ok {
-				return newErrorWithTrace("converge expects goroutine names", node, ctx)
-			}
-
-			goroutine, exists := globalGoroutineManager.GetNamedGoroutine(nameIdent.Value)
-			if !exists {
-				return newErrorWithTrace("goroutine '%s' not found", node, ctx, nameIdent.Value)
-			}
+	// Find every named goroutine before waiting, so a typo is reported
+	// without blocking first
+	goroutines := make([]*object.Goroutine, 0, len(node.Names))
+	for _, nameExpr := range node.Names {
+		nameIdent, ok := nameExpr.(*ast.Identifier)
+		if !ok {
+			return newErrorWithTrace("converge expects goroutine names", node, ctx)
+		}
 
-			// Wait for goroutine completion regardless of IsRunning state
-			// to handle race conditions where IsRunning might have changed
-			select {
-			case <-goroutine.Done:
-				// Goroutine completed
-			default:
-				// If Done channel doesn't have a value yet, wait for it
-				if goroutine.IsRunning {
-					<-goroutine.Done
-				}
-			}
+		goroutine, exists := globalGoroutineManager.GetNamedGoroutine(nameIdent.Value)
+		if !exists {
+			return newErrorWithTrace("goroutine '%s' not found", node, ctx, nameIdent.Value)
+		}
+		goroutines = append(goroutines, goroutine)
+	}
 
-			// Remove from manager with proper cleanup
-			globalGoroutineManager.RemoveAndCleanupNamed(nameIdent.Value)
+	// On timeout the goroutines stay registered and can be converged again
+	for _, goroutine := range goroutines {
+		if errObj := awaitGoroutine(goroutine, expired, node, ctx); errObj != nil {
+			return errObj
 		}
 	}
 
-	return object.NONE
+	// Remove from manager with proper cleanup
+	for _, nameExpr := range node.Names {
+		globalGoroutineManager.RemoveAndCleanupNamed(nameExpr.(*ast.Identifier).Value)
+	}
+
+	if !node.AsExpression {
+		return object.NONE
+	}
+	results := make([]object.Object, len(goroutines))
+	for i, goroutine := range goroutines {
+		results[i] = goroutineOutcome(goroutine)
+		if isError(results[i]) {
+			return results[i]
+		}
+	}
+	if len(results) == 1 {
+		return results[0]
+	}
+	return &object.Tuple{Elements: results}
 }
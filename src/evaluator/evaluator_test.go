@@ -1,8 +1,10 @@
 package evaluator
 
 import (
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/javanhut/TheCarrionLanguage/src/lexer"
 	"github.com/javanhut/TheCarrionLanguage/src/object"
@@ -91,6 +93,34 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+// testInspectObject checks a composite result through its Inspect form,
+// reporting input when the evaluation failed or gave something else.
+func testInspectObject(t *testing.T, input string, obj object.Object, expected string) bool {
+	t.Helper()
+	if isError(obj) {
+		t.Errorf("input %q returned error: %s", input, obj.Inspect())
+		return false
+	}
+	if obj.Inspect() != expected {
+		t.Errorf("input %q: expected %s, got %s", input, expected, obj.Inspect())
+		return false
+	}
+	return true
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, wanted=%g", result.Value, expected)
+		return false
+	}
+	return true
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -449,21 +479,22 @@ func TestHashLiterals(t *testing.T) {
 	if !ok {
 		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
 	}
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                             5,
-		FALSE.HashKey():                            6,
+	expected := map[object.Object]int64{
+		&object.String{Value: "one"}:   1,
+		&object.String{Value: "two"}:   2,
+		&object.String{Value: "three"}: 3,
+		&object.Integer{Value: 4}:      4,
+		TRUE:                           5,
+		FALSE:                          6,
 	}
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		pair, ok := result.Get(expectedKey)
 		if !ok {
-			t.Errorf("no pair for given key in Pairs")
+			t.Errorf("no pair for key %s", expectedKey.Inspect())
+			continue
 		}
 		testIntegerObject(t, pair.Value, expectedValue)
 	}
@@ -514,6 +545,26 @@ func TestHashIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestHashInsertionOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, "{b: 1, a: 2, c: 3}"},
+		{"h = {\"b\": 1}\nh[\"a\"] = 2\nh[\"b\"] = 3\nh", "{b: 3, a: 2}"},
+		{"keys = []\nfor k in {3: 0, 1: 0, 2: 0}:\n    keys = keys + [k]\nkeys", "[3, 1, 2]"},
+		{"{k: 0 for k in [\"z\", \"y\", \"x\"]}", "{z: 0, y: 0, x: 0}"},
+		{`pairs({"z": 1, "a": 2}, "key")`, "[z, a]"},
+		{`jsonParse("{\"z\": 1, \"a\": {\"y\": 2, \"b\": 3}}")`, "{z: 1, a: {y: 2, b: 3}}"},
+		{`httpStringifyJSON({"z": 1, "a": [{"y": 2, "b": 3}]})`, `{"z":1,"a":[{"y":2,"b":3}]}`},
+		{"yamlParse(\"z: 1\\na: 2\\n\")", "{z: 1, a: 2}"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestGrimoireMethodCall(t *testing.T) {
 	input := `
  grim Calculator:
@@ -926,3 +977,1256 @@ result
 
 	t.Logf("enumerate on String instance works! Got: %v", result.Inspect())
 }
+
+func TestAnonymousSpell(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int64
+	}{
+		{"single expression", "double = spell(x): x * 2\ndouble(21)", 42},
+		{"immediate call", "(spell(a, b): a - b)(10, 3)", 7},
+		{"default parameter", "f = spell(a, b=5): a + b\nf(1)", 6},
+		{"closure over definition env", `
+spell make_adder(n):
+    return spell(x): x + n
+
+add5 = make_adder(5)
+add5(10)
+`, 15},
+		{"block body", `
+f = spell(x):
+    y = x * 3
+    return y + 1
+(f(2))
+`, 7},
+		{"named argument", `
+spell apply(fn, value):
+    return fn(value)
+
+apply(value=4, fn=spell(v): v * v)
+`, 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testIntegerObject(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestComprehensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"list with filter", "[x * 2 for x in [1, -2, 3] if x > 0]", "[2, 6]"},
+		{"nested clauses", "[x * y for x in [1, 2] for y in [10, 100]]", "[10, 100, 20, 200]"},
+		{"tuple unpacking", "[a + b for a, b in [(1, 2), (3, 4)]]", "[3, 7]"},
+		{"multiple filters", "[n for n in range(10) if n > 2 if n % 3 == 0]", "[3, 6, 9]"},
+		{"string iteration", `[c for c in "abc"]`, "[a, b, c]"},
+		{"loop variable does not leak", "x = 7\nys = [x for x in [1, 2]]\nx", "7"},
+		{"hash comprehension", `
+h = {k: v * 10 for k, v in pairs({"a": 1, "b": 2})}
+h["b"]
+`, "20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestDecorators(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int64
+	}{
+		{"simple decorator", `
+spell twice(fn):
+    return spell(x): fn(fn(x))
+
+@twice
+spell inc(x):
+    return x + 1
+
+inc(5)
+`, 7},
+		{"stacked decorators with arguments", `
+spell twice(fn):
+    return spell(x): fn(fn(x))
+
+spell add(n):
+    return spell(fn):
+        return spell(x): fn(x) + n
+
+@add(100)
+@twice
+spell double(x):
+    return x * 2
+
+double(3)
+`, 112},
+		{"decorated method sees self", `
+spell twice(fn):
+    return spell(n): fn(fn(n))
+
+grim Counter:
+    init(start):
+        self.count = start
+
+    @twice
+    spell bump(n):
+        self.count = self.count + n
+        return self.count
+
+c = Counter(10)
+c.bump(1)
+c.count
+`, 22},
+		{"decorated method keeps its own self", `
+spell twice(fn):
+    return spell(n): fn(fn(n))
+
+grim Counter:
+    init(start):
+        self.count = start
+
+    @twice
+    spell bump(n):
+        self.count = self.count + n
+        return self.count
+
+    spell bump_other(other):
+        bump = other.bump
+        return bump(1)
+
+a = Counter(10)
+b = Counter(100)
+a.bump_other(b)
+a.count * 1000 + b.count
+`, 10202},
+		{"decorated init", `
+spell doubled(fn):
+    return spell(n): fn(n * 2)
+
+grim Box:
+    @doubled
+    init(n):
+        self.n = n
+
+Box(n=21).n
+`, 42},
+		{"method decorators run once at definition", `
+routes = {}
+spell route(path):
+    return spell(fn):
+        routes[path] = len(routes) + 1
+        return fn
+
+grim Svc:
+    init():
+        self.name = "svc"
+
+    @route("/a")
+    spell a():
+        return self.name
+
+defined = routes["/a"]
+s1 = Svc()
+s2 = Svc()
+s1.a()
+s2.a()
+defined * 10 + len(routes)
+`, 11},
+		{"memoized method shares one cache", `
+spell memoize(fn):
+    cache = {}
+    spell wrapper(n):
+        if n not in cache:
+            cache[n] = fn(n)
+        return cache[n]
+    return wrapper
+
+grim Calc:
+    init(k):
+        self.k = k
+        self.calls = 0
+
+    @memoize
+    spell scaled(n):
+        self.calls = self.calls + 1
+        return n * self.k
+
+a = Calc(3)
+b = Calc(5)
+total = a.scaled(2) + b.scaled(2) + a.scaled(2)
+total + a.calls * 100 + b.calls * 1000
+`, 118},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testIntegerObject(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestConditionalExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 if True else 2", 1},
+		{"1 if False else 2", 2},
+		{"x = 0\n\"neg\" if x < 0 else \"zero\" if x == 0 else \"pos\"", "zero"},
+		{"spell pick(n):\n    return n * 2 if n > 0 else 0\npick(-3)", 0},
+		{"10 if True else 1 / 0", 10},
+		{"1 / 0 if False else 20", 20},
+		{`f"{'odd' if 3 % 2 == 1 else 'even'}"`, "odd"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		}
+	}
+}
+
+func TestStructuralPatternMatching(t *testing.T) {
+	setup := `
+grim Point:
+    init(x, y):
+        self.x = x
+        self.y = y
+
+spell describe(v):
+    match v:
+        case 0 | 1:
+            return "small"
+        case [first, *rest]:
+            return f"seq {first} {rest}"
+        case {"kind": "user", "name": name}:
+            return f"user {name}"
+        case Point(0, y):
+            return f"y-axis {y}"
+        case Point(x, y) if x == y:
+            return f"diagonal {x}"
+        case Point(x=px):
+            return f"point {px}"
+        case _:
+            return "other"
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"describe(1)", "small"},
+		{"describe([1, 2, 3])", "seq 1 [2, 3]"},
+		{"describe((4, 5))", "seq 4 [5]"},
+		{`describe({"kind": "user", "name": "ann", "age": 3})`, "user ann"},
+		{`describe({"kind": "group"})`, "other"},
+		{"describe(Point(0, 7))", "y-axis 7"},
+		{"describe(Point(3, 3))", "diagonal 3"},
+		{"describe(Point(2, 5))", "point 2"},
+		{`describe("text")`, "other"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(setup + tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: object is not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("%s: got=%q, want=%q", tt.input, str.Value, tt.expected)
+		}
+	}
+}
+
+func TestMatchCaptureScope(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+expected = 5
+match 3:
+    case expected:
+        seen = expected
+expected
+`, 5},
+		{`
+expected = 5
+match 3:
+    case expected:
+        seen = expected
+seen
+`, 3},
+		{`
+total = 0
+match [4, 6]:
+    case [a, b]:
+        total = a + b
+total
+`, 10},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestNonExhaustiveMatch(t *testing.T) {
+	input := `
+match 5:
+    case 1:
+        "one"
+`
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.ErrorWithTrace)
+	if !ok {
+		t.Fatalf("expected ErrorWithTrace, got=%T (%+v)", evaluated, evaluated)
+	}
+	if !strings.HasPrefix(errObj.Message, "MatchError:") {
+		t.Errorf("unexpected error message: %q", errObj.Message)
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`
+spell count(n):
+    i = 0
+    while i < n:
+        yield i
+        i = i + 1
+total = 0
+for x in count(5):
+    total = total + x
+total
+`, 10},
+		{`
+spell naturals():
+    i = 0
+    while True:
+        yield i
+        i = i + 1
+last = 0
+for n in naturals():
+    if n > 3:
+        stop
+    last = n
+last
+`, 3},
+		{`
+spell pair():
+    yield 1
+    yield 2
+g = pair()
+g.next() + g.next()
+`, 3},
+		{`
+spell squares(n):
+    for i in range(n):
+        yield i * i
+[v for v in squares(4)]
+`, []int64{0, 1, 4, 9}},
+		{`
+grim Bag:
+    init(items):
+        self.items = items
+    spell iter():
+        for it in self.items:
+            yield it * 10
+total = 0
+for v in Bag([1, 2, 3]):
+    total = total + v
+total
+`, 60},
+		{`
+state = {"closed": False}
+spell guarded():
+    attempt:
+        yield 1
+        yield 2
+    resolve:
+        state["closed"] = True
+for v in guarded():
+    stop
+state["closed"]
+`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case []int64:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Fatalf("wrong number of elements. got=%d", len(arr.Elements))
+			}
+			for i, want := range expected {
+				testIntegerObject(t, arr.Elements[i], want)
+			}
+		}
+	}
+}
+
+func TestGeneratorExhaustion(t *testing.T) {
+	input := `
+spell one():
+    yield 1
+g = one()
+g.next()
+g.next()
+`
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.ErrorWithTrace)
+	if !ok {
+		t.Fatalf("expected ErrorWithTrace, got=%T (%+v)", evaluated, evaluated)
+	}
+	if !strings.HasPrefix(errObj.Message, "StopIteration:") {
+		t.Errorf("unexpected error message: %q", errObj.Message)
+	}
+}
+
+func TestGeneratorClose(t *testing.T) {
+	input := `
+state = {"closed": False}
+spell guarded():
+    attempt:
+        yield 1
+        yield 2
+    resolve:
+        state["closed"] = True
+g = guarded()
+g.next()
+g.close()
+state["closed"]
+`
+	testBooleanObject(t, testEval(input), true)
+
+	evaluated := testEval("spell two():\n    yield 1\n    yield 2\ng = two()\ng.next()\ng.close()\ng.next()")
+	if message, ok := getErrorMessage(evaluated); !ok || !strings.HasPrefix(message, "StopIteration:") {
+		t.Errorf("expected StopIteration after close, got %T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestAbandonedGeneratorIsClosed(t *testing.T) {
+	env := object.NewEnvironment()
+	input := `
+closed = channel(1)
+spell guarded():
+    attempt:
+        yield 1
+        yield 2
+    resolve:
+        closed.send(True)
+spell take_one():
+    g = guarded()
+    return g.next()
+take_one()
+`
+	testIntegerObject(t, evalInEnv(t, input, env, ""), 1)
+	value, _ := env.Get("closed")
+	closed := value.(*object.Channel)
+
+	// Nothing refers to the generator any more, so collecting it closes
+	// the suspended body on a background goroutine
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		select {
+		case <-closed.Values():
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Errorf("abandoned generator was never closed")
+}
+
+func TestVariadicParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+spell total(*nums):
+    s = 0
+    for n in nums:
+        s = s + n
+    return s
+total(1, 2, 3)
+`, 6},
+		{`
+spell count(*nums):
+    return len(nums)
+count()
+`, 0},
+		{`
+spell f(a, *rest, scale=1):
+    return (a + len(rest)) * scale
+f(1, 2, 3, scale=10)
+`, 30},
+		{`
+spell f(a, **opts):
+    return a + opts["b"] + opts["c"]
+f(1, c=3, b=2)
+`, 6},
+		{`
+spell f(a, b, c):
+    return a * 100 + b * 10 + c
+f(*[1, 2], **{"c": 3})
+`, 123},
+		{`
+spell add(a, b=5):
+    return a + b
+spell forward(*args, **kwargs):
+    return add(*args, **kwargs)
+forward(1) + forward(1, 2) + forward(1, b=10)
+`, 20},
+		{`
+grim Point:
+    init(*coords, **meta):
+        self.coords = coords
+        self.meta = meta
+    spell size(*extra):
+        return len(self.coords) + len(extra) + len(self.meta)
+p = Point(1, 2, 3, label="p")
+p.size(*[7, 8])
+`, 6},
+		{`
+grim Util:
+    @arcanespell
+    spell pick(first, *rest, **kw):
+        return first + len(rest) + kw["bonus"]
+Util.pick(*[10, 20, 30], **{"bonus": 5})
+`, 17},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestVariadicParameterErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`
+spell f(a):
+    return a
+f(1, **{"b": 2})
+`, "unknown keyword argument: b"},
+		{`
+spell f(a):
+    return a
+f(**{"a": 1, 2: 3})
+`, "keywords must be strings, not INTEGER"},
+		{`
+spell f(*args):
+    return args
+f(*5)
+`, ""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.ErrorWithTrace)
+		if !ok {
+			t.Errorf("expected ErrorWithTrace, got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if tt.expectedMessage != "" && errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	vector := `
+grim Vec:
+    init(x, y):
+        self.x = x
+        self.y = y
+    spell __add__(other):
+        return Vec(self.x + other.x, self.y + other.y)
+    spell __rmul__(k):
+        return Vec(self.x * k, self.y * k)
+    spell __eq__(other):
+        return self.x == other.x and self.y == other.y
+    spell __lt__(other):
+        return self.x < other.x
+    spell __neg__():
+        return Vec(-self.x, -self.y)
+    spell __len__():
+        return 2
+    spell __getitem__(i):
+        if i == 0:
+            return self.x
+        return self.y
+    spell __setitem__(i, v):
+        if i == 0:
+            self.x = v
+        else:
+            self.y = v
+    spell __contains__(v):
+        return v == self.x or v == self.y
+`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"(Vec(1, 2) + Vec(3, 4)).y", 6},
+		{"(3 * Vec(1, 2)).x", 3},
+		{"Vec(1, 2) == Vec(1, 2)", true},
+		{"Vec(1, 2) != Vec(1, 2)", false},
+		{"Vec(1, 2) < Vec(3, 0)", true},
+		{"Vec(3, 0) > Vec(1, 2)", true},
+		{"(-Vec(1, 2)).y", -2},
+		{"len(Vec(1, 2))", 2},
+		{"Vec(1, 7)[1]", 7},
+		{"v = Vec(1, 2)\nv[0] = 9\nv.x", 9},
+		{"2 in Vec(1, 2)", true},
+		{"5 not in Vec(1, 2)", true},
+		{"Vec(1, 2) in [Vec(0, 0), Vec(1, 2)]", true},
+		{"[Vec(3, 0), Vec(1, 0), Vec(2, 0)].sort()[0].x", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(vector + tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+
+	// Errors raised by comparison methods reach the caller
+	broken := `
+grim Broken:
+    spell __eq__(other):
+        return 1 / 0
+    spell __lt__(other):
+        return 1 / 0
+`
+	for _, input := range []string{
+		"[Broken(), Broken()].sort()",
+		"Broken() in [Broken()]",
+		"[Broken()].contains(Broken())",
+		"[Broken()].index_of(Broken())",
+	} {
+		result := testEval(broken + input)
+		if msg, ok := getErrorMessage(result); !ok || !strings.Contains(msg, "division by zero") {
+			t.Errorf("input %q: expected the comparison error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestHashKeys(t *testing.T) {
+	// Every Point hashes to the same bucket, so lookups rely on __eq__.
+	point := `
+grim Point:
+    init(x, y):
+        self.x = x
+        self.y = y
+    spell __hash__():
+        return 7
+    spell __eq__(other):
+        return self.x == other.x and self.y == other.y
+`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{(1, 2): 5}[(1, 2)]`, 5},
+		{`{(1, "a"): 1, (1, "b"): 2}[(1, "b")]`, 2},
+		{`len({0.1234567: 1, 0.1234568: 2})`, 2},
+		{`{0.1234568: 1}[0.1234567]`, nil},
+		{`{0.0: 3}[-0.0]`, 3},
+		{`len({(1, 2), (1, 2), (2, 1)})`, 2},
+		{"h = {Point(1, 2): 1, Point(3, 4): 2}\nh[Point(3, 4)]", 2},
+		{"h = {Point(1, 2): 1}\nh[Point(1, 2)] = 9\nlen(h)", 1},
+		{"h = {Point(1, 2): 1}\nh[Point(2, 1)]", nil},
+		{"len({Point(1, 2), Point(1, 2), Point(2, 2)})", 2},
+		{"Point(2, 2) in {Point(1, 2), Point(2, 2)}", true},
+		{"Point(5, 5) in {Point(1, 2): 0}", false},
+		{"hash(Point(1, 2))", 7},
+		{`hash("abc") == hash("abc")`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(point + tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		default:
+			testNoneObject(t, evaluated)
+		}
+	}
+
+	for _, input := range []string{`{[1]: 2}`, `{([1], 2): 3}`, `hash([1])`, "grim Plain:\n    init():\n        self.x = 1\n{Plain(): 1}"} {
+		if result := testEval(input); !isError(result) {
+			t.Errorf("input %q: expected unhashable error, got %s", input, result.Inspect())
+		}
+	}
+
+	// An error raised by __eq__ while comparing keys is raised to the caller
+	broken := `
+grim Broken:
+    spell __hash__():
+        return 7
+    spell __eq__(other):
+        return 1 / 0
+`
+	for _, input := range []string{
+		"{Broken(): 1, Broken(): 2}",
+		"h = {Broken(): 1}\nh[Broken()]",
+		"h = {Broken(): 1}\nh[Broken()] = 2",
+		"Broken() in {Broken(): 1}",
+		"{Broken(), Broken()}",
+		"{Broken()} | {Broken()}",
+	} {
+		result := testEval(broken + input)
+		if msg, ok := getErrorMessage(result); !ok || !strings.Contains(msg, "division by zero") {
+			t.Errorf("input %q: expected the __eq__ error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestNumericLiterals(t *testing.T) {
+	intTests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF + 1", 256},
+		{"0o17 * 2", 30},
+		{"0b1010 - 0b0011", 7},
+		{"1_000_000 // 1_000", 1000},
+		{"x = 0x_dead_beef\nx", 0xdeadbeef},
+	}
+	for _, tt := range intTests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	floatTests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1e3 + 1.0", 1001},
+		{"2.5E-1 * 4.0", 1},
+		{"1_000.5", 1000.5},
+	}
+	for _, tt := range floatTests {
+		testFloatObject(t, testEval(tt.input), tt.expected)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 0xZZ", `at line 1, column 5: invalid digit 'Z' in hexadecimal literal "0xZZ"`},
+		{"x = 1\ny = 1__0", `at line 2, column 5: underscores in "1__0" must separate digits`},
+	}
+	for _, tt := range errorTests {
+		message, ok := getErrorMessage(testEval(tt.input))
+		if !ok {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		if !strings.HasPrefix(message, tt.expected) {
+			t.Errorf("%q: expected error %q, got %q", tt.input, tt.expected, message)
+		}
+	}
+}
+
+func TestBigIntegerPromotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"2 ** 100", "1267650600228229401496703205376"},
+		{"1 << 64", "18446744073709551616"},
+		{"123456789012345678901234567890", "123456789012345678901234567890"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(2 ** 100) // (2 ** 98)", "4"},
+		{"(2 ** 100) % 7", "2"},
+		{"(2 ** 64) - (2 ** 64) + 5", "5"},
+		{"r = 1\nfor i in range(1, 26):\n    r *= i\nr", "15511210043330985984000000"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+
+	// Results that fit in an int64 are demoted back to Integer
+	testIntegerObject(t, testEval("(2 ** 100) // (2 ** 98)"), 4)
+	testBooleanObject(t, testEval("2 ** 70 == 2 ** 70"), true)
+	testBooleanObject(t, testEval("2 ** 70 > 2 ** 63"), true)
+	testBooleanObject(t, testEval("{2 ** 70: 1}[2 ** 70] == 1"), true)
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0.1d + 0.2d", "0.3"},
+		{"19.99d * 3", "59.97"},
+		{"1.50d + 1", "2.50"},
+		{"10d - 0.01d", "9.99"},
+		{"1d / 3d", "0.3333333333333333333333333333"},
+		{"1d / 4", "0.25"},
+		{"7.5d // 2", "3"},
+		{"-7.5d % 2", "-1.5"},
+		{"1.5d ** 2", "2.25"},
+		{"2d ** -2", "0.25"},
+		{"-2.5d", "-2.5"},
+		{"0.1d + 0.1", "0.2"},
+		{"Decimal(\"12.345\")", "12.345"},
+		{"Decimal(0.1)", "0.1"},
+		{"Decimal(\"2.675\").round(2)", "2.68"},
+		{"Decimal(\"2.665\").round(2)", "2.66"},
+		{"Decimal(\"2.665\").round(2, \"half_up\")", "2.67"},
+		{"Decimal(\"-2.5\").round(0, \"floor\")", "-3"},
+		{"5d.round(2)", "5.00"},
+		{"price = 10.5d\nf\"{price:.2f}\"", "10.50"},
+		{"data = jsonParse(\"{\\\"price\\\": 19.99}\", True)\ndata[\"price\"] * 3", "59.97"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+
+	testBooleanObject(t, testEval("0.1d + 0.2d == 0.3d"), true)
+	testBooleanObject(t, testEval("1.0d == 1"), true)
+	testBooleanObject(t, testEval("1.5d < 2"), true)
+	testBooleanObject(t, testEval("{1.0d: 1}[1.00d] == 1"), true)
+	testIntegerObject(t, testEval("int(9.99d)"), 9)
+
+	if result := testEval("1d / 0"); !isError(result) {
+		t.Errorf("expected division by zero error, got %s", result.Inspect())
+	}
+	if result := testEval("2d ** 0.5d"); !isError(result) {
+		t.Errorf("expected non-integer exponent error, got %s", result.Inspect())
+	}
+}
+
+func TestDecimalContext(t *testing.T) {
+	defer object.SetDecimalContext(object.DefaultDecimalPrecision, object.RoundHalfEven)
+
+	result := testEval("decimalContext(5, \"half_up\")\n2d / 3d")
+	if result.Inspect() != "0.66667" {
+		t.Errorf("expected 0.66667 with precision 5, got %s", result.Inspect())
+	}
+	result = testEval("decimalContext(3, \"down\")\n2d / 3d")
+	if result.Inspect() != "0.666" {
+		t.Errorf("expected 0.666 rounding down, got %s", result.Inspect())
+	}
+	if result := testEval("decimalContext(5, \"sideways\")"); !isError(result) {
+		t.Errorf("expected error for unknown rounding mode, got %s", result.Inspect())
+	}
+}
+
+func TestSets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{1, 2, 2, 3}", "{1, 2, 3}"},
+		{"set()", "set()"},
+		{"set([1, 1, 2])", "{1, 2}"},
+		{"{1, 2, 3} | {3, 4}", "{1, 2, 3, 4}"},
+		{"{1, 2, 3} & {2, 3, 4}", "{2, 3}"},
+		{"{1, 2, 3} - {2}", "{1, 3}"},
+		{"{1, 2} ^ {2, 3}", "{1, 3}"},
+		{"{x * x for x in range(4)}", "{0, 1, 4, 9}"},
+		{"s = {1}\ns.add(2)\ns.add(2)\ns", "{1, 2}"},
+		{"s = {1, 2}\ns.discard(1)\ns.discard(5)\ns", "{2}"},
+		{"{1, 2}.union([5])", "{1, 2, 5}"},
+		{"list({3, 1, 2})", "[3, 1, 2]"},
+		{"type({1})", "Set"},
+		{`
+grim Countdown:
+    init(n):
+        self.n = n
+    spell iter():
+        return self
+    spell next():
+        if self.n <= 0:
+            raise StopIteration("done")
+        self.n = self.n - 1
+        return self.n % 3
+set(Countdown(6))`, "{2, 1, 0}"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+
+	testBooleanObject(t, testEval("2 in {1, 2}"), true)
+	testBooleanObject(t, testEval("5 not in {1, 2}"), true)
+	testBooleanObject(t, testEval("{1, 2} == {2, 1}"), true)
+	testBooleanObject(t, testEval("{1} <= {1, 2}"), true)
+	testBooleanObject(t, testEval("{1, 2} < {1, 2}"), false)
+	testBooleanObject(t, testEval("{1, 2}.issubset([1, 2, 3])"), true)
+	testBooleanObject(t, testEval("{1, 2}.issuperset({2})"), true)
+	testIntegerObject(t, testEval("len({1, 2, 3})"), 3)
+	testIntegerObject(t, testEval("total = 0\nfor x in {1, 2, 3}:\n    total += x\ntotal"), 6)
+
+	if result := testEval("{[1, 2]}"); !isError(result) {
+		t.Errorf("expected unhashable error, got %s", result.Inspect())
+	}
+	if result := testEval("{1}.remove(2)"); !isError(result) {
+		t.Errorf("expected KeyError, got %s", result.Inspect())
+	}
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`b"abc"`, `b"abc"`},
+		{`b"\x00\xff\n"`, `b"\x00\xff\n"`},
+		{`b"ab" + b"cd"`, `b"abcd"`},
+		{`b"ab" * 2`, `b"abab"`},
+		{`b"hello"[1:3]`, `b"el"`},
+		{`bytes("hé")`, `b"h\xc3\xa9"`},
+		{`bytes([104, 105])`, `b"hi"`},
+		{`bytes(3)`, `b"\x00\x00\x00"`},
+		{`b"hi".to_hex()`, "6869"},
+		{`bytesFromHex("6869")`, `b"hi"`},
+		{`b"hi".to_base64()`, "aGk="},
+		{`bytesFromBase64("aGk=")`, `b"hi"`},
+		{`bytes("hé").decode()`, "hé"},
+		{`b"abc".to_list()`, "[97, 98, 99]"},
+		{"a = bytearray(b\"abc\")\na[0] = 65\na.append(100)\na", `bytearray(b"Abcd")`},
+		{"a = bytearray(2)\na.extend(b\"z\")\na.to_bytes()", `b"\x00\x00z"`},
+		{`bytearray(b"ab")[0:1]`, `bytearray(b"a")`},
+		{`type(b"")`, "Bytes"},
+		{`type(bytearray())`, "ByteArray"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+
+	testIntegerObject(t, testEval(`b"abc"[0]`), 97)
+	testIntegerObject(t, testEval(`b"abc"[-1]`), 99)
+	testIntegerObject(t, testEval(`len(b"\x00\x01")`), 2)
+	testIntegerObject(t, testEval("total = 0\nfor x in b\"\\x01\\x02\":\n    total += x\ntotal"), 3)
+	testBooleanObject(t, testEval(`b"abc" == b"abc"`), true)
+	testBooleanObject(t, testEval(`b"abc" == bytearray(b"abc")`), true)
+	testBooleanObject(t, testEval(`b"abc" == "abc"`), false)
+	testBooleanObject(t, testEval(`b"a" < b"b"`), true)
+	testBooleanObject(t, testEval(`98 in b"abc"`), true)
+	testBooleanObject(t, testEval(`b"bc" in b"abc"`), true)
+	testBooleanObject(t, testEval(`b"abc".starts_with(b"ab")`), true)
+
+	if b, ok := testEval(`encodingEncode("hi")`).(*object.Bytes); !ok || string(b.Value) != "hi" {
+		t.Errorf("encodingEncode should return Bytes")
+	}
+	if b, ok := testEval(`encodingStripBOM(b"\xef\xbb\xbfhi")`).(*object.Bytes); !ok || string(b.Value) != "hi" {
+		t.Errorf("encodingStripBOM should return Bytes without the BOM")
+	}
+
+	for _, input := range []string{`b"abc"[3]`, `b"abc".append(1)`, "a = bytearray(1)\na[0] = 256", `bytes([300])`, `b"\xff".decode()`, `b"a\xc3".decode("utf-8")`} {
+		if result := testEval(input); !isError(result) {
+			t.Errorf("input %q: expected error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestUnicodeStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"héllo"[1]`, "é"},
+		{`"日本語"[-1]`, "語"},
+		{`"😀x"[0]`, "😀"},
+		{`"héllo wörld"[1:4]`, "éll"},
+		{`"日本語"[-2:]`, "本語"},
+		{`list("aé😀")`, "[a, é, 😀]"},
+		{`"é" == "é"`, "true"},
+		{`"\x41\U0001F600"`, "A😀"},
+		{`r"\n\t"`, `\n\t`},
+		{"out = \"\"\nfor c in \"añb\":\n    out = c + out\nout", "bña"},
+		{"café = 3\nnaïve = café * 2\nnaïve", "6"},
+		{`chr(955)`, "λ"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+
+	testIntegerObject(t, testEval(`len("héllo")`), 5)
+	testIntegerObject(t, testEval(`len("😀")`), 1)
+	testIntegerObject(t, testEval(`ord("λ")`), 955)
+
+	for _, input := range []string{`"日本語"[3]`, `ord("ab")`, `chr(55296)`} {
+		if result := testEval(input); !isError(result) {
+			t.Errorf("input %q: expected error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestMultilineBrackets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"config = {\n    \"name\": \"app\",\n    \"ports\": [\n        80,\n        443,\n    ],\n}\nconfig", "{name: app, ports: [80, 443]}"},
+		{"spell add(\n    a,\n    b,\n):\n    return a + b\nadd(\n    1,\n    2,\n)", "3"},
+		{"[\n    x * 2\n    for x in [1, 2, 3]\n]", "[2, 4, 6]"},
+		{"t = (1,)\nlen(t)", "1"},
+		{"total = (1 +\n         2 +\n         3)\ntotal", "6"},
+		{"spell apply(f, x):\n    return f(x)\napply(spell(v):\n    y = v + 1\n    return y * 2\n, 4)", "10"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestSliceSteps(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[0, 1, 2, 3, 4, 5][::2]", "[0, 2, 4]"},
+		{"[0, 1, 2, 3, 4, 5][::-1]", "[5, 4, 3, 2, 1, 0]"},
+		{"[0, 1, 2, 3, 4, 5][4:1:-1]", "[4, 3, 2]"},
+		{"[0, 1, 2, 3, 4, 5][-2::-2]", "[4, 2, 0]"},
+		{"[0, 1, 2, 3, 4, 5][1:100:3]", "[1, 4]"},
+		{"[0, 1, 2][5:0:-1]", "[2, 1]"},
+		{"[0, 1, 2][1:2:-1]", "[]"},
+		{`"héllo"[::-1]`, "olléh"},
+		{`"abcdef"[1::2]`, "bdf"},
+		{"(1, 2, 3)[::-1]", "(3, 2, 1)"},
+		{"(1, 2, 3)[1:]", "(2, 3)"},
+		{`b"abc"[::-1]`, `b"cba"`},
+		{"xs = [0, 1, 2, 3, 4]\nxs[1:3] = [9]\nxs", "[0, 9, 3, 4]"},
+		{"xs = [0, 1]\nxs[1:1] = (7, 8)\nxs", "[0, 7, 8, 1]"},
+		{"xs = [0, 1, 2, 3]\nxs[::2] = [\"a\", \"b\"]\nxs", "[a, 1, b, 3]"},
+		{"xs = [0, 1, 2]\nxs[::-1] = [3, 4, 5]\nxs", "[5, 4, 3]"},
+		{"xs = [0, 1, 2]\nxs[:] = []\nxs", "[]"},
+		{"ba = bytearray(b\"hello\")\nba[1:3] = b\"EL\"\nba", `bytearray(b"hELlo")`},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+
+	for _, input := range []string{
+		"[1, 2][::0]",
+		`[1, 2]["a":]`,
+		"xs = [1, 2, 3]\nxs[::2] = [1]",
+		"s = \"abc\"\ns[0:1] = \"z\"",
+		"xs = [1]\nxs[0:1] = 5",
+	} {
+		if result := testEval(input); !isError(result) {
+			t.Errorf("input %q: expected error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestEnums(t *testing.T) {
+	colors := "grim Color(Enum):\n    RED\n    GREEN\n    BLUE = 10\n    PURPLE\n\n    spell label():\n        return self.name + \"!\"\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Color.RED", "Color.RED"},
+		{"Color.RED.name", "RED"},
+		{"Color.GREEN.value", "2"},
+		{"Color.PURPLE.value", "11"},
+		{"Color(10)", "Color.BLUE"},
+		{"Color(Color.GREEN)", "Color.GREEN"},
+		{"list(Color)", "[Color.RED, Color.GREEN, Color.BLUE, Color.PURPLE]"},
+		{"names = []\nfor c in Color:\n    names = names + [c.name]\nnames", "[RED, GREEN, BLUE, PURPLE]"},
+		{"len(Color)", "4"},
+		{"Color.RED in Color", "true"},
+		{"Color.RED == Color.RED", "true"},
+		{"Color.RED == Color.GREEN", "false"},
+		{"Color.RED == 1", "false"},
+		{"Color.GREEN.label()", "GREEN!"},
+		{"str(Color.BLUE)", "Color.BLUE"},
+		{"{Color.RED: \"r\", Color.BLUE: \"b\"}[Color.BLUE]", "b"},
+		{"match Color.BLUE:\n    case Color.RED:\n        x = \"red\"\n    case Color.BLUE:\n        x = \"blue\"\nx", "blue"},
+		{"spell paint(c: Color):\n    return c.value\npaint(Color.BLUE)", "10"},
+	}
+
+	for _, tt := range tests {
+		input := colors + tt.input
+		testInspectObject(t, tt.input, testEval(input), tt.expected)
+	}
+
+	for _, input := range []string{
+		"Color(99)",
+		"Color.RED.value = 5",
+		"Color.RED.extra = 5",
+		"spell paint(c: Color):\n    return c\npaint(1)",
+		"grim Dup(Enum):\n    A\n    A",
+		"grim Same(Enum):\n    A = 1\n    B = 1",
+		"grim More(Color):\n    ORANGE",
+	} {
+		if result := testEval(colors + input); !isError(result) {
+			t.Errorf("input %q: expected error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestProperties(t *testing.T) {
+	temp := `
+grim Temperature:
+    init(celsius):
+        self.celsius = celsius
+
+    @property
+    spell celsius():
+        return self._celsius
+
+    @celsius.setter
+    spell celsius(value):
+        if value < -273:
+            value = -273
+        self._celsius = value
+
+    @property
+    spell fahrenheit():
+        return self._celsius * 9 / 5 + 32
+
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Temperature(100).fahrenheit", "212"},
+		{"t = Temperature(0)\nt.celsius = 10\nt.celsius", "10"},
+		{"t = Temperature(-500)\nt.celsius", "-273"},
+		{"t = Temperature(5)\nt.celsius = 20\nt.fahrenheit", "68"},
+		{"t = Temperature(5)\nf\"{t.celsius} degrees\"", "5 degrees"},
+		{"grim Warm(Temperature):\n    init(c):\n        super.init(c)\n    @property\n    spell fahrenheit():\n        return 0\nw = Warm(7)\nw.celsius = -1000\n(w.celsius, w.fahrenheit)", "(-273, 0)"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(temp + tt.input), tt.expected)
+	}
+
+	evaluated := testEval(temp + "t = Temperature(1)\nt.fahrenheit = 3")
+	errObj, ok := evaluated.(*object.ErrorWithTrace)
+	if !ok {
+		t.Fatalf("expected ErrorWithTrace, got=%T (%+v)", evaluated, evaluated)
+	}
+	if !strings.HasPrefix(errObj.Message, "AttributeError:") {
+		t.Errorf("unexpected error message: %q", errObj.Message)
+	}
+
+	for _, input := range []string{
+		"grim A:\n    @x.setter\n    spell x(v):\n        ignore",
+		"grim B:\n    @property\n    spell x(v):\n        return v",
+		"grim C:\n    @property\n    spell x():\n        return 1\n    @x.setter\n    spell x():\n        ignore",
+	} {
+		if result := testEval(input); !isError(result) {
+			t.Errorf("input %q: expected error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestChannels(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ch = channel()\ndiverge:\n    for i in range(5):\n        ch.send(i)\n    ch.close()\ntotal = 0\nfor v in ch:\n    total = total + v\ntotal", "10"},
+		{"ch = channel(2)\nch.send(1)\nch.send(2)\n(len(ch), ch.receive(), len(ch))", "(2, 1, 1)"},
+		{"ch = channel(2)\nch.send(\"x\")\nch.close()\n(ch.receive(), ch.receive(), ch.is_closed())", "(x, None, true)"},
+		{"ch = channel(3)\nch.send(1)\nch.send(2)\nch.close()\nlist(ch)", "[1, 2]"},
+		{"ch = channel()\nselect:\n    case v = ch.receive():\n        r = v\n    case timeout(0.01):\n        r = \"timeout\"\nr", "timeout"},
+		{"ch = channel()\nselect:\n    case ch.receive():\n        r = \"got\"\n    case _:\n        r = \"default\"\nr", "default"},
+		{"ch = channel(1)\nselect:\n    case ch.send(7):\n        r = \"sent\"\n(r, ch.receive())", "(sent, 7)"},
+		{"a = channel()\nb = channel(1)\nb.send(\"b\")\nselect:\n    case v = a.receive():\n        r = v\n    case v = b.receive():\n        r = v\nr", "b"},
+		{"ch = channel()\nch.close()\nselect:\n    case v = ch.receive():\n        r = v\nr", "None"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+
+	for _, input := range []string{
+		"ch = channel()\nch.close()\nch.send(1)",
+		"ch = channel()\nch.close()\nch.close()",
+		"channel(-1)",
+		"ch = channel()\nch.close()\nselect:\n    case ch.send(1):\n        ignore",
+		"ch = channel(1)\nch.close()\nch.send(1)",
+		"x = 5\nselect:\n    case x.receive():\n        ignore",
+	} {
+		if result := testEval(input); !isError(result) {
+			t.Errorf("input %q: expected error, got %s", input, result.Inspect())
+		}
+	}
+
+	// The buffer has room, so the send arm is ready too; it must never win
+	for i := 0; i < 50; i++ {
+		input := "ch = channel(1)\nch.close()\nselect:\n    case ch.send(1):\n        ignore"
+		if result := testEval(input); !isError(result) {
+			t.Fatalf("attempt %d: expected send on closed buffered channel to fail, got %s", i, result.Inspect())
+		}
+	}
+}
+
+func TestAutocloseCallsCloseSpell(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"grim Res:\n    init():\n        self.open = True\n    spell close():\n        self.open = False\nr = Res()\nstate = {}\nautoclose r as x:\n    state[\"inside\"] = x.open\n(state[\"inside\"], r.open)", "(true, false)"},
+		{"grim Res:\n    init():\n        self.open = True\n    spell close():\n        self.open = False\nr = Res()\nstate = {}\nautoclose r:\n    state[\"inside\"] = r.open\n(state[\"inside\"], r.open)", "(true, false)"},
+		{"grim Res:\n    init():\n        self.open = True\n    spell close():\n        self.open = False\nr = Res()\nspell use():\n    autoclose r:\n        return 1\n(use(), r.open)", "(1, false)"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+
+	failing := "grim Res:\n    spell close():\n        raise \"close failed\"\nautoclose Res():\n    x = 1\n1"
+	if result := testEval(failing); !isError(result) {
+		t.Errorf("expected close error to propagate, got %s", result.Inspect())
+	}
+}
@@ -0,0 +1,352 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/javanhut/TheCarrionLanguage/src/object"
+)
+
+// Worker pools run Carrion callables on a bounded number of goroutines. The
+// WorkerPool grimoire and parallel_map in munin/parallel.crl hold a handle
+// into poolHandles.
+//
+// Workers are started on demand, up to the pool size, and exit as soon as
+// the queue is empty, so an idle pool holds no goroutines. Each worker is
+// registered with the global goroutine manager and so counts towards its
+// anonymous goroutine limit; the tasks themselves do not, which lets a pool
+// work through any number of items. Every task gets its own goroutine handle
+// for waiting on it, collecting its result or cancelling it.
+var (
+	poolHandles           = make(map[int64]*workerPool)
+	nextPoolHandle  int64 = 1
+	poolHandleMutex sync.RWMutex
+)
+
+var errPoolClosed = errors.New("worker pool is closed")
+
+type poolTask struct {
+	fn     object.Object
+	args   []object.Object
+	handle *object.Goroutine
+}
+
+type workerPool struct {
+	size int
+
+	mu          sync.Mutex
+	idle        *sync.Cond // signalled when the last worker exits
+	queue       []*poolTask
+	running     int // workers started and not yet exited
+	outstanding int // tasks submitted and not yet finished
+	closed      bool
+}
+
+func newWorkerPool(size int) *workerPool {
+	p := &workerPool{size: size}
+	p.idle = sync.NewCond(&p.mu)
+	return p
+}
+
+// submit queues a call of fn with args. It fails if the pool is closed, or
+// if no worker is running and the goroutine manager refuses to start one.
+// Otherwise a refused worker only leaves the pool running below its size.
+func (p *workerPool) submit(fn object.Object, args []object.Object) (*object.Goroutine, error) {
+	task := &poolTask{fn: fn, args: args, handle: object.NewGoroutine("")}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errPoolClosed
+	}
+	if p.running < p.size {
+		worker := object.NewGoroutine("")
+		if err := globalGoroutineManager.AddAnonymousGoroutine(worker); err == nil {
+			p.running++
+			go p.work(worker)
+		} else if p.running == 0 {
+			return nil, fmt.Errorf("cannot start worker: %w", err)
+		}
+	}
+	p.queue = append(p.queue, task)
+	p.outstanding++
+	return task.handle, nil
+}
+
+func (p *workerPool) work(worker *object.Goroutine) {
+	p.mu.Lock()
+	for len(p.queue) > 0 {
+		task := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		runPoolTask(task)
+
+		p.mu.Lock()
+		p.outstanding--
+	}
+	// The worker finishes before waiters are woken, so a pool that has
+	// gone idle never reports running workers
+	p.running--
+	worker.Finish(NONE, nil)
+	if p.running == 0 {
+		p.idle.Broadcast()
+	}
+	p.mu.Unlock()
+}
+
+// runPoolTask calls the task's function and records the outcome on its
+// handle. A task cancelled while still queued is never started.
+func runPoolTask(task *poolTask) {
+	var result, errObj object.Object
+	defer func() {
+		// Recover from any panic so the worker keeps serving the queue
+		if r := recover(); r != nil {
+			errObj = &object.Error{Message: fmt.Sprintf("Goroutine panic: %v", r)}
+		}
+		if task.handle.Finish(result, errObj) {
+			pendingCancels.Add(-1)
+		}
+	}()
+
+	if task.handle.IsCancelled() {
+		errObj = cancelledError(task.handle, nil, nil)
+		return
+	}
+
+	env := object.NewEnvironment()
+	ctx := &CallContext{
+		FunctionName: "worker_pool",
+		env:          env,
+		Goroutine:    task.handle,
+	}
+	if fn, ok := task.fn.(*object.Function); ok {
+		ctx.Node = fn.Body
+	}
+
+	evaluated := evalCallExpression(task.fn, task.args, env, ctx)
+	if isError(evaluated) {
+		errObj = evaluated
+	} else {
+		result = evaluated
+	}
+}
+
+// mapItems calls fn on every item and returns the results in item order.
+// An item whose call raised an error holds the caught error instead, so one
+// failure does not abort the rest of the batch. When the goroutine running
+// ctx is cancelled while it waits, the unfinished tasks are cancelled and
+// it returns the CancelledError.
+func (p *workerPool) mapItems(fn object.Object, items []object.Object, ctx *CallContext) ([]object.Object, object.Object) {
+	handles := make([]*object.Goroutine, 0, len(items))
+	for _, item := range items {
+		handle, err := p.submit(fn, []object.Object{item})
+		if err != nil {
+			cancelTasks(handles)
+			return nil, newError("pool_map: %s", err)
+		}
+		handles = append(handles, handle)
+	}
+
+	results := make([]object.Object, len(handles))
+	for i, handle := range handles {
+		if errObj := awaitGoroutine(handle, nil, ctx.Node, ctx); errObj != nil {
+			cancelTasks(handles[i:])
+			return nil, errObj
+		}
+		if handle.Error != nil {
+			results[i] = &object.CaughtError{OriginalError: handle.Error}
+		} else {
+			results[i] = goroutineOutcome(handle)
+		}
+	}
+	return results, nil
+}
+
+// cancelTasks cancels the tasks among handles that have not finished.
+func cancelTasks(handles []*object.Goroutine) {
+	for _, handle := range handles {
+		cancelGoroutine(handle)
+	}
+}
+
+// wait blocks until every submitted task has finished and the workers have
+// exited.
+func (p *workerPool) wait() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.outstanding > 0 || p.running > 0 {
+		p.idle.Wait()
+	}
+}
+
+// close stops the pool accepting tasks and waits for the queued ones.
+func (p *workerPool) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.wait()
+}
+
+func (p *workerPool) pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.outstanding
+}
+
+// Pool handle management
+func storePoolHandle(pool *workerPool) int64 {
+	poolHandleMutex.Lock()
+	defer poolHandleMutex.Unlock()
+	handleID := nextPoolHandle
+	nextPoolHandle++
+	poolHandles[handleID] = pool
+	return handleID
+}
+
+func removePoolHandle(handleID int64) {
+	poolHandleMutex.Lock()
+	defer poolHandleMutex.Unlock()
+	delete(poolHandles, handleID)
+}
+
+// lookupPool resolves args[0] to a worker pool, returning an error object
+// naming the builtin when the argument count or handle is wrong.
+func lookupPool(name string, args []object.Object, want int) (*workerPool, int64, object.Object) {
+	if len(args) != want {
+		return nil, 0, newError("%s requires %d argument(s), got %d", name, want, len(args))
+	}
+	handleID, ok := unwrapPrimitive(args[0]).(*object.Integer)
+	if !ok {
+		return nil, 0, newError("%s: handle must be an integer", name)
+	}
+	poolHandleMutex.RLock()
+	pool, exists := poolHandles[handleID.Value]
+	poolHandleMutex.RUnlock()
+	if !exists {
+		return nil, 0, newError("%s: invalid handle", name)
+	}
+	return pool, handleID.Value, nil
+}
+
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.BoundMethod, *object.StaticMethod, *object.Builtin, *contextBuiltin, *object.Grimoire:
+		return true
+	}
+	return false
+}
+
+// The pool builtins call back into the evaluator, so like len() and set()
+// they are registered in an init function to avoid an initialization cycle.
+func init() {
+	builtins["pool_new"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("pool_new requires 1 argument, got %d", len(args))
+			}
+			workers, ok := unwrapPrimitive(args[0]).(*object.Integer)
+			if !ok {
+				return newError("pool_new: workers must be an integer, got %s", args[0].Type())
+			}
+			if workers.Value < 0 {
+				return newError("pool_new: workers cannot be negative, got %d", workers.Value)
+			}
+			size := int(workers.Value)
+			if size == 0 {
+				size = runtime.NumCPU()
+			}
+			return object.NewInteger(storePoolHandle(newWorkerPool(size)))
+		},
+	}
+	builtins["pool_size"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			pool, _, errObj := lookupPool("pool_size", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			return object.NewInteger(int64(pool.size))
+		},
+	}
+	builtins["pool_submit"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			pool, _, errObj := lookupPool("pool_submit", args, 3)
+			if errObj != nil {
+				return errObj
+			}
+			if !isCallable(args[1]) {
+				return newError("pool_submit: %s is not callable", args[1].Type())
+			}
+			var callArgs []object.Object
+			switch list := args[2].(type) {
+			case *object.Array:
+				callArgs = list.Elements
+			case *object.Tuple:
+				callArgs = list.Elements
+			default:
+				return newError("pool_submit: arguments must be an array or tuple, got %s", args[2].Type())
+			}
+			handle, err := pool.submit(args[1], append([]object.Object{}, callArgs...))
+			if err != nil {
+				return newError("pool_submit: %s", err)
+			}
+			return handle
+		},
+	}
+	// pool_map iterates its items in the caller's goroutine, which runs the
+	// iter() and next() of grimoire instances, so it needs the caller's
+	// env and ctx.
+	contextBuiltins["pool_map"] = &contextBuiltin{
+		Fn: func(args []object.Object, env *object.Environment, ctx *CallContext) object.Object {
+			pool, _, errObj := lookupPool("pool_map", args, 3)
+			if errObj != nil {
+				return errObj
+			}
+			if !isCallable(args[1]) {
+				return newError("pool_map: %s is not callable", args[1].Type())
+			}
+			items, errObj := iterableElements(unwrapPrimitive(args[2]), ctx.Node, env, ctx)
+			if errObj != nil {
+				return errObj
+			}
+			results, errObj := pool.mapItems(args[1], items, ctx)
+			if errObj != nil {
+				return errObj
+			}
+			return &object.Array{Elements: results}
+		},
+	}
+	builtins["pool_wait"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			pool, _, errObj := lookupPool("pool_wait", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			pool.wait()
+			return NONE
+		},
+	}
+	builtins["pool_pending"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			pool, _, errObj := lookupPool("pool_pending", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			return object.NewInteger(int64(pool.pending()))
+		},
+	}
+	builtins["pool_close"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			pool, handleID, errObj := lookupPool("pool_close", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			pool.close()
+			removePoolHandle(handleID)
+			return NONE
+		},
+	}
+}
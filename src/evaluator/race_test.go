@@ -0,0 +1,750 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/javanhut/TheCarrionLanguage/src/lexer"
+	"github.com/javanhut/TheCarrionLanguage/src/object"
+	"github.com/javanhut/TheCarrionLanguage/src/parser"
+)
+
+// The tests in this file are meant to be run with -race. They exercise the
+// interpreter state shared between goroutines started with diverge and the
+// goroutines net/http uses to serve Carrion route handlers.
+
+// evalInEnv evaluates input in env as if it were read from sourceFile.
+func evalInEnv(t *testing.T, input string, env *object.Environment, sourceFile string) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse errors: %s", strings.Join(p.Errors(), ", "))
+	}
+	ctx := &CallContext{
+		FunctionName:      "<program>",
+		Node:              program,
+		IsDirectExecution: true,
+		SourceFile:        sourceFile,
+		env:               env,
+	}
+	return Eval(program, env, ctx)
+}
+
+func TestDivergeSharedState(t *testing.T) {
+	input := `
+spell fib(n):
+    if n < 2:
+        return n
+    return fib(n - 1) + fib(n - 2)
+
+base = 12
+results = channel(16)
+for i in range(16):
+    diverge:
+        local = fib(base)
+        results.send(local + base - base)
+converge
+
+total = 0
+for i in range(16):
+    total = total + results.receive()
+total
+`
+	evaluated := testEval(input)
+	testIntegerObject(t, evaluated, 16*144)
+}
+
+// Goroutines writing one hash and one array must not corrupt them or
+// crash the process.
+func TestDivergeSharedCollections(t *testing.T) {
+	input := `
+shared = {}
+items = []
+for i in range(16):
+    diverge:
+        for j in range(100):
+            shared[j] = j
+            items.append(j)
+            x = shared[j] + items[0]
+            len(items)
+converge
+(len(shared), len(items))
+`
+	testInspectObject(t, input, evalWithStdlib(t, input), "(100, 1600)")
+}
+
+// Goroutines sharing a generator each receive distinct values.
+func TestDivergeSharedGenerator(t *testing.T) {
+	input := `
+spell numbers():
+    for i in range(200):
+        yield i
+gen = numbers()
+total = Atomic()
+for w in range(4):
+    diverge:
+        for n in gen:
+            total.add(n)
+converge
+total.get()
+`
+	testInspectObject(t, input, evalWithStdlib(t, input), "19900")
+}
+
+func TestConvergeNamedAndAnonymous(t *testing.T) {
+	input := `
+spell count(n):
+    total = 0
+    for i in range(n):
+        total = total + i
+    return total
+
+done = channel(4)
+diverge first:
+    done.send(count(100))
+diverge second:
+    done.send(count(200))
+diverge:
+    done.send(count(300))
+diverge:
+    done.send(count(400))
+converge first, second
+converge
+done.receive() + done.receive() + done.receive() + done.receive()
+`
+	evaluated := testEval(input)
+	testIntegerObject(t, evaluated, 4950+19900+44850+79800)
+}
+
+func TestConcurrentPrograms(t *testing.T) {
+	input := `
+spell depth(n):
+    if n == 0:
+        return 0
+    return 1 + depth(n - 1)
+
+out = channel(8)
+for i in range(8):
+    diverge:
+        out.send(depth(50))
+converge
+sum = 0
+for i in range(8):
+    sum = sum + out.receive()
+sum
+`
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result := testEval(input); isError(result) {
+				t.Errorf("unexpected error: %s", result.Inspect())
+			} else if result.Inspect() != "400" {
+				t.Errorf("expected 400, got %s", result.Inspect())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestImportInsideDiverge(t *testing.T) {
+	dir := t.TempDir()
+	helper := "spell triple(x):\n    return x * 3\n"
+	if err := os.WriteFile(filepath.Join(dir, "helper.crl"), []byte(helper), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `
+out = channel(8)
+for i in range(8):
+    diverge:
+        import "./helper"
+        out.send(triple(2))
+converge
+total = 0
+for i in range(8):
+    total = total + out.receive()
+total
+`
+	env := object.NewEnvironment()
+	evaluated := evalInEnv(t, input, env, filepath.Join(dir, "main.crl"))
+	testIntegerObject(t, evaluated, 48)
+}
+
+func TestConcurrentHTTPHandlers(t *testing.T) {
+	env := object.NewEnvironment()
+	if err := LoadMuninStdlib(env); err != nil {
+		t.Fatalf("failed to load stdlib: %v", err)
+	}
+
+	input := `
+spell greet(name):
+    return "hello " + name
+
+hits = channel(32)
+
+spell hello(request):
+    hits.send(request["path"])
+    return {"status": 200, "body": greet(request["query"]["name"])}
+
+handle = server("web", "127.0.0.1:0")
+http_register_route(handle, "GET", "/hello", hello)
+handle
+`
+	handle := evalInEnv(t, input, env, "")
+	if isError(handle) {
+		t.Fatalf("failed to start server: %s", handle.Inspect())
+	}
+	info := evalInEnv(t, "socket_get_info(handle)[\"address\"]", env, "")
+	defer evalInEnv(t, "socket_close(handle)", env, "")
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("caller%d", i)
+			resp, err := http.Get(fmt.Sprintf("http://%s/hello?name=%s", info.Inspect(), name))
+			if err != nil {
+				t.Errorf("request failed: %v", err)
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK || string(body) != "hello "+name {
+				t.Errorf("expected 200 %q, got %d %q", "hello "+name, resp.StatusCode, body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if hits := evalInEnv(t, "len(hits)", env, ""); hits.Inspect() != "16" {
+		t.Errorf("expected 16 handler calls, got %s", hits.Inspect())
+	}
+}
+
+func evalWithStdlib(t *testing.T, input string) object.Object {
+	t.Helper()
+	env := object.NewEnvironment()
+	if err := LoadMuninStdlib(env); err != nil {
+		t.Fatalf("failed to load stdlib: %v", err)
+	}
+	return evalInEnv(t, input, env, "")
+}
+
+func TestSyncPrimitives(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Reading and then writing an entry is not atomic; the mutex makes it safe
+		{`
+counts = {"n": 0}
+lock = Mutex()
+for i in range(32):
+    diverge:
+        autoclose lock.lock():
+            counts["n"] = counts["n"] + 1
+converge
+(counts["n"], lock.is_locked())`, "(32, false)"},
+		{`
+counter = Atomic()
+for i in range(32):
+    diverge:
+        counter.increment()
+converge
+counter.get()`, "32"},
+		{`
+a = Atomic(5)
+(a.compare_and_swap(5, 9), a.compare_and_swap(5, 1), a.set(3), a.add(-4))`, "(true, false, 9, -1)"},
+		{`
+lock = Mutex()
+guard = lock.lock()
+held = (lock.is_locked(), lock.try_lock())
+guard.close()
+guard.close()
+(held, lock.is_locked())`, "((true, false), false)"},
+		{`
+wg = WaitGroup()
+done = Atomic()
+wg.add(8)
+for i in range(8):
+    diverge:
+        done.increment()
+        wg.done()
+wg.wait()
+(done.get(), wg.count())`, "(8, 0)"},
+		{`
+sem = Semaphore(2)
+peak = Atomic()
+active = Atomic()
+for i in range(8):
+    diverge:
+        autoclose sem.acquire():
+            now = active.increment()
+            seen = peak.get()
+            while now > seen and not peak.compare_and_swap(seen, now):
+                seen = peak.get()
+            active.decrement()
+converge
+(peak.get() <= 2, sem.available())`, "(true, 2)"},
+		{`
+rw = RWLock()
+shared = {"v": 0}
+for i in range(8):
+    diverge:
+        autoclose rw.lock():
+            shared["v"] = shared["v"] + 1
+    diverge:
+        autoclose rw.read_lock():
+            x = shared["v"]
+converge
+shared["v"]`, "8"},
+		{`
+once = Once()
+calls = Atomic()
+spell setup():
+    calls.increment()
+for i in range(8):
+    diverge:
+        once.do(setup)
+converge
+(calls.get(), once.do(setup))`, "(1, false)"},
+		// Closing twice is harmless
+		{`
+a = Atomic(3)
+a.close()
+a.close()
+once = Once()
+once.close()
+a._closed`, "true"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, evalWithStdlib(t, tt.input), tt.expected)
+	}
+
+	for _, input := range []string{
+		"Mutex().unlock()",
+		"RWLock().read_unlock()",
+		"WaitGroup().done()",
+		"Semaphore(0)",
+		"Semaphore(1).release()",
+		"a = Atomic()\na.close()\na.get()",
+		"m = Mutex()\nm.close()\nm.lock()",
+	} {
+		if result := evalWithStdlib(t, input); !isError(result) {
+			t.Errorf("input %q: expected error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestUnclosedSyncPrimitiveIsFreed(t *testing.T) {
+	env := object.NewEnvironment()
+	if err := LoadMuninStdlib(env); err != nil {
+		t.Fatalf("failed to load stdlib: %v", err)
+	}
+	// Keep only the raw handle, so the Mutex instance itself can be collected
+	testInspectObject(t, "handle", evalInEnv(t, "handle = Mutex().handle\nmutex_is_locked(handle)", env, ""), "false")
+
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+		if isError(evalInEnv(t, "mutex_is_locked(handle)", env, "")) {
+			return
+		}
+	}
+	t.Errorf("handle of an unreachable Mutex was never freed")
+}
+
+func TestGoroutineResults(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`
+diverge answer:
+    return 6 * 7
+result = converge answer
+result`, "42"},
+		{`
+diverge first_half:
+    return 1
+diverge second_half:
+    "two"
+results = converge first_half, second_half
+results`, "(1, two)"},
+		{`
+diverge quick:
+    return [1, 2]
+h = goroutine("quick")
+(h.result(), h.name, h.wait(), h.status())`, "([1, 2], quick, true, completed)"},
+		{`
+diverge failing:
+    raise "boom"
+converge failing
+"contained"`, "contained"},
+		{`
+diverge failing_value:
+    raise "boom"
+attempt:
+    x = converge failing_value
+ensnare:
+    x = "reraised"
+x`, "reraised"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestGoroutineTimeoutAndCancel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// A timed out converge leaves the goroutine to be converged again
+		{`
+gate = channel()
+diverge gated:
+    gate.receive()
+    return "opened"
+attempt:
+    converge gated timeout 0.05
+    state = "finished"
+ensnare ("TimeoutError"):
+    state = "timed out"
+gate.send(True)
+(state, converge gated timeout 5)`, "(timed out, opened)"},
+		// Cancellation interrupts loops, including ones in called spells
+		{`
+spell spin():
+    n = 0
+    while True:
+        n = n + 1
+diverge spinner:
+    spin()
+h = goroutine("spinner")
+(h.cancel(), h.cancel(), h.wait(5), h.status(), h.is_cancelled())`, "(true, false, true, cancelled, true)"},
+		{`
+items = channel()
+diverge reader:
+    for item in items:
+        print(item)
+h = goroutine("reader")
+h.cancel()
+attempt:
+    converge reader
+    outcome = "finished"
+ensnare ("CancelledError"):
+    outcome = "not raised"
+(outcome, h.status())`, "(finished, cancelled)"},
+		{`
+idle = channel()
+diverge selector:
+    select:
+        case value = idle.receive():
+            return value
+h = goroutine("selector")
+h.cancel()
+attempt:
+    h.result()
+    outcome = "no error"
+ensnare ("CancelledError"):
+    outcome = "cancelled"
+outcome`, "cancelled"},
+		{`
+blocker = channel()
+diverge sender:
+    blocker.send(1)
+h = goroutine("sender")
+(h.wait(0.05), h.cancel(), h.wait(5), h.status())`, "(false, true, true, cancelled)"},
+		{`
+diverge finished_first:
+    return 1
+h = goroutine("finished_first")
+h.wait()
+h.cancel()`, "false"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+
+	for _, input := range []string{
+		`goroutine("never_started")`,
+		"converge never_started timeout 1",
+		"diverge typed_timeout:\n    return 1\nconverge typed_timeout timeout \"soon\"",
+	} {
+		if result := testEval(input); !isError(result) {
+			t.Errorf("input %q: expected error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestGoroutinesIntrospection(t *testing.T) {
+	input := `
+gate = channel()
+diverge inspected_ok:
+    return 1
+diverge inspected_failed:
+    raise "boom"
+diverge inspected_waiting:
+    gate.receive()
+goroutine("inspected_ok").wait()
+goroutine("inspected_failed").wait()
+stats = goroutines()
+gate.send(True)
+converge inspected_ok, inspected_failed, inspected_waiting
+(stats["completed"] >= 1, stats["failed"] >= 1, stats["running"] >= 1, "inspected_waiting" in stats["names"])
+`
+	evaluated := testEval(input)
+	if evaluated.Inspect() != "(true, true, true, true)" {
+		t.Errorf("unexpected goroutines() stats: %s", evaluated.Inspect())
+	}
+}
+
+func TestParallelMap(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`
+spell square(x):
+    return x * x
+parallel_map(square, range(10), workers=3)`, "[0, 1, 4, 9, 16, 25, 36, 49, 64, 81]"},
+		// Failures stay in their slot and the rest of the batch still runs
+		{`
+spell validate(x):
+    if x % 3 == 0:
+        raise "bad " + str(x)
+    return x
+results = parallel_map(validate, [1, 2, 3, 4, 5, 6, 7])
+failures = parallel_errors(results)
+(results[0], results[6], len(failures), failures[0][0], failures[1][1].message)`, "(1, 7, 2, 2, Error: bad 6)"},
+		{`
+spell slow(x):
+    now = active.increment()
+    seen = peak.get()
+    while now > seen and not peak.compare_and_swap(seen, now):
+        seen = peak.get()
+    total = 0
+    for i in range(200):
+        total = total + i
+    active.decrement()
+    return x
+active = Atomic()
+peak = Atomic()
+results = parallel_map(slow, range(40), workers=4)
+(results[0], results[39], len(results), peak.get() <= 4)`, "(0, 39, 40, true)"},
+		{`
+spell upper(s):
+    return s.upper()
+parallel_map(upper, ("a", "b"), workers=1)`, "[A, B]"},
+		{`parallel_map(len, [], workers=2)`, "[]"},
+		{`
+grim Counter:
+    init(n):
+        self.n = n
+        self.i = 0
+    spell iter():
+        return self
+    spell next():
+        if self.i >= self.n:
+            raise StopIteration("done")
+        self.i = self.i + 1
+        return self.i
+spell square(x):
+    return x * x
+parallel_map(square, Counter(3), workers=2)`, "[1, 4, 9]"},
+		// Cancelling the caller stops waiting and cancels the unfinished tasks
+		{`
+spell spin(x):
+    started.increment()
+    while True:
+        x = x + 1
+started = Atomic()
+diverge mapper:
+    parallel_map(spin, range(4), workers=2)
+h = goroutine("mapper")
+while started.get() < 2:
+    pass
+(h.cancel(), h.wait(5), h.status())`, "(true, true, cancelled)"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, evalWithStdlib(t, tt.input), tt.expected)
+	}
+
+	for _, input := range []string{
+		"parallel_map(5, [1, 2])",
+		"spell f(x):\n    return x\nparallel_map(f, [1], workers=-1)",
+		"spell f(x):\n    return x\nparallel_map(f, 10)",
+	} {
+		if result := evalWithStdlib(t, input); !isError(result) {
+			t.Errorf("input %q: expected error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+// Recursion limits are counted per goroutine, so workers running the same
+// spell never add up each other's depth.
+func TestRecursionDepthPerGoroutine(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`
+spell depth(n):
+    if n == 0:
+        return 0
+    return 1 + depth(n - 1)
+spell deep(x):
+    return depth(700)
+parallel_map(deep, range(8), workers=8)`, "[700, 700, 700, 700, 700, 700, 700, 700]"},
+		{`
+spell depth(n):
+    if n == 0:
+        return 0
+    return 1 + depth(n - 1)
+total = Atomic()
+diverge a:
+    total.add(depth(700))
+diverge b:
+    total.add(depth(700))
+converge
+total.get()`, "1400"},
+	}
+
+	for _, tt := range tests {
+		for run := 0; run < 5; run++ {
+			if !testInspectObject(t, tt.input, evalWithStdlib(t, tt.input), tt.expected) {
+				break
+			}
+		}
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`
+spell add(a, b):
+    return a + b
+pool = WorkerPool(2)
+handles = [pool.submit(add, 1, 2), pool.submit(add, 3, 4)]
+pool.wait()
+pool.close()
+(pool.workers, handles[0].result(), handles[1].result(), handles[1].status(), pool.pending())`, "(2, 3, 7, completed, 0)"},
+		{`
+spell spin():
+    n = 0
+    while True:
+        n = n + 1
+spell fail():
+    raise "boom"
+pool = WorkerPool(1)
+spinner = pool.submit(spin)
+queued = pool.submit(fail)
+queued.cancel()
+spinner.cancel()
+pool.wait()
+(spinner.status(), queued.status())`, "(cancelled, cancelled)"},
+		{`
+spell fail():
+    raise "boom"
+pool = WorkerPool(2)
+handle = pool.submit(fail)
+attempt:
+    handle.result()
+    outcome = "no error"
+ensnare:
+    outcome = "reraised"
+(outcome, handle.status())`, "(reraised, failed)"},
+		{`
+spell double(x):
+    return x * 2
+pool = WorkerPool()
+out = {}
+autoclose pool:
+    out["first"] = pool.map(double, [1, 2, 3])
+    out["second"] = pool.map(double, [4])
+pool.close()
+(out["first"], out["second"], pool.workers > 0)`, "([2, 4, 6], [8], true)"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, evalWithStdlib(t, tt.input), tt.expected)
+	}
+
+	for _, input := range []string{
+		"pool = WorkerPool(1)\npool.close()\npool.submit(len, [])",
+		"WorkerPool(1).submit(5)",
+	} {
+		if result := evalWithStdlib(t, input); !isError(result) {
+			t.Errorf("input %q: expected error, got %s", input, result.Inspect())
+		}
+	}
+}
+
+func TestGoroutineLimits(t *testing.T) {
+	manager := GetGoroutineManager()
+	manager.CleanupCompletedGoroutines()
+	_, _, anonymous, _ := manager.GetCapacityInfo()
+	manager.SetMaxLimits(0, anonymous+2)
+	defer manager.SetMaxLimits(0, 0)
+
+	// Pools shrink to the workers the manager allows
+	input := `
+spell slow(x):
+    now = active.increment()
+    seen = peak.get()
+    while now > seen and not peak.compare_and_swap(seen, now):
+        seen = peak.get()
+    total = 0
+    for i in range(200):
+        total = total + i
+    active.decrement()
+    return x * 10
+active = Atomic()
+peak = Atomic()
+results = parallel_map(slow, range(20), workers=8)
+(results[19], len(results), peak.get() <= 2)`
+	if evaluated := evalWithStdlib(t, input); evaluated.Inspect() != "(190, 20, true)" {
+		t.Errorf("unexpected parallel_map result under limits: %s", evaluated.Inspect())
+	}
+
+	// diverge reports the limit instead of starting an untracked goroutine
+	input = `
+gate = channel()
+diverge:
+    gate.receive()
+diverge:
+    gate.receive()
+attempt:
+    diverge:
+        gate.receive()
+    outcome = "started"
+ensnare:
+    outcome = "refused"
+gate.send(1)
+gate.send(2)
+converge
+outcome`
+	if evaluated := testEval(input); evaluated.Inspect() != "refused" {
+		t.Errorf("expected diverge to be refused at the limit, got %s", evaluated.Inspect())
+	}
+}
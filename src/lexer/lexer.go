@@ -2,8 +2,10 @@ package lexer
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/javanhut/TheCarrionLanguage/src/token"
 )
@@ -24,6 +26,22 @@ type Lexer struct {
 	indentStyle     byte // 0 = unset, ' ' = spaces, '\t' = tabs
 	indentStyleLine int  // Line number (1-indexed) where indentation style was first set
 	indentError     string // Non-empty if an indentation error was detected
+
+	// Inside (), [] and {} newlines and indentation are not significant.
+	// bracketDepth counts the open brackets; blockBrackets saves the depth
+	// while the block body of an anonymous spell written inside brackets is
+	// lexed, and spellDepth is the depth of a spell still waiting for its ':'
+	// (-1 if none).
+	bracketDepth  int
+	blockBrackets []bracketFrame
+	spellDepth    int
+}
+
+// bracketFrame records the bracket depth suspended by a block body and the
+// indentation of the line that opened it.
+type bracketFrame struct {
+	depth  int
+	indent int
 }
 
 func New(input string) *Lexer {
@@ -37,6 +55,7 @@ func NewWithFilename(input string, sourceFile string) *Lexer {
 		lines:       rawLines,
 		indentStack: []int{0},
 		sourceFile:  sourceFile, // Use sourceFile instead of filename to avoid confusion
+		spellDepth:  -1,
 	}
 	if len(l.lines) == 0 {
 		l.finished = true
@@ -72,6 +91,10 @@ func (l *Lexer) NextToken() token.Token {
    // Handle indentation changes at the start of a new line, but be selective about tokens
    if l.charIndex == 0 && !l.indentResolved {
        l.indentResolved = true
+       if l.bracketDepth > 0 {
+           // A continuation line inside brackets; its indentation is free-form
+           return l.NextToken()
+       }
        newIndent, indentErr := l.measureIndent(l.currLine)
        if indentErr != "" {
            return token.Token{
@@ -83,6 +106,10 @@ func (l *Lexer) NextToken() token.Token {
            }
        }
        tok := l.handleIndentChange(newIndent)
+       for n := len(l.blockBrackets); n > 0 && newIndent <= l.blockBrackets[n-1].indent; n-- {
+           l.bracketDepth = l.blockBrackets[n-1].depth
+           l.blockBrackets = l.blockBrackets[:n-1]
+       }
        // Return all indentation-related tokens
        if tok.Type == token.NEWLINE || tok.Type == token.INDENT || tok.Type == token.DEDENT {
            return tok
@@ -93,6 +120,11 @@ func (l *Lexer) NextToken() token.Token {
 
    // Generate NEWLINE token at end of line
    if l.charIndex >= len(l.currLine) {
+       if l.bracketDepth > 0 {
+           // Implicit line joining inside brackets
+           l.advanceLine()
+           return l.NextToken()
+       }
        l.advanceLine()
        // Return NEWLINE token for explicit line break
        return l.newToken(token.NEWLINE, "")
@@ -113,6 +145,14 @@ func (l *Lexer) NextToken() token.Token {
 		}
 		return l.readIdentifier()
 	}
+	if ch == 'b' {
+		next := l.peekChar()
+		if next == '"' || next == '\'' {
+			l.charIndex++
+			return l.readBytes()
+		}
+		return l.readIdentifier()
+	}
 	if ch == 'i' {
 		next := l.peekChar()
 		if next == '"' || next == '\'' {
@@ -121,6 +161,14 @@ func (l *Lexer) NextToken() token.Token {
 		}
 		return l.readIdentifier()
 	}
+	if ch == 'r' {
+		next := l.peekChar()
+		if next == '"' || next == '\'' {
+			l.charIndex++
+			return l.readRawString()
+		}
+		return l.readIdentifier()
+	}
 
 	switch ch {
 	case '=':
@@ -245,6 +293,18 @@ func (l *Lexer) NextToken() token.Token {
 
 	case ':':
 		l.charIndex++
+		if l.spellDepth == l.bracketDepth {
+			if l.bracketDepth > 0 && l.restOfLineBlank() {
+				// An anonymous spell's block body: indentation is significant
+				// again until the body dedents back to the opening line
+				l.blockBrackets = append(l.blockBrackets, bracketFrame{
+					depth:  l.bracketDepth,
+					indent: l.indentStack[len(l.indentStack)-1],
+				})
+				l.bracketDepth = 0
+			}
+			l.spellDepth = -1
+		}
 		return l.newToken(token.COLON, ":")
 
 	case ';':
@@ -252,26 +312,32 @@ func (l *Lexer) NextToken() token.Token {
 		return l.newToken(token.SEMICOLON, ";")
 	case '(':
 		l.charIndex++
+		l.bracketDepth++
 		return l.newToken(token.LPAREN, "(")
 
 	case ')':
 		l.charIndex++
+		l.closeBracket()
 		return l.newToken(token.RPAREN, ")")
 
 	case '[':
 		l.charIndex++
+		l.bracketDepth++
 		return l.newToken(token.LBRACK, "[")
 
 	case ']':
 		l.charIndex++
+		l.closeBracket()
 		return l.newToken(token.RBRACK, "]")
 
 	case '{':
 		l.charIndex++
+		l.bracketDepth++
 		return l.newToken(token.LBRACE, "{")
 
 	case '}':
 		l.charIndex++
+		l.closeBracket()
 		return l.newToken(token.RBRACE, "}")
 
 	case '.':
@@ -315,9 +381,12 @@ func (l *Lexer) NextToken() token.Token {
 			return l.readIdentifier()
 		} else if isDigit(ch) {
 			return l.readNumber()
+		} else if r, size := utf8.DecodeRuneInString(l.currLine[l.charIndex:]); isIdentifierStart(r) {
+			return l.readIdentifier()
 		} else {
-			l.charIndex++
-			return token.Token{Type: token.ILLEGAL, Literal: string(ch)}
+			start := l.charIndex
+			l.charIndex += size
+			return token.Token{Type: token.ILLEGAL, Literal: l.currLine[start:l.charIndex]}
 		}
 	}
 }
@@ -356,6 +425,7 @@ func (l *Lexer) readFString() token.Token {
 	}
 
 	var sb strings.Builder
+	var escErr string
 
 	if isTriple {
 		for {
@@ -380,20 +450,8 @@ func (l *Lexer) readFString() token.Token {
 			if ch == '\\' {
 				l.charIndex++
 				if l.charIndex < len(l.currLine) {
-					esc := l.currLine[l.charIndex]
-					switch esc {
-					case 'n':
-						sb.WriteByte('\n')
-					case 't':
-						sb.WriteByte('\t')
-					case 'r':
-						sb.WriteByte('\r')
-					case '\\':
-						sb.WriteByte('\\')
-					case openingQuote:
-						sb.WriteByte(openingQuote)
-					default:
-						sb.WriteByte(esc)
+					if msg := l.readEscape(&sb); msg != "" && escErr == "" {
+						escErr = msg
 					}
 				}
 			} else {
@@ -415,20 +473,8 @@ func (l *Lexer) readFString() token.Token {
 			if ch == '\\' {
 				l.charIndex++
 				if l.charIndex < len(l.currLine) {
-					esc := l.currLine[l.charIndex]
-					switch esc {
-					case 'n':
-						sb.WriteByte('\n')
-					case 't':
-						sb.WriteByte('\t')
-					case 'r':
-						sb.WriteByte('\r')
-					case '\\':
-						sb.WriteByte('\\')
-					case openingQuote:
-						sb.WriteByte(openingQuote)
-					default:
-						sb.WriteByte(esc)
+					if msg := l.readEscape(&sb); msg != "" && escErr == "" {
+						escErr = msg
 					}
 				}
 			} else {
@@ -438,6 +484,9 @@ func (l *Lexer) readFString() token.Token {
 		}
 	}
 
+	if escErr != "" {
+		return token.Token{Type: token.ILLEGAL, Literal: escErr}
+	}
 	return token.Token{
 		Type:    token.FSTRING,
 		Literal: sb.String(),
@@ -537,6 +586,32 @@ func (l *Lexer) handleIndentChange(newIndent int) token.Token {
 	return l.newToken(token.DEDENT, "")
 }
 
+// closeBracket records a closing bracket. Unbalanced closers are left for
+// the parser to report.
+func (l *Lexer) closeBracket() {
+	if l.bracketDepth > 0 {
+		l.bracketDepth--
+	}
+	if l.spellDepth > l.bracketDepth {
+		l.spellDepth = -1
+	}
+}
+
+// restOfLineBlank reports whether only whitespace or a comment follows the
+// current position.
+func (l *Lexer) restOfLineBlank() bool {
+	for i := l.charIndex; i < len(l.currLine); i++ {
+		switch l.currLine[i] {
+		case ' ', '\t', '\r':
+			continue
+		case '#':
+			return true
+		}
+		return false
+	}
+	return true
+}
+
 func (l *Lexer) advanceLine() {
 	l.lineIndex++
 	l.indentResolved = false
@@ -633,6 +708,7 @@ func (l *Lexer) readString() token.Token {
 	}
 
 	var sb strings.Builder
+	var escErr string
 
 	if isTriple {
 		for {
@@ -656,20 +732,8 @@ func (l *Lexer) readString() token.Token {
 			if ch == '\\' {
 				l.charIndex++
 				if l.charIndex < len(l.currLine) {
-					esc := l.currLine[l.charIndex]
-					switch esc {
-					case 'n':
-						sb.WriteByte('\n')
-					case 't':
-						sb.WriteByte('\t')
-					case 'r':
-						sb.WriteByte('\r')
-					case '\\':
-						sb.WriteByte('\\')
-					case byte(quoteChar):
-						sb.WriteByte(quoteChar)
-					default:
-						sb.WriteByte(esc)
+					if msg := l.readEscape(&sb); msg != "" && escErr == "" {
+						escErr = msg
 					}
 				}
 			} else {
@@ -677,6 +741,9 @@ func (l *Lexer) readString() token.Token {
 			}
 			l.charIndex++
 		}
+		if escErr != "" {
+			return token.Token{Type: token.ILLEGAL, Literal: escErr}
+		}
 		return token.Token{
 			Type:    token.DOCSTRING,
 			Literal: sb.String(),
@@ -694,20 +761,8 @@ func (l *Lexer) readString() token.Token {
 			if ch == '\\' {
 				l.charIndex++
 				if l.charIndex < len(l.currLine) {
-					esc := l.currLine[l.charIndex]
-					switch esc {
-					case 'n':
-						sb.WriteByte('\n')
-					case 't':
-						sb.WriteByte('\t')
-					case 'r':
-						sb.WriteByte('\r')
-					case '\\':
-						sb.WriteByte('\\')
-					case byte(quoteChar):
-						sb.WriteByte(quoteChar)
-					default:
-						sb.WriteByte(esc)
+					if msg := l.readEscape(&sb); msg != "" && escErr == "" {
+						escErr = msg
 					}
 				}
 			} else {
@@ -715,6 +770,9 @@ func (l *Lexer) readString() token.Token {
 			}
 			l.charIndex++
 		}
+		if escErr != "" {
+			return token.Token{Type: token.ILLEGAL, Literal: escErr}
+		}
 		return token.Token{
 			Type:    token.STRING,
 			Literal: sb.String(),
@@ -722,10 +780,158 @@ func (l *Lexer) readString() token.Token {
 	}
 }
 
+// readEscape decodes the escape sequence after a backslash in a string
+// literal, leaving charIndex on its last character. \xNN, \uXXXX and
+// \UXXXXXXXX name code points, which are written as UTF-8. A malformed
+// escape is reported through the returned message.
+func (l *Lexer) readEscape(sb *strings.Builder) string {
+	esc := l.currLine[l.charIndex]
+	width := 0
+	switch esc {
+	case 'n':
+		sb.WriteByte('\n')
+	case 't':
+		sb.WriteByte('\t')
+	case 'r':
+		sb.WriteByte('\r')
+	case '0':
+		sb.WriteByte(0)
+	case 'x':
+		width = 2
+	case 'u':
+		width = 4
+	case 'U':
+		width = 8
+	default:
+		// \\, quotes, \$ and unknown escapes stand for themselves
+		sb.WriteByte(esc)
+	}
+	if width == 0 {
+		return ""
+	}
+	if l.charIndex+width >= len(l.currLine) {
+		return fmt.Sprintf("truncated \\%c escape: expected %d hex digits", esc, width)
+	}
+	digits := l.currLine[l.charIndex+1 : l.charIndex+1+width]
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return fmt.Sprintf("invalid \\%c escape: %q is not %d hex digits", esc, digits, width)
+	}
+	if !utf8.ValidRune(rune(value)) {
+		return fmt.Sprintf("invalid \\%c escape: U+%04X is not a valid code point", esc, value)
+	}
+	sb.WriteRune(rune(value))
+	l.charIndex += width
+	return ""
+}
+
+// readRawString reads an r"..." literal, in single or triple quotes.
+// Backslashes are kept as written; a backslash still stops the next
+// character from closing the literal, so r"\"" is the two characters \".
+func (l *Lexer) readRawString() token.Token {
+	quoteChar := l.currLine[l.charIndex]
+	l.charIndex++
+
+	isTriple := l.charIndex+1 < len(l.currLine) &&
+		l.currLine[l.charIndex] == quoteChar &&
+		l.currLine[l.charIndex+1] == quoteChar
+	if isTriple {
+		l.charIndex += 2
+	}
+
+	var sb strings.Builder
+	for {
+		if l.charIndex >= len(l.currLine) {
+			if !isTriple {
+				return token.Token{Type: token.ILLEGAL, Literal: "unterminated raw string literal"}
+			}
+			sb.WriteByte('\n')
+			l.advanceLine()
+			if l.finished {
+				return token.Token{Type: token.ILLEGAL, Literal: "unterminated raw string literal"}
+			}
+			continue
+		}
+		ch := l.currLine[l.charIndex]
+		if ch == quoteChar {
+			if !isTriple {
+				l.charIndex++
+				return token.Token{Type: token.STRING, Literal: sb.String()}
+			}
+			if l.charIndex+2 < len(l.currLine) &&
+				l.currLine[l.charIndex+1] == quoteChar &&
+				l.currLine[l.charIndex+2] == quoteChar {
+				l.charIndex += 3
+				return token.Token{Type: token.DOCSTRING, Literal: sb.String()}
+			}
+		}
+		sb.WriteByte(ch)
+		if ch == '\\' && l.charIndex+1 < len(l.currLine) {
+			l.charIndex++
+			sb.WriteByte(l.currLine[l.charIndex])
+		}
+		l.charIndex++
+	}
+}
+
+// readBytes reads a single-line b"..." literal. Unlike string escapes,
+// \xNN writes a single raw byte, so the literal holds byte values.
+func (l *Lexer) readBytes() token.Token {
+	quoteChar := l.currLine[l.charIndex]
+	l.charIndex++
+
+	var sb strings.Builder
+	for {
+		if l.charIndex >= len(l.currLine) {
+			return token.Token{Type: token.ILLEGAL, Literal: "unterminated bytes literal"}
+		}
+		ch := l.currLine[l.charIndex]
+		if ch == quoteChar {
+			l.charIndex++
+			break
+		}
+		if ch == '\\' && l.charIndex+1 < len(l.currLine) {
+			l.charIndex++
+			esc := l.currLine[l.charIndex]
+			switch esc {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '0':
+				sb.WriteByte(0)
+			case 'x':
+				if l.charIndex+2 >= len(l.currLine) {
+					return token.Token{Type: token.ILLEGAL, Literal: "truncated \\x escape in bytes literal"}
+				}
+				value, err := strconv.ParseUint(l.currLine[l.charIndex+1:l.charIndex+3], 16, 8)
+				if err != nil {
+					return token.Token{Type: token.ILLEGAL, Literal: "invalid \\x escape in bytes literal"}
+				}
+				sb.WriteByte(byte(value))
+				l.charIndex += 2
+			default:
+				// \\, \' and \" stand for themselves
+				sb.WriteByte(esc)
+			}
+		} else {
+			sb.WriteByte(ch)
+		}
+		l.charIndex++
+	}
+	return token.Token{Type: token.BYTES, Literal: sb.String()}
+}
+
 func (l *Lexer) readIdentifier() token.Token {
 	start := l.charIndex
-	for l.charIndex < len(l.currLine) && isLetterOrDigit(l.currLine[l.charIndex]) {
-		l.charIndex++
+	for l.charIndex < len(l.currLine) {
+		r, size := utf8.DecodeRuneInString(l.currLine[l.charIndex:])
+		if !isIdentifierPart(r) {
+			break
+		}
+		l.charIndex += size
 	}
 	literal := l.currLine[start:l.charIndex]
 
@@ -760,6 +966,9 @@ func (l *Lexer) readIdentifier() token.Token {
 	}
 
 	tokType := token.LookupIdent(literal)
+	if tokType == token.SPELL {
+		l.spellDepth = l.bracketDepth
+	}
 	return token.Token{
 		Type:     tokType,
 		Literal:  literal,
@@ -769,32 +978,94 @@ func (l *Lexer) readIdentifier() token.Token {
 	}
 }
 
+// isLetterOrDigit reports whether ch can continue an identifier. Bytes of
+// multi-byte UTF-8 sequences count, so a word boundary is never found in the
+// middle of a Unicode identifier.
 func isLetterOrDigit(ch byte) bool {
-	return isLetter(ch) || unicode.IsDigit(rune(ch))
+	return isLetter(ch) || isDigit(ch) || ch >= utf8.RuneSelf
+}
+
+// isIdentifierStart reports whether r can begin an identifier: a Unicode
+// letter or an underscore.
+func isIdentifierStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
 }
 
+// isIdentifierPart reports whether r can continue an identifier, which also
+// allows digits, combining marks and connector punctuation.
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.IsDigit(r) ||
+		unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc)
+}
+
+// readNumber reads a numeric literal: 42, 1_000, 0xFF, 0o755, 0b1010,
+// 3.14, 1e-9 or 19.99d. Letters and underscores that follow are kept in the
+// literal so the parser can report malformed numbers as a whole.
 func (l *Lexer) readNumber() token.Token {
 	start := l.charIndex
+	if l.currLine[start] == '0' && start+1 < len(l.currLine) &&
+		strings.IndexByte("xXoObB", l.currLine[start+1]) >= 0 {
+		l.charIndex += 2
+		l.skipIdentifierChars()
+		return l.numberToken(token.INT, l.currLine[start:l.charIndex], start)
+	}
+
 	isFloat := false
-	for l.charIndex < len(l.currLine) {
-		ch := l.currLine[l.charIndex]
-		if ch == '.' {
-			if isFloat {
-				break
-			}
+	l.skipDigits()
+	// A dot followed by a name is attribute access, not a fraction
+	if l.peekCharAt(0) == '.' && !isLetter(l.peekCharAt(1)) {
+		isFloat = true
+		l.charIndex++
+		l.skipDigits()
+	}
+	if ch := l.peekCharAt(0); ch == 'e' || ch == 'E' {
+		next := l.peekCharAt(1)
+		if isDigit(next) || next == '+' || next == '-' {
 			isFloat = true
-		} else if !isDigit(ch) {
-			break
+			l.charIndex += 2
+			l.skipDigits()
 		}
-		l.charIndex++
 	}
+
 	literal := l.currLine[start:l.charIndex]
+	suffixStart := l.charIndex
+	l.skipIdentifierChars()
+	// A trailing 'd' marks a decimal literal: 19.99d
+	if l.currLine[suffixStart:l.charIndex] == "d" {
+		return l.numberToken(token.DECIMAL, literal, start)
+	}
+	literal = l.currLine[start:l.charIndex]
 	if isFloat {
-		return token.Token{Type: token.FLOAT, Literal: literal}
+		return l.numberToken(token.FLOAT, literal, start)
+	}
+	return l.numberToken(token.INT, literal, start)
+}
+
+// numberToken positions a numeric token at start, so the parser can point
+// at malformed literals.
+func (l *Lexer) numberToken(tokType token.TokenType, literal string, start int) token.Token {
+	return token.Token{
+		Type:     tokType,
+		Literal:  literal,
+		Filename: l.sourceFile,
+		Line:     l.lineIndex + 1,
+		Column:   start + 1,
+	}
+}
+
+func (l *Lexer) skipDigits() {
+	for l.charIndex < len(l.currLine) && (isDigit(l.currLine[l.charIndex]) || l.currLine[l.charIndex] == '_') {
+		l.charIndex++
 	}
-	return token.Token{Type: token.INT, Literal: literal}
 }
 
+func (l *Lexer) skipIdentifierChars() {
+	for l.charIndex < len(l.currLine) && isLetterOrDigit(l.currLine[l.charIndex]) {
+		l.charIndex++
+	}
+}
+
+
 func (l *Lexer) newToken(tokenType token.TokenType, literal string) token.Token {
 	return token.Token{
 		Type:     tokenType,
@@ -805,12 +1076,14 @@ func (l *Lexer) newToken(tokenType token.TokenType, literal string) token.Token
 	}
 }
 
+// isLetter and isDigit classify single ASCII bytes; non-ASCII identifiers
+// are decoded as runes by readIdentifier.
 func isLetter(ch byte) bool {
-	return unicode.IsLetter(rune(ch)) || ch == '_'
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
 
 func isDigit(ch byte) bool {
-	return unicode.IsDigit(rune(ch))
+	return '0' <= ch && ch <= '9'
 }
 
 func isHorizontalWhitespace(ch byte) bool {
@@ -834,6 +1107,7 @@ func (l *Lexer) readStringInterpolation() token.Token {
 	}
 
 	var sb strings.Builder
+	var escErr string
 	isBraceOpen := false
 	braceDepth := 0
 	exprStart := 0
@@ -865,22 +1139,8 @@ func (l *Lexer) readStringInterpolation() token.Token {
 			if ch == '\\' {
 				l.charIndex++
 				if l.charIndex < len(l.currLine) {
-					esc := l.currLine[l.charIndex]
-					switch esc {
-					case 'n':
-						sb.WriteByte('\n')
-					case 't':
-						sb.WriteByte('\t')
-					case 'r':
-						sb.WriteByte('\r')
-					case '\\':
-						sb.WriteByte('\\')
-					case openingQuote:
-						sb.WriteByte(openingQuote)
-					case '$':
-						sb.WriteByte('$')
-					default:
-						sb.WriteByte(esc)
+					if msg := l.readEscape(&sb); msg != "" && escErr == "" {
+						escErr = msg
 					}
 				}
 				return true
@@ -916,6 +1176,9 @@ func (l *Lexer) readStringInterpolation() token.Token {
 		l.charIndex++
 	}
 
+	if escErr != "" {
+		return token.Token{Type: token.ILLEGAL, Literal: escErr}
+	}
 	return token.Token{
 		Type:    token.INTERP,
 		Literal: sb.String(),
@@ -252,3 +252,148 @@ z = 3`
 		}
 	}
 }
+
+func TestBytesLiteral(t *testing.T) {
+	l := New(`data = b"a\x00\xff\n" + b'\''`)
+
+	var literals []string
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ILLEGAL {
+			t.Fatalf("unexpected ILLEGAL token: %q", tok.Literal)
+		}
+		if tok.Type == token.BYTES {
+			literals = append(literals, tok.Literal)
+		}
+	}
+
+	expected := []string{"a\x00\xff\n", "'"}
+	if len(literals) != len(expected) {
+		t.Fatalf("expected %d bytes literals, got %d", len(expected), len(literals))
+	}
+	for i, want := range expected {
+		if literals[i] != want {
+			t.Errorf("literal %d: expected %q, got %q", i, want, literals[i])
+		}
+	}
+
+	l = New(`b"unterminated`)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ILLEGAL {
+			return
+		}
+	}
+	t.Errorf("expected ILLEGAL token for unterminated bytes literal")
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected token.Token
+	}{
+		{`"a\tb\n"`, token.Token{Type: token.STRING, Literal: "a\tb\n"}},
+		{`"\x41\u00e9\U0001F600"`, token.Token{Type: token.STRING, Literal: "Aé😀"}},
+		{`"nul\0"`, token.Token{Type: token.STRING, Literal: "nul\x00"}},
+		{`'it\'s'`, token.Token{Type: token.STRING, Literal: "it's"}},
+		{`f"\u03bb{x}"`, token.Token{Type: token.FSTRING, Literal: "λ{x}"}},
+		{`r"C:\new\t"`, token.Token{Type: token.STRING, Literal: `C:\new\t`}},
+		{`r'\d+\''`, token.Token{Type: token.STRING, Literal: `\d+\'`}},
+		{"r\"\"\"a\\n\nb\"\"\"", token.Token{Type: token.DOCSTRING, Literal: "a\\n\nb"}},
+		{`"\u12"`, token.Token{Type: token.ILLEGAL, Literal: `truncated \u escape: expected 4 hex digits`}},
+		{`"\xZZ"`, token.Token{Type: token.ILLEGAL, Literal: `invalid \x escape: "ZZ" is not 2 hex digits`}},
+		{`"\UDEADBEEF"`, token.Token{Type: token.ILLEGAL, Literal: `invalid \U escape: U+DEADBEEF is not a valid code point`}},
+		{`r"open`, token.Token{Type: token.ILLEGAL, Literal: "unterminated raw string literal"}},
+	}
+
+	for _, tt := range tests {
+		tok := firstToken(tt.input)
+		if tok.Type != tt.expected.Type || tok.Literal != tt.expected.Literal {
+			t.Errorf("input %s: expected %s %q, got %s %q",
+				tt.input, tt.expected.Type, tt.expected.Literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	l := New("café = naïve_λ2 + 変数 - rate * r")
+	l.NextToken() // leading NEWLINE from indentation handling
+
+	expected := []token.Token{
+		{Type: token.IDENT, Literal: "café"},
+		{Type: token.ASSIGN, Literal: "="},
+		{Type: token.IDENT, Literal: "naïve_λ2"},
+		{Type: token.PLUS, Literal: "+"},
+		{Type: token.IDENT, Literal: "変数"},
+		{Type: token.MINUS, Literal: "-"},
+		{Type: token.IDENT, Literal: "rate"},
+		{Type: token.ASTERISK, Literal: "*"},
+		{Type: token.IDENT, Literal: "r"},
+	}
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want.Type || tok.Literal != want.Literal {
+			t.Fatalf("token %d: expected %s %q, got %s %q", i, want.Type, want.Literal, tok.Type, tok.Literal)
+		}
+	}
+
+	tok := firstToken("→")
+	if tok.Type != token.ILLEGAL || tok.Literal != "→" {
+		t.Errorf("expected ILLEGAL %q, got %s %q", "→", tok.Type, tok.Literal)
+	}
+}
+
+// firstToken returns the first token of input after the NEWLINE emitted for
+// the opening line.
+func firstToken(input string) token.Token {
+	l := New(input)
+	tok := l.NextToken()
+	for tok.Type == token.NEWLINE {
+		tok = l.NextToken()
+	}
+	return tok
+}
+
+func TestImplicitLineJoining(t *testing.T) {
+	input := `config = {
+    "ports": [
+        80,  # http
+            443,
+    ],
+}
+f(spell(x):
+    return x
+, 1)
+`
+	expected := []token.TokenType{
+		token.IDENT, token.ASSIGN, token.LBRACE,
+		token.STRING, token.COLON, token.LBRACK,
+		token.INT, token.COMMA,
+		token.INT, token.COMMA,
+		token.RBRACK, token.COMMA,
+		token.RBRACE, token.NEWLINE,
+		token.IDENT, token.LPAREN, token.SPELL, token.LPAREN, token.IDENT, token.RPAREN, token.COLON, token.NEWLINE,
+		token.INDENT, token.RETURN, token.IDENT, token.NEWLINE,
+		token.DEDENT, token.COMMA, token.INT, token.RPAREN, token.NEWLINE,
+	}
+
+	l := New(input)
+	var got []token.TokenType
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		// Lines that keep their indentation emit an extra NEWLINE
+		if tok.Type == token.NEWLINE && len(got) > 0 && got[len(got)-1] == token.NEWLINE {
+			continue
+		}
+		if tok.Type == token.NEWLINE && len(got) == 0 {
+			continue
+		}
+		got = append(got, tok.Type)
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(expected), len(got), got)
+	}
+	for i, want := range expected {
+		if got[i] != want {
+			t.Fatalf("token %d: expected %s, got %s (%v)", i, want, got[i], got)
+		}
+	}
+}
@@ -55,6 +55,36 @@ func extractStringEncoding(obj object.Object) (string, bool) {
 	}
 }
 
+// extractByteData accepts the ways binary data can be passed to a builtin:
+// Bytes, ByteArray, a string (its raw bytes) or an array of integers 0-255.
+func extractByteData(obj object.Object, name string) ([]byte, *object.Error) {
+	if data, ok := object.ByteData(obj); ok {
+		return data, nil
+	}
+	switch v := obj.(type) {
+	case *object.String:
+		return []byte(v.Value), nil
+	case *object.Array:
+		data := make([]byte, len(v.Elements))
+		for i, elem := range v.Elements {
+			intVal, ok := elem.(*object.Integer)
+			if !ok {
+				return nil, &object.Error{Message: name + ": array must contain integers"}
+			}
+			if intVal.Value < 0 || intVal.Value > 255 {
+				return nil, &object.Error{Message: name + ": byte values must be 0-255"}
+			}
+			data[i] = byte(intVal.Value)
+		}
+		return data, nil
+	case *object.Instance:
+		if value, exists := v.Env.Get("value"); exists {
+			return extractByteData(value, name)
+		}
+	}
+	return nil, &object.Error{Message: name + ": data must be bytes, a string or an array of bytes"}
+}
+
 // getEncoding retrieves the encoding by name (case-insensitive)
 func getEncoding(name string) (encoding.Encoding, bool) {
 	enc, ok := encodingMap[strings.ToLower(name)]
@@ -134,26 +164,9 @@ var EncodingBuiltins = map[string]*object.Builtin{
 				return &object.Error{Message: "encodingDecode requires 1-2 arguments: data, [encoding]"}
 			}
 
-			var data []byte
-
-			// Handle data argument - can be string (raw bytes) or array of integers
-			switch v := args[0].(type) {
-			case *object.String:
-				data = []byte(v.Value)
-			case *object.Array:
-				data = make([]byte, len(v.Elements))
-				for i, elem := range v.Elements {
-					if intVal, ok := elem.(*object.Integer); ok {
-						if intVal.Value < 0 || intVal.Value > 255 {
-							return &object.Error{Message: "encodingDecode: byte values must be 0-255"}
-						}
-						data[i] = byte(intVal.Value)
-					} else {
-						return &object.Error{Message: "encodingDecode: array must contain integers"}
-					}
-				}
-			default:
-				return &object.Error{Message: "encodingDecode: data must be a string or array of bytes"}
+			data, errObj := extractByteData(args[0], "encodingDecode")
+			if errObj != nil {
+				return errObj
 			}
 
 			encodingName := "utf-8"
@@ -199,12 +212,7 @@ var EncodingBuiltins = map[string]*object.Builtin{
 				return &object.Error{Message: "encodingEncode: " + err.Error()}
 			}
 
-			// Return as array of integers (bytes)
-			elements := make([]object.Object, len(encoded))
-			for i, b := range encoded {
-				elements[i] = &object.Integer{Value: int64(b)}
-			}
-			return &object.Array{Elements: elements}
+			return &object.Bytes{Value: encoded}
 		},
 	},
 
@@ -240,26 +248,9 @@ var EncodingBuiltins = map[string]*object.Builtin{
 				return &object.Error{Message: "encodingDetectBOM requires 1 argument: data"}
 			}
 
-			var data []byte
-
-			// Handle data argument - can be string (raw bytes) or array of integers
-			switch v := args[0].(type) {
-			case *object.String:
-				data = []byte(v.Value)
-			case *object.Array:
-				data = make([]byte, len(v.Elements))
-				for i, elem := range v.Elements {
-					if intVal, ok := elem.(*object.Integer); ok {
-						if intVal.Value < 0 || intVal.Value > 255 {
-							return &object.Error{Message: "encodingDetectBOM: byte values must be 0-255"}
-						}
-						data[i] = byte(intVal.Value)
-					} else {
-						return &object.Error{Message: "encodingDetectBOM: array must contain integers"}
-					}
-				}
-			default:
-				return &object.Error{Message: "encodingDetectBOM: data must be a string or array of bytes"}
+			data, errObj := extractByteData(args[0], "encodingDetectBOM")
+			if errObj != nil {
+				return errObj
 			}
 
 			detected := detectBOM(data)
@@ -273,25 +264,9 @@ var EncodingBuiltins = map[string]*object.Builtin{
 				return &object.Error{Message: "encodingStripBOM requires 1 argument: data"}
 			}
 
-			var data []byte
-
-			switch v := args[0].(type) {
-			case *object.String:
-				data = []byte(v.Value)
-			case *object.Array:
-				data = make([]byte, len(v.Elements))
-				for i, elem := range v.Elements {
-					if intVal, ok := elem.(*object.Integer); ok {
-						if intVal.Value < 0 || intVal.Value > 255 {
-							return &object.Error{Message: "encodingStripBOM: byte values must be 0-255"}
-						}
-						data[i] = byte(intVal.Value)
-					} else {
-						return &object.Error{Message: "encodingStripBOM: array must contain integers"}
-					}
-				}
-			default:
-				return &object.Error{Message: "encodingStripBOM: data must be a string or array of bytes"}
+			data, errObj := extractByteData(args[0], "encodingStripBOM")
+			if errObj != nil {
+				return errObj
 			}
 
 			// Strip known BOMs
@@ -308,12 +283,7 @@ var EncodingBuiltins = map[string]*object.Builtin{
 				data = data[2:]
 			}
 
-			// Return as array of integers (bytes)
-			elements := make([]object.Object, len(data))
-			for i, b := range data {
-				elements[i] = &object.Integer{Value: int64(b)}
-			}
-			return &object.Array{Elements: elements}
+			return &object.Bytes{Value: data}
 		},
 	},
 }
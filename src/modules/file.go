@@ -642,12 +642,7 @@ var FileBuiltins = map[string]*object.Builtin{
 			if err != nil {
 				return &object.Error{Message: "failed to read file '" + pathStr + "': " + err.Error()}
 			}
-			// Return as array of integers (bytes)
-			elements := make([]object.Object, len(data))
-			for i, b := range data {
-				elements[i] = &object.Integer{Value: int64(b)}
-			}
-			return &object.Array{Elements: elements}
+			return &object.Bytes{Value: data}
 		},
 	},
 
@@ -661,22 +656,9 @@ var FileBuiltins = map[string]*object.Builtin{
 				return &object.Error{Message: "fileWriteBytes: path must be a string"}
 			}
 
-			// Handle bytes as array of integers
-			arr, ok := args[1].(*object.Array)
-			if !ok {
-				return &object.Error{Message: "fileWriteBytes: bytes must be an array of integers"}
-			}
-
-			data := make([]byte, len(arr.Elements))
-			for i, elem := range arr.Elements {
-				intVal, ok := elem.(*object.Integer)
-				if !ok {
-					return &object.Error{Message: "fileWriteBytes: array must contain integers"}
-				}
-				if intVal.Value < 0 || intVal.Value > 255 {
-					return &object.Error{Message: "fileWriteBytes: byte values must be 0-255"}
-				}
-				data[i] = byte(intVal.Value)
+			data, errObj := extractByteData(args[1], "fileWriteBytes")
+			if errObj != nil {
+				return errObj
 			}
 
 			err := os.WriteFile(pathStr, data, 0644)
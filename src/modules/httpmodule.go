@@ -1,11 +1,14 @@
 package modules
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -57,34 +60,7 @@ var HttpModule = map[string]*object.Builtin{
 			}
 			defer resp.Body.Close()
 
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return &object.Error{Message: fmt.Sprintf("Failed to read response: %v", err)}
-			}
-
-			result := &object.Hash{
-				Pairs: make(map[object.HashKey]object.HashPair),
-			}
-
-			statusKey := &object.String{Value: "status"}
-			result.Pairs[statusKey.HashKey()] = object.HashPair{
-				Key:   statusKey,
-				Value: &object.Integer{Value: int64(resp.StatusCode)},
-			}
-
-			bodyKey := &object.String{Value: "body"}
-			result.Pairs[bodyKey.HashKey()] = object.HashPair{
-				Key:   bodyKey,
-				Value: &object.String{Value: string(body)},
-			}
-
-			headersKey := &object.String{Value: "headers"}
-			result.Pairs[headersKey.HashKey()] = object.HashPair{
-				Key:   headersKey,
-				Value: headersToHash(resp.Header),
-			}
-
-			return result
+			return buildResponse(resp)
 		},
 	},
 	"httpPost": {
@@ -98,7 +74,7 @@ var HttpModule = map[string]*object.Builtin{
 				return err
 			}
 
-			bodyStr, err := extractString(args[1], "body")
+			bodyStr, err := extractBody(args[1])
 			if err != nil {
 				return err
 			}
@@ -140,7 +116,7 @@ var HttpModule = map[string]*object.Builtin{
 				return err
 			}
 
-			bodyStr, err := extractString(args[1], "body")
+			bodyStr, err := extractBody(args[1])
 			if err != nil {
 				return err
 			}
@@ -238,21 +214,19 @@ var HttpModule = map[string]*object.Builtin{
 			}
 			defer resp.Body.Close()
 
-			result := &object.Hash{
-				Pairs: make(map[object.HashKey]object.HashPair),
-			}
+			result := object.NewHash()
 
 			statusKey := &object.String{Value: "status"}
-			result.Pairs[statusKey.HashKey()] = object.HashPair{
+			result.Set(statusKey.HashKey(), object.HashPair{
 				Key:   statusKey,
 				Value: &object.Integer{Value: int64(resp.StatusCode)},
-			}
+			})
 
 			headersKey := &object.String{Value: "headers"}
-			result.Pairs[headersKey.HashKey()] = object.HashPair{
+			result.Set(headersKey.HashKey(), object.HashPair{
 				Key:   headersKey,
 				Value: headersToHash(resp.Header),
-			}
+			})
 
 			return result
 		},
@@ -279,7 +253,11 @@ var HttpModule = map[string]*object.Builtin{
 			}
 
 			var bodyReader io.Reader
-			if body, err := getHashString(options, "body"); err == nil {
+			if bodyObj, err := getHashValue(options, "body"); err == nil {
+				body, errObj := extractBody(bodyObj)
+				if errObj != nil {
+					return errObj
+				}
 				bodyReader = strings.NewReader(body)
 			}
 
@@ -314,8 +292,12 @@ var HttpModule = map[string]*object.Builtin{
 	},
 	"httpParseJSON": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return &object.Error{Message: "httpParseJSON expects 1 argument: httpParseJSON(jsonString)"}
+			if len(args) != 1 && len(args) != 2 {
+				return &object.Error{Message: "httpParseJSON expects 1 argument: httpParseJSON(jsonString[, decimals])"}
+			}
+			decimals, errObj := decimalsOption("httpParseJSON", args, 1)
+			if errObj != nil {
+				return errObj
 			}
 
 			jsonStr, err := extractString(args[0], "JSON string")
@@ -323,12 +305,12 @@ var HttpModule = map[string]*object.Builtin{
 				return err
 			}
 
-			var result interface{}
-			if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
-				return &object.Error{Message: fmt.Sprintf("Failed to parse JSON: %v", err)}
+			result, parseErr := DecodeJSON([]byte(jsonStr), decimals)
+			if parseErr != nil {
+				return &object.Error{Message: fmt.Sprintf("Failed to parse JSON: %v", parseErr)}
 			}
 
-			return jsonToObject(result)
+			return result
 		},
 	},
 	"httpStringifyJSON": {
@@ -358,7 +340,7 @@ var HttpModule = map[string]*object.Builtin{
 			}
 
 			var queryParts []string
-			for _, pair := range params.Pairs {
+			for _, pair := range params.Ordered() {
 				key := pair.Key.Inspect()
 				value := pair.Value.Inspect()
 				queryParts = append(queryParts, fmt.Sprintf("%s=%s", key, value))
@@ -394,7 +376,7 @@ var HttpModule = map[string]*object.Builtin{
 				return &object.Error{Message: "http_response: status_code must be an integer"}
 			}
 
-			body, err := extractString(args[1], "body")
+			body, err := extractBody(args[1])
 			if err != nil {
 				return err
 			}
@@ -402,7 +384,7 @@ var HttpModule = map[string]*object.Builtin{
 			headers := make(map[string]string)
 			if len(args) == 3 {
 				if headerHash, ok := args[2].(*object.Hash); ok {
-					for _, pair := range headerHash.Pairs {
+					for _, pair := range headerHash.Ordered() {
 						key := pair.Key.Inspect()
 						value := pair.Value.Inspect()
 						headers[key] = value
@@ -459,13 +441,22 @@ func extractString(obj object.Object, name string) (string, object.Object) {
 	return "", &object.Error{Message: fmt.Sprintf("%s must be a string", name)}
 }
 
+// extractBody accepts a request or response body as a string or as
+// Bytes/ByteArray for binary payloads.
+func extractBody(obj object.Object) (string, object.Object) {
+	if data, ok := object.ByteData(obj); ok {
+		return string(data), nil
+	}
+	return extractString(obj, "body")
+}
+
 func setHeaders(req *http.Request, headersObj object.Object) object.Object {
 	headers, ok := headersObj.(*object.Hash)
 	if !ok {
 		return &object.Error{Message: "Headers must be a hash"}
 	}
 
-	for _, pair := range headers.Pairs {
+	for _, pair := range headers.Ordered() {
 		key := pair.Key.Inspect()
 		value := pair.Value.Inspect()
 		req.Header.Set(key, value)
@@ -475,17 +466,15 @@ func setHeaders(req *http.Request, headersObj object.Object) object.Object {
 }
 
 func headersToHash(headers http.Header) *object.Hash {
-	result := &object.Hash{
-		Pairs: make(map[object.HashKey]object.HashPair),
-	}
+	result := object.NewHash()
 
-	for key, values := range headers {
+	for _, key := range slices.Sorted(maps.Keys(headers)) {
 		keyObj := &object.String{Value: key}
-		valueObj := &object.String{Value: strings.Join(values, ", ")}
-		result.Pairs[keyObj.HashKey()] = object.HashPair{
+		valueObj := &object.String{Value: strings.Join(headers[key], ", ")}
+		result.Set(keyObj.HashKey(), object.HashPair{
 			Key:   keyObj,
 			Value: valueObj,
-		}
+		})
 	}
 
 	return result
@@ -497,34 +486,38 @@ func buildResponse(resp *http.Response) object.Object {
 		return &object.Error{Message: fmt.Sprintf("Failed to read response: %v", err)}
 	}
 
-	result := &object.Hash{
-		Pairs: make(map[object.HashKey]object.HashPair),
-	}
+	result := object.NewHash()
 
 	statusKey := &object.String{Value: "status"}
-	result.Pairs[statusKey.HashKey()] = object.HashPair{
+	result.Set(statusKey.HashKey(), object.HashPair{
 		Key:   statusKey,
 		Value: &object.Integer{Value: int64(resp.StatusCode)},
-	}
+	})
 
 	bodyKey := &object.String{Value: "body"}
-	result.Pairs[bodyKey.HashKey()] = object.HashPair{
+	result.Set(bodyKey.HashKey(), object.HashPair{
 		Key:   bodyKey,
 		Value: &object.String{Value: string(body)},
-	}
+	})
+
+	bodyBytesKey := &object.String{Value: "body_bytes"}
+	result.Set(bodyBytesKey.HashKey(), object.HashPair{
+		Key:   bodyBytesKey,
+		Value: &object.Bytes{Value: body},
+	})
 
 	headersKey := &object.String{Value: "headers"}
-	result.Pairs[headersKey.HashKey()] = object.HashPair{
+	result.Set(headersKey.HashKey(), object.HashPair{
 		Key:   headersKey,
 		Value: headersToHash(resp.Header),
-	}
+	})
 
 	return result
 }
 
 func getHashString(hash *object.Hash, key string) (string, error) {
 	keyObj := &object.String{Value: key}
-	if pair, ok := hash.Pairs[keyObj.HashKey()]; ok {
+	if pair, ok := hash.Get(keyObj); ok {
 		if str, ok := pair.Value.(*object.String); ok {
 			return str.Value, nil
 		}
@@ -535,7 +528,7 @@ func getHashString(hash *object.Hash, key string) (string, error) {
 
 func getHashInt(hash *object.Hash, key string) (int64, error) {
 	keyObj := &object.String{Value: key}
-	if pair, ok := hash.Pairs[keyObj.HashKey()]; ok {
+	if pair, ok := hash.Get(keyObj); ok {
 		if intObj, ok := pair.Value.(*object.Integer); ok {
 			return intObj.Value, nil
 		}
@@ -546,49 +539,40 @@ func getHashInt(hash *object.Hash, key string) (int64, error) {
 
 func getHashValue(hash *object.Hash, key string) (object.Object, error) {
 	keyObj := &object.String{Value: key}
-	if pair, ok := hash.Pairs[keyObj.HashKey()]; ok {
+	if pair, ok := hash.Get(keyObj); ok {
 		return pair.Value, nil
 	}
 	return nil, fmt.Errorf("key %s not found", key)
 }
 
-func jsonToObject(data interface{}) object.Object {
-	switch v := data.(type) {
-	case nil:
-		return &object.None{}
-	case bool:
-		if v {
-			return &object.Boolean{Value: true}
-		}
-		return &object.Boolean{Value: false}
-	case float64:
-		if v == float64(int64(v)) {
-			return &object.Integer{Value: int64(v)}
-		}
-		return &object.Float{Value: v}
-	case string:
-		return &object.String{Value: v}
-	case []interface{}:
-		elements := make([]object.Object, len(v))
-		for i, elem := range v {
-			elements[i] = jsonToObject(elem)
+// jsonObject marshals as a JSON object with its keys in insertion order,
+// which encoding/json would otherwise sort.
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			out.WriteByte(',')
 		}
-		return &object.Array{Elements: elements}
-	case map[string]interface{}:
-		result := &object.Hash{
-			Pairs: make(map[object.HashKey]object.HashPair),
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
 		}
-		for key, value := range v {
-			keyObj := &object.String{Value: key}
-			result.Pairs[keyObj.HashKey()] = object.HashPair{
-				Key:   keyObj,
-				Value: jsonToObject(value),
-			}
+		valueJSON, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
 		}
-		return result
-	default:
-		return &object.Error{Message: fmt.Sprintf("Unsupported JSON type: %T", v)}
+		out.Write(keyJSON)
+		out.WriteByte(':')
+		out.Write(valueJSON)
 	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
 }
 
 func objectToInterface(obj object.Object) interface{} {
@@ -599,6 +583,10 @@ func objectToInterface(obj object.Object) interface{} {
 		return o.Value
 	case *object.Integer:
 		return o.Value
+	case *object.BigInteger:
+		return json.Number(o.Value.String())
+	case *object.Decimal:
+		return json.Number(o.String())
 	case *object.Float:
 		return o.Value
 	case *object.String:
@@ -609,11 +597,20 @@ func objectToInterface(obj object.Object) interface{} {
 			result[i] = objectToInterface(elem)
 		}
 		return result
+	case *object.Set:
+		result := make([]interface{}, 0, o.Len())
+		for _, elem := range o.Items() {
+			result = append(result, objectToInterface(elem))
+		}
+		return result
 	case *object.Hash:
-		result := make(map[string]interface{})
-		for _, pair := range o.Pairs {
+		result := &jsonObject{values: make(map[string]interface{})}
+		for _, pair := range o.Ordered() {
 			key := pair.Key.Inspect()
-			result[key] = objectToInterface(pair.Value)
+			if _, exists := result.values[key]; !exists {
+				result.keys = append(result.keys, key)
+			}
+			result.values[key] = objectToInterface(pair.Value)
 		}
 		return result
 	default:
@@ -639,7 +636,7 @@ func parseHTTPRequest(requestData string) object.Object {
 	version := requestLine[2]
 
 	// Parse headers
-	headers := make(map[object.HashKey]object.HashPair)
+	headers := object.NewHash()
 	var bodyStart int
 	for i := 1; i < len(lines); i++ {
 		line := lines[i]
@@ -652,7 +649,7 @@ func parseHTTPRequest(requestData string) object.Object {
 		if len(parts) == 2 {
 			key := &object.String{Value: strings.TrimSpace(parts[0])}
 			value := &object.String{Value: strings.TrimSpace(parts[1])}
-			headers[key.HashKey()] = object.HashPair{Key: key, Value: value}
+			headers.Set(key.HashKey(), object.HashPair{Key: key, Value: value})
 		}
 	}
 
@@ -663,59 +660,59 @@ func parseHTTPRequest(requestData string) object.Object {
 	}
 
 	// Create request hash
-	result := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+	result := object.NewHash()
 
 	methodKey := &object.String{Value: "method"}
-	result.Pairs[methodKey.HashKey()] = object.HashPair{Key: methodKey, Value: &object.String{Value: method}}
+	result.Set(methodKey.HashKey(), object.HashPair{Key: methodKey, Value: &object.String{Value: method}})
 
 	pathKey := &object.String{Value: "path"}
-	result.Pairs[pathKey.HashKey()] = object.HashPair{Key: pathKey, Value: &object.String{Value: path}}
+	result.Set(pathKey.HashKey(), object.HashPair{Key: pathKey, Value: &object.String{Value: path}})
 
 	versionKey := &object.String{Value: "version"}
-	result.Pairs[versionKey.HashKey()] = object.HashPair{Key: versionKey, Value: &object.String{Value: version}}
+	result.Set(versionKey.HashKey(), object.HashPair{Key: versionKey, Value: &object.String{Value: version}})
 
 	headersKey := &object.String{Value: "headers"}
-	result.Pairs[headersKey.HashKey()] = object.HashPair{Key: headersKey, Value: &object.Hash{Pairs: headers}}
+	result.Set(headersKey.HashKey(), object.HashPair{Key: headersKey, Value: headers})
 
 	bodyKey := &object.String{Value: "body"}
-	result.Pairs[bodyKey.HashKey()] = object.HashPair{Key: bodyKey, Value: &object.String{Value: body}}
+	result.Set(bodyKey.HashKey(), object.HashPair{Key: bodyKey, Value: &object.String{Value: body}})
 
 	return result
 }
 
 func buildHTTPResponse(statusCode int, body string, headers map[string]string) object.Object {
 	// Return a Hash structure for HTTP responses (compatible with new WebSocket servers)
-	response := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+	response := object.NewHash()
 
 	// Add status code
 	statusKey := &object.String{Value: "status"}
-	response.Pairs[statusKey.HashKey()] = object.HashPair{
+	response.Set(statusKey.HashKey(), object.HashPair{
 		Key:   statusKey,
 		Value: &object.Integer{Value: int64(statusCode)},
-	}
+	})
 
 	// Add body
 	bodyKey := &object.String{Value: "body"}
-	response.Pairs[bodyKey.HashKey()] = object.HashPair{
+	response.Set(bodyKey.HashKey(), object.HashPair{
 		Key:   bodyKey,
 		Value: &object.String{Value: body},
-	}
+	})
 
 	// Add headers
 	if len(headers) > 0 {
-		headersHash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
-		for key, value := range headers {
+		headersHash := object.NewHash()
+		for _, key := range slices.Sorted(maps.Keys(headers)) {
 			keyObj := &object.String{Value: key}
-			headersHash.Pairs[keyObj.HashKey()] = object.HashPair{
+			headersHash.Set(keyObj.HashKey(), object.HashPair{
 				Key:   keyObj,
-				Value: &object.String{Value: value},
-			}
+				Value: &object.String{Value: headers[key]},
+			})
 		}
 		headersKey := &object.String{Value: "headers"}
-		response.Pairs[headersKey.HashKey()] = object.HashPair{
+		response.Set(headersKey.HashKey(), object.HashPair{
 			Key:   headersKey,
 			Value: headersHash,
-		}
+		})
 	}
 
 	return response
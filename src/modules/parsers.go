@@ -1,10 +1,13 @@
 package modules
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -37,6 +40,87 @@ func extractStringParser(obj object.Object) (string, bool) {
 	}
 }
 
+// DecodeJSON parses JSON text into Carrion objects. Object keys keep their
+// document order and integers of any size stay exact. Other numbers become
+// Floats, or Decimals when decimals is set so amounts keep every digit.
+func DecodeJSON(data []byte, decimals bool) (object.Object, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decodeJSONValue(decoder, decimals)
+}
+
+func decodeJSONValue(decoder *json.Decoder, decimals bool) (object.Object, error) {
+	tok, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '[' {
+			elements := []object.Object{}
+			for decoder.More() {
+				elem, err := decodeJSONValue(decoder, decimals)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, elem)
+			}
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return &object.Array{Elements: elements}, nil
+		}
+
+		result := object.NewHash()
+		for decoder.More() {
+			keyTok, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			keyObj := &object.String{Value: keyTok.(string)}
+			value, err := decodeJSONValue(decoder, decimals)
+			if err != nil {
+				return nil, err
+			}
+			result.Set(keyObj.HashKey(), object.HashPair{Key: keyObj, Value: value})
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return result, nil
+	case json.Number:
+		if decimals {
+			if value, ok := object.ParseInteger(v.String(), 10); ok {
+				return value, nil
+			}
+			if value, ok := object.ParseDecimal(v.String()); ok {
+				return value, nil
+			}
+		}
+		return object.FromJSONNumber(v), nil
+	case string:
+		return &object.String{Value: v}, nil
+	case bool:
+		return &object.Boolean{Value: v}, nil
+	default:
+		return &object.None{}, nil
+	}
+}
+
+// decimalsOption reads the optional "decimals" flag passed after the
+// required arguments of a JSON parsing builtin.
+func decimalsOption(name string, args []object.Object, required int) (bool, *object.Error) {
+	if len(args) == required {
+		return false, nil
+	}
+	flag, ok := args[required].(*object.Boolean)
+	if !ok {
+		return false, &object.Error{Message: name + ": decimals flag must be a boolean"}
+	}
+	return flag.Value, nil
+}
+
 // convertToCarrionObject converts Go values to Carrion objects
 func convertToCarrionObject(data interface{}) object.Object {
 	switch v := data.(type) {
@@ -48,6 +132,8 @@ func convertToCarrionObject(data interface{}) object.Object {
 		return &object.Integer{Value: int64(v)}
 	case int64:
 		return &object.Integer{Value: v}
+	case json.Number:
+		return object.FromJSONNumber(v)
 	case float64:
 		return &object.Float{Value: v}
 	case float32:
@@ -61,22 +147,18 @@ func convertToCarrionObject(data interface{}) object.Object {
 		}
 		return &object.Array{Elements: elements}
 	case map[string]interface{}:
-		result := &object.Hash{
-			Pairs: make(map[object.HashKey]object.HashPair),
-		}
-		for key, value := range v {
+		result := object.NewHash()
+		for _, key := range slices.Sorted(maps.Keys(v)) {
 			keyObj := &object.String{Value: key}
-			result.Pairs[keyObj.HashKey()] = object.HashPair{
+			result.Set(keyObj.HashKey(), object.HashPair{
 				Key:   keyObj,
-				Value: convertToCarrionObject(value),
-			}
+				Value: convertToCarrionObject(v[key]),
+			})
 		}
 		return result
 	case map[interface{}]interface{}:
 		// YAML sometimes returns this type
-		result := &object.Hash{
-			Pairs: make(map[object.HashKey]object.HashPair),
-		}
+		values := make(map[string]interface{}, len(v))
 		for key, value := range v {
 			var keyStr string
 			switch k := key.(type) {
@@ -86,13 +168,9 @@ func convertToCarrionObject(data interface{}) object.Object {
 				// Convert any other type to string
 				keyStr = fmt.Sprintf("%v", k)
 			}
-			keyObj := &object.String{Value: keyStr}
-			result.Pairs[keyObj.HashKey()] = object.HashPair{
-				Key:   keyObj,
-				Value: convertToCarrionObject(value),
-			}
+			values[keyStr] = value
 		}
-		return result
+		return convertToCarrionObject(values)
 	default:
 		// Try to handle as string
 		return &object.String{Value: ""}
@@ -103,8 +181,12 @@ var ParserBuiltins = map[string]*object.Builtin{
 	// ==================== JSON ====================
 	"jsonReadFile": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return &object.Error{Message: "jsonReadFile requires 1 argument: path"}
+			if len(args) != 1 && len(args) != 2 {
+				return &object.Error{Message: "jsonReadFile requires 1 argument: path (and an optional decimals flag)"}
+			}
+			decimals, errObj := decimalsOption("jsonReadFile", args, 1)
+			if errObj != nil {
+				return errObj
 			}
 
 			pathStr, ok := extractStringParser(args[0])
@@ -117,19 +199,23 @@ var ParserBuiltins = map[string]*object.Builtin{
 				return &object.Error{Message: "jsonReadFile: failed to read file '" + pathStr + "': " + err.Error()}
 			}
 
-			var result interface{}
-			if err := json.Unmarshal(data, &result); err != nil {
+			result, err := DecodeJSON(data, decimals)
+			if err != nil {
 				return &object.Error{Message: "jsonReadFile: failed to parse JSON: " + err.Error()}
 			}
 
-			return convertToCarrionObject(result)
+			return result
 		},
 	},
 
 	"jsonParse": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return &object.Error{Message: "jsonParse requires 1 argument: jsonString"}
+			if len(args) != 1 && len(args) != 2 {
+				return &object.Error{Message: "jsonParse requires 1 argument: jsonString (and an optional decimals flag)"}
+			}
+			decimals, errObj := decimalsOption("jsonParse", args, 1)
+			if errObj != nil {
+				return errObj
 			}
 
 			jsonStr, ok := extractStringParser(args[0])
@@ -137,12 +223,12 @@ var ParserBuiltins = map[string]*object.Builtin{
 				return &object.Error{Message: "jsonParse: argument must be a string"}
 			}
 
-			var result interface{}
-			if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
+			result, err := DecodeJSON([]byte(jsonStr), decimals)
+			if err != nil {
 				return &object.Error{Message: "jsonParse: failed to parse JSON: " + err.Error()}
 			}
 
-			return convertToCarrionObject(result)
+			return result
 		},
 	},
 
@@ -163,12 +249,12 @@ var ParserBuiltins = map[string]*object.Builtin{
 				return &object.Error{Message: "yamlReadFile: failed to read file '" + pathStr + "': " + err.Error()}
 			}
 
-			var result interface{}
-			if err := yaml.Unmarshal(data, &result); err != nil {
+			result, err := decodeYAML(data)
+			if err != nil {
 				return &object.Error{Message: "yamlReadFile: failed to parse YAML: " + err.Error()}
 			}
 
-			return convertToCarrionObject(result)
+			return result
 		},
 	},
 
@@ -183,12 +269,12 @@ var ParserBuiltins = map[string]*object.Builtin{
 				return &object.Error{Message: "yamlParse: argument must be a string"}
 			}
 
-			var result interface{}
-			if err := yaml.Unmarshal([]byte(yamlStr), &result); err != nil {
+			result, err := decodeYAML([]byte(yamlStr))
+			if err != nil {
 				return &object.Error{Message: "yamlParse: failed to parse YAML: " + err.Error()}
 			}
 
-			return convertToCarrionObject(result)
+			return result
 		},
 	},
 
@@ -413,30 +499,26 @@ func parseXMLToCarrion(data []byte) object.Object {
 
 // iniToCarrion converts an INI file to a Carrion Hash
 func iniToCarrion(cfg *ini.File) object.Object {
-	result := &object.Hash{
-		Pairs: make(map[object.HashKey]object.HashPair),
-	}
+	result := object.NewHash()
 
 	for _, section := range cfg.Sections() {
 		sectionName := section.Name()
-		sectionHash := &object.Hash{
-			Pairs: make(map[object.HashKey]object.HashPair),
-		}
+		sectionHash := object.NewHash()
 
 		for _, key := range section.Keys() {
 			keyObj := &object.String{Value: key.Name()}
 			valueObj := &object.String{Value: key.Value()}
-			sectionHash.Pairs[keyObj.HashKey()] = object.HashPair{
+			sectionHash.Set(keyObj.HashKey(), object.HashPair{
 				Key:   keyObj,
 				Value: valueObj,
-			}
+			})
 		}
 
 		sectionKeyObj := &object.String{Value: sectionName}
-		result.Pairs[sectionKeyObj.HashKey()] = object.HashPair{
+		result.Set(sectionKeyObj.HashKey(), object.HashPair{
 			Key:   sectionKeyObj,
 			Value: sectionHash,
-		}
+		})
 	}
 
 	return result
@@ -444,9 +526,7 @@ func iniToCarrion(cfg *ini.File) object.Object {
 
 // parseProperties parses Java-style properties files
 func parseProperties(data string) object.Object {
-	result := &object.Hash{
-		Pairs: make(map[object.HashKey]object.HashPair),
-	}
+	result := object.NewHash()
 
 	lines := strings.Split(data, "\n")
 	for _, line := range lines {
@@ -472,12 +552,111 @@ func parseProperties(data string) object.Object {
 
 			keyObj := &object.String{Value: key}
 			valueObj := &object.String{Value: value}
-			result.Pairs[keyObj.HashKey()] = object.HashPair{
+			result.Set(keyObj.HashKey(), object.HashPair{
 				Key:   keyObj,
 				Value: valueObj,
-			}
+			})
 		}
 	}
 
 	return result
 }
+
+// decodeYAML parses YAML text into Carrion objects, keeping mapping keys in
+// document order.
+func decodeYAML(data []byte) (object.Object, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return &object.None{}, nil
+	}
+	return yamlNodeToCarrion(&doc)
+}
+
+func yamlNodeToCarrion(node *yaml.Node) (object.Object, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return yamlNodeToCarrion(node.Content[0])
+	case yaml.AliasNode:
+		return yamlNodeToCarrion(node.Alias)
+	case yaml.SequenceNode:
+		elements := make([]object.Object, len(node.Content))
+		for i, child := range node.Content {
+			elem, err := yamlNodeToCarrion(child)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = elem
+		}
+		return &object.Array{Elements: elements}, nil
+	case yaml.MappingNode:
+		result := object.NewHash()
+		if err := addYAMLMapping(result, node); err != nil {
+			return nil, err
+		}
+		return result, nil
+	default:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return convertToCarrionObject(value), nil
+	}
+}
+
+// addYAMLMapping copies the pairs of a mapping node into result. Merge keys
+// (<<) pull in the referenced mappings without overriding explicit keys.
+func addYAMLMapping(result *object.Hash, node *yaml.Node) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if keyNode.Tag == "!!merge" {
+			if err := mergeYAMLMapping(result, valueNode); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var key interface{}
+		if err := keyNode.Decode(&key); err != nil {
+			return err
+		}
+		keyStr, ok := key.(string)
+		if !ok {
+			keyStr = fmt.Sprintf("%v", key)
+		}
+		value, err := yamlNodeToCarrion(valueNode)
+		if err != nil {
+			return err
+		}
+		keyObj := &object.String{Value: keyStr}
+		result.Set(keyObj.HashKey(), object.HashPair{Key: keyObj, Value: value})
+	}
+	return nil
+}
+
+func mergeYAMLMapping(result *object.Hash, node *yaml.Node) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.SequenceNode {
+		for _, child := range node.Content {
+			if err := mergeYAMLMapping(result, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	merged := object.NewHash()
+	if err := addYAMLMapping(merged, node); err != nil {
+		return err
+	}
+	for _, pair := range merged.Ordered() {
+		if _, exists := result.Get(pair.Key); !exists {
+			result.Put(pair.Key, pair.Value)
+		}
+	}
+	return nil
+}
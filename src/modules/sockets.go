@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -57,6 +59,15 @@ func extractSocketString(obj object.Object) (string, bool) {
 	}
 }
 
+// extractSocketData returns the payload for a send: a string or any
+// Bytes/ByteArray value.
+func extractSocketData(obj object.Object) (string, bool) {
+	if data, ok := object.ByteData(obj); ok {
+		return string(data), true
+	}
+	return extractSocketString(obj)
+}
+
 func extractSocketInt(obj object.Object) (int64, bool) {
 	switch v := obj.(type) {
 	case *object.Integer:
@@ -113,6 +124,11 @@ func allocatePort(address string) (string, string) {
 		return address, ""
 	}
 
+	// Port 0 asks the OS for any free port, so there is nothing to track
+	if portNum == 0 {
+		return address, ""
+	}
+
 	// Validate port range
 	if !isValidPort(portNum) {
 		return address, fmt.Sprintf("Port %d is out of valid range (1-65535)", portNum)
@@ -219,6 +235,30 @@ type WebSocket struct {
 	Running      bool                        // Track if server is running
 	EvalFunc     EvalCallback                // Function to evaluate Carrion functions
 	DocumentRoot string                      // Root directory for static files
+
+	// mu guards Routes, Running and DocumentRoot, which are read by request
+	// goroutines while the script may still be registering routes.
+	mu sync.RWMutex
+}
+
+// lookupRoute returns the handler registered for routeKey.
+func (ws *WebSocket) lookupRoute(routeKey string) (*object.Function, bool) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	handler, exists := ws.Routes[routeKey]
+	return handler, exists
+}
+
+func (ws *WebSocket) documentRoot() string {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return ws.DocumentRoot
+}
+
+func (ws *WebSocket) setRunning(running bool) {
+	ws.mu.Lock()
+	ws.Running = running
+	ws.mu.Unlock()
 }
 
 type UnixSocket struct {
@@ -451,9 +491,9 @@ var SocketsModule = map[string]*object.Builtin{
 				return &object.Error{Message: "socket_send: handleID must be an integer"}
 			}
 
-			data, ok := extractSocketString(args[1])
+			data, ok := extractSocketData(args[1])
 			if !ok {
-				return &object.Error{Message: "socket_send: data must be a string"}
+				return &object.Error{Message: "socket_send: data must be a string or bytes"}
 			}
 
 			socket, exists := getSocketHandle(handleID)
@@ -467,28 +507,13 @@ var SocketsModule = map[string]*object.Builtin{
 
 	"socket_receive": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) < 1 || len(args) > 2 {
-				return &object.Error{Message: "socket_receive requires 1-2 arguments: handleID, [bufferSize]"}
-			}
-
-			handleID, ok := extractSocketInt(args[0])
-			if !ok {
-				return &object.Error{Message: "socket_receive: handleID must be an integer"}
-			}
-
-			bufferSize := int64(1024)
-			if len(args) > 1 {
-				if size, ok := extractSocketInt(args[1]); ok {
-					bufferSize = size
-				}
-			}
-
-			socket, exists := getSocketHandle(handleID)
-			if !exists {
-				return &object.Error{Message: "socket_receive: invalid socket handle"}
-			}
+			return socketReceive("socket_receive", args, false)
+		},
+	},
 
-			return receiveData(socket, bufferSize)
+	"socket_receive_bytes": {
+		Fn: func(args ...object.Object) object.Object {
+			return socketReceive("socket_receive_bytes", args, true)
 		},
 	},
 
@@ -613,9 +638,9 @@ var SocketsModule = map[string]*object.Builtin{
 			if !ok {
 				return &object.Error{Message: "socket_send_to: handleID must be an integer"}
 			}
-			data, ok := extractSocketString(args[1])
+			data, ok := extractSocketData(args[1])
 			if !ok {
-				return &object.Error{Message: "socket_send_to: data must be a string"}
+				return &object.Error{Message: "socket_send_to: data must be a string or bytes"}
 			}
 			targetAddress, ok := extractSocketString(args[2])
 			if !ok {
@@ -687,7 +712,9 @@ var SocketsModule = map[string]*object.Builtin{
 			}
 
 			routeKey := strings.ToUpper(method) + ":" + path
+			webSocket.mu.Lock()
 			webSocket.Routes[routeKey] = handler
+			webSocket.mu.Unlock()
 
 			return &object.None{}
 		},
@@ -748,7 +775,9 @@ var SocketsModule = map[string]*object.Builtin{
 			}
 
 			// Set the document root
+			webSocket.mu.Lock()
 			webSocket.DocumentRoot = documentRoot
+			webSocket.mu.Unlock()
 
 			return &object.None{}
 		},
@@ -955,6 +984,17 @@ func startWebServer(address string, timeout time.Duration) object.Object {
 	// Allocate port with automatic incrementing
 	allocatedAddress, message := allocatePort(address)
 
+	// Bind before serving so the handle reports the real address, which
+	// differs from the requested one when port 0 was asked for
+	listener, err := net.Listen("tcp", allocatedAddress)
+	if err != nil {
+		releasePort(allocatedAddress)
+		return &object.Error{Message: fmt.Sprintf("failed to start web server: %v", err)}
+	}
+	if _, port, _ := net.SplitHostPort(allocatedAddress); port == "0" {
+		allocatedAddress = listener.Addr().String()
+	}
+
 	mux := http.NewServeMux()
 	server := &http.Server{
 		Addr:         allocatedAddress,
@@ -980,17 +1020,17 @@ func startWebServer(address string, timeout time.Duration) object.Object {
 		routeKey := r.Method + ":" + r.URL.Path
 
 		// Look up the handler function
-		handler, exists := socket.Routes[routeKey]
+		handler, exists := socket.lookupRoute(routeKey)
 		if !exists {
 			// Try without trailing slash
 			if r.URL.Path != "/" && r.URL.Path[len(r.URL.Path)-1] == '/' {
 				routeKey = r.Method + ":" + r.URL.Path[:len(r.URL.Path)-1]
-				handler, exists = socket.Routes[routeKey]
+				handler, exists = socket.lookupRoute(routeKey)
 			}
 
 			if !exists {
 				// Try to serve static file if document root is set
-				if socket.DocumentRoot != "" && tryServeStaticFile(w, r, socket.DocumentRoot) {
+				if root := socket.documentRoot(); root != "" && tryServeStaticFile(w, r, root) {
 					return
 				}
 
@@ -1002,71 +1042,65 @@ func startWebServer(address string, timeout time.Duration) object.Object {
 		}
 
 		// Build request object to pass to Carrion handler
-		requestHash := &object.Hash{
-			Pairs: make(map[object.HashKey]object.HashPair),
-		}
+		requestHash := object.NewHash()
 
 		// Add method
 		methodKey := &object.String{Value: "method"}
-		requestHash.Pairs[methodKey.HashKey()] = object.HashPair{
+		requestHash.Set(methodKey.HashKey(), object.HashPair{
 			Key:   methodKey,
 			Value: &object.String{Value: r.Method},
-		}
+		})
 
 		// Add path
 		pathKey := &object.String{Value: "path"}
-		requestHash.Pairs[pathKey.HashKey()] = object.HashPair{
+		requestHash.Set(pathKey.HashKey(), object.HashPair{
 			Key:   pathKey,
 			Value: &object.String{Value: r.URL.Path},
-		}
+		})
 
 		// Add headers
-		headersHash := &object.Hash{
-			Pairs: make(map[object.HashKey]object.HashPair),
-		}
-		for headerName, headerValues := range r.Header {
+		headersHash := object.NewHash()
+		for _, headerName := range slices.Sorted(maps.Keys(r.Header)) {
 			headerKey := &object.String{Value: headerName}
 			// Join multiple header values with comma (HTTP spec)
-			headerValue := &object.String{Value: strings.Join(headerValues, ", ")}
-			headersHash.Pairs[headerKey.HashKey()] = object.HashPair{
+			headerValue := &object.String{Value: strings.Join(r.Header[headerName], ", ")}
+			headersHash.Set(headerKey.HashKey(), object.HashPair{
 				Key:   headerKey,
 				Value: headerValue,
-			}
+			})
 		}
 		headersKey := &object.String{Value: "headers"}
-		requestHash.Pairs[headersKey.HashKey()] = object.HashPair{
+		requestHash.Set(headersKey.HashKey(), object.HashPair{
 			Key:   headersKey,
 			Value: headersHash,
-		}
+		})
 
 		// Add query parameters
-		queryHash := &object.Hash{
-			Pairs: make(map[object.HashKey]object.HashPair),
-		}
+		queryHash := object.NewHash()
 		queryParams := r.URL.Query()
-		for paramName, paramValues := range queryParams {
+		for _, paramName := range slices.Sorted(maps.Keys(queryParams)) {
 			paramKey := &object.String{Value: paramName}
 			// Join multiple parameter values with comma
-			paramValue := &object.String{Value: strings.Join(paramValues, ", ")}
-			queryHash.Pairs[paramKey.HashKey()] = object.HashPair{
+			paramValue := &object.String{Value: strings.Join(queryParams[paramName], ", ")}
+			queryHash.Set(paramKey.HashKey(), object.HashPair{
 				Key:   paramKey,
 				Value: paramValue,
-			}
+			})
 		}
 		queryKey := &object.String{Value: "query"}
-		requestHash.Pairs[queryKey.HashKey()] = object.HashPair{
+		requestHash.Set(queryKey.HashKey(), object.HashPair{
 			Key:   queryKey,
 			Value: queryHash,
-		}
+		})
 
 		// Add request body
 		bodyBytes, err := io.ReadAll(r.Body)
 		if err == nil && len(bodyBytes) > 0 {
 			bodyKey := &object.String{Value: "body"}
-			requestHash.Pairs[bodyKey.HashKey()] = object.HashPair{
+			requestHash.Set(bodyKey.HashKey(), object.HashPair{
 				Key:   bodyKey,
 				Value: &object.String{Value: string(bodyBytes)},
-			}
+			})
 		}
 		r.Body.Close()
 
@@ -1091,9 +1125,9 @@ func startWebServer(address string, timeout time.Duration) object.Object {
 		if responseHash, ok := result.(*object.Hash); ok {
 			// Extract and set headers first (before WriteHeader)
 			headersKey := &object.String{Value: "headers"}
-			if headersPair, exists := responseHash.Pairs[headersKey.HashKey()]; exists {
+			if headersPair, exists := responseHash.Get(headersKey); exists {
 				if headersHash, ok := headersPair.Value.(*object.Hash); ok {
-					for _, pair := range headersHash.Pairs {
+					for _, pair := range headersHash.Ordered() {
 						if keyStr, ok := pair.Key.(*object.String); ok {
 							if valStr, ok := pair.Value.(*object.String); ok {
 								w.Header().Set(keyStr.Value, valStr.Value)
@@ -1106,7 +1140,7 @@ func startWebServer(address string, timeout time.Duration) object.Object {
 			// Extract and set status code
 			statusCode := 200 // default
 			statusKey := &object.String{Value: "status"}
-			if statusPair, exists := responseHash.Pairs[statusKey.HashKey()]; exists {
+			if statusPair, exists := responseHash.Get(statusKey); exists {
 				if statusInt, ok := statusPair.Value.(*object.Integer); ok {
 					statusCode = int(statusInt.Value)
 				}
@@ -1115,7 +1149,7 @@ func startWebServer(address string, timeout time.Duration) object.Object {
 
 			// Extract and write body
 			bodyKey := &object.String{Value: "body"}
-			if bodyPair, exists := responseHash.Pairs[bodyKey.HashKey()]; exists {
+			if bodyPair, exists := responseHash.Get(bodyKey); exists {
 				if bodyStr, ok := bodyPair.Value.(*object.String); ok {
 					w.Write([]byte(bodyStr.Value))
 				}
@@ -1131,9 +1165,9 @@ func startWebServer(address string, timeout time.Duration) object.Object {
 	startupChan := make(chan error, 1)
 
 	go func() {
-		socket.Running = true
-		err := server.ListenAndServe()
-		socket.Running = false
+		socket.setRunning(true)
+		err := server.Serve(listener)
+		socket.setRunning(false)
 		if err != nil && err != http.ErrServerClosed {
 			startupChan <- err
 		}
@@ -1239,7 +1273,42 @@ func sendData(socket interface{}, data string) object.Object {
 	}
 }
 
-func receiveData(socket interface{}, bufferSize int64) object.Object {
+// socketReceive implements socket_receive and socket_receive_bytes, which
+// differ only in whether the data comes back as a String or as Bytes.
+func socketReceive(name string, args []object.Object, binary bool) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return &object.Error{Message: name + " requires 1-2 arguments: handleID, [bufferSize]"}
+	}
+
+	handleID, ok := extractSocketInt(args[0])
+	if !ok {
+		return &object.Error{Message: name + ": handleID must be an integer"}
+	}
+
+	bufferSize := int64(1024)
+	if len(args) > 1 {
+		if size, ok := extractSocketInt(args[1]); ok {
+			bufferSize = size
+		}
+	}
+
+	socket, exists := getSocketHandle(handleID)
+	if !exists {
+		return &object.Error{Message: name + ": invalid socket handle"}
+	}
+
+	return receiveData(socket, bufferSize, binary)
+}
+
+// receivedValue wraps data read from a socket as Bytes or as a String.
+func receivedValue(data []byte, binary bool) object.Object {
+	if binary {
+		return &object.Bytes{Value: data}
+	}
+	return &object.String{Value: string(data)}
+}
+
+func receiveData(socket interface{}, bufferSize int64, binary bool) object.Object {
 	switch s := socket.(type) {
 	case *TCPSocket:
 		if s.Conn == nil {
@@ -1250,7 +1319,7 @@ func receiveData(socket interface{}, bufferSize int64) object.Object {
 		if err != nil && err != io.EOF {
 			return &object.Error{Message: fmt.Sprintf("failed to receive TCP data: %v", err)}
 		}
-		return &object.String{Value: string(buffer[:n])}
+		return receivedValue(buffer[:n], binary)
 
 	case *UDPSocket:
 		if s.Conn == nil {
@@ -1261,7 +1330,7 @@ func receiveData(socket interface{}, bufferSize int64) object.Object {
 		if err != nil && err != io.EOF {
 			return &object.Error{Message: fmt.Sprintf("failed to receive UDP data: %v", err)}
 		}
-		return &object.String{Value: string(buffer[:n])}
+		return receivedValue(buffer[:n], binary)
 
 	case *UnixSocket:
 		if s.Conn == nil {
@@ -1272,7 +1341,7 @@ func receiveData(socket interface{}, bufferSize int64) object.Object {
 		if err != nil && err != io.EOF {
 			return &object.Error{Message: fmt.Sprintf("failed to receive Unix data: %v", err)}
 		}
-		return &object.String{Value: string(buffer[:n])}
+		return receivedValue(buffer[:n], binary)
 
 	default:
 		return &object.Error{Message: "unsupported socket type for receive operation"}
@@ -1318,21 +1387,19 @@ func receiveDataFrom(socket interface{}, bufferSize int64) object.Object {
 		}
 
 		// Return a hash with data and sender address
-		result := &object.Hash{
-			Pairs: make(map[object.HashKey]object.HashPair),
-		}
+		result := object.NewHash()
 
 		dataKey := &object.String{Value: "data"}
-		result.Pairs[dataKey.HashKey()] = object.HashPair{
+		result.Set(dataKey.HashKey(), object.HashPair{
 			Key:   dataKey,
 			Value: &object.String{Value: string(buffer[:n])},
-		}
+		})
 
 		senderKey := &object.String{Value: "sender"}
-		result.Pairs[senderKey.HashKey()] = object.HashPair{
+		result.Set(senderKey.HashKey(), object.HashPair{
 			Key:   senderKey,
 			Value: &object.String{Value: addr.String()},
-		}
+		})
 
 		return result
 
@@ -1534,9 +1601,7 @@ func setSocketTimeout(socket interface{}, timeout time.Duration) object.Object {
 }
 
 func getSocketInfo(socket interface{}) object.Object {
-	result := &object.Hash{
-		Pairs: make(map[object.HashKey]object.HashPair),
-	}
+	result := object.NewHash()
 
 	var socketType, address string
 	var timeout time.Duration
@@ -1580,22 +1645,22 @@ func getSocketInfo(socket interface{}) object.Object {
 	}
 
 	typeKey := &object.String{Value: "type"}
-	result.Pairs[typeKey.HashKey()] = object.HashPair{
+	result.Set(typeKey.HashKey(), object.HashPair{
 		Key:   typeKey,
 		Value: &object.String{Value: socketType},
-	}
+	})
 
 	addressKey := &object.String{Value: "address"}
-	result.Pairs[addressKey.HashKey()] = object.HashPair{
+	result.Set(addressKey.HashKey(), object.HashPair{
 		Key:   addressKey,
 		Value: &object.String{Value: address},
-	}
+	})
 
 	timeoutKey := &object.String{Value: "timeout"}
-	result.Pairs[timeoutKey.HashKey()] = object.HashPair{
+	result.Set(timeoutKey.HashKey(), object.HashPair{
 		Key:   timeoutKey,
 		Value: &object.Integer{Value: int64(timeout.Seconds())},
-	}
+	})
 
 	return result
 }
@@ -0,0 +1,515 @@
+package modules
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/javanhut/TheCarrionLanguage/src/object"
+)
+
+// Global sync primitive registry. The Mutex, RWLock, WaitGroup, Semaphore
+// and Atomic grimoires in munin/sync.crl hold a handle into it, so the same
+// primitive can be shared by every goroutine that sees the instance. Their
+// close() spells remove the handle with sync_free, and sync_bind removes it
+// once the instance is garbage collected if close() was never called.
+var (
+	syncHandles           = make(map[int64]interface{})
+	nextSyncHandle  int64 = 1
+	syncHandleMutex sync.RWMutex
+)
+
+// Go's sync types abort the whole process on misuse such as unlocking an
+// unlocked mutex, so the wrappers below track enough state to report that
+// as a Carrion error instead.
+
+type syncMutex struct {
+	mu     sync.Mutex
+	locked atomic.Bool
+}
+
+func (m *syncMutex) lock() {
+	m.mu.Lock()
+	m.locked.Store(true)
+}
+
+func (m *syncMutex) tryLock() bool {
+	if !m.mu.TryLock() {
+		return false
+	}
+	m.locked.Store(true)
+	return true
+}
+
+func (m *syncMutex) unlock() bool {
+	if !m.locked.CompareAndSwap(true, false) {
+		return false
+	}
+	m.mu.Unlock()
+	return true
+}
+
+type syncRWLock struct {
+	mu      sync.RWMutex
+	writer  atomic.Bool
+	readers atomic.Int64
+}
+
+func (l *syncRWLock) unlock() bool {
+	if !l.writer.CompareAndSwap(true, false) {
+		return false
+	}
+	l.mu.Unlock()
+	return true
+}
+
+func (l *syncRWLock) readUnlock() bool {
+	for {
+		n := l.readers.Load()
+		if n <= 0 {
+			return false
+		}
+		if l.readers.CompareAndSwap(n, n-1) {
+			l.mu.RUnlock()
+			return true
+		}
+	}
+}
+
+// syncWaitGroup is built on sync.Cond rather than sync.WaitGroup, whose
+// Add panics when it races with Wait on a group that is being reused.
+type syncWaitGroup struct {
+	mu      sync.Mutex
+	zero    *sync.Cond
+	counter int64
+}
+
+func newSyncWaitGroup() *syncWaitGroup {
+	w := &syncWaitGroup{}
+	w.zero = sync.NewCond(&w.mu)
+	return w
+}
+
+func (w *syncWaitGroup) add(delta int64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.counter+delta < 0 {
+		return false
+	}
+	w.counter += delta
+	if w.counter == 0 {
+		w.zero.Broadcast()
+	}
+	return true
+}
+
+func (w *syncWaitGroup) wait() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.counter > 0 {
+		w.zero.Wait()
+	}
+}
+
+// syncSemaphore holds one token in slots per acquired permit.
+type syncSemaphore struct {
+	slots chan struct{}
+}
+
+func (s *syncSemaphore) release() bool {
+	select {
+	case <-s.slots:
+		return true
+	default:
+		return false
+	}
+}
+
+// Sync handle management
+func getSyncHandle(handleID int64) (interface{}, bool) {
+	syncHandleMutex.RLock()
+	defer syncHandleMutex.RUnlock()
+	primitive, exists := syncHandles[handleID]
+	return primitive, exists
+}
+
+func storeSyncHandle(primitive interface{}) int64 {
+	syncHandleMutex.Lock()
+	defer syncHandleMutex.Unlock()
+	handleID := nextSyncHandle
+	nextSyncHandle++
+	syncHandles[handleID] = primitive
+	return handleID
+}
+
+func removeSyncHandle(handleID int64) bool {
+	syncHandleMutex.Lock()
+	defer syncHandleMutex.Unlock()
+	if _, exists := syncHandles[handleID]; !exists {
+		return false
+	}
+	delete(syncHandles, handleID)
+	return true
+}
+
+// lookupSync resolves args[0] to a primitive of type T, returning an error
+// object naming the builtin when the handle is missing or of another kind.
+func lookupSync[T any](name string, args []object.Object, want int) (T, object.Object) {
+	var zero T
+	if len(args) != want {
+		return zero, &object.Error{Message: fmt.Sprintf("%s requires %d argument(s), got %d", name, want, len(args))}
+	}
+	handleID, ok := extractSocketInt(args[0])
+	if !ok {
+		return zero, &object.Error{Message: name + ": handle must be an integer"}
+	}
+	primitive, exists := getSyncHandle(handleID)
+	if !exists {
+		return zero, &object.Error{Message: name + ": invalid handle"}
+	}
+	typed, ok := primitive.(T)
+	if !ok {
+		return zero, &object.Error{Message: name + ": handle has the wrong type"}
+	}
+	return typed, nil
+}
+
+func syncBool(value bool) object.Object {
+	return &object.Boolean{Value: value}
+}
+
+var SyncModule = map[string]*object.Builtin{
+	"sync_free": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return &object.Error{Message: "sync_free requires 1 argument: handle"}
+			}
+			handleID, ok := extractSocketInt(args[0])
+			if !ok {
+				return &object.Error{Message: "sync_free: handle must be an integer"}
+			}
+			if !removeSyncHandle(handleID) {
+				return &object.Error{Message: "sync_free: invalid handle"}
+			}
+			return &object.None{}
+		},
+	},
+
+	"sync_bind": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return &object.Error{Message: "sync_bind requires 2 arguments: owner, handle"}
+			}
+			owner, ok := args[0].(*object.Instance)
+			if !ok {
+				return &object.Error{Message: "sync_bind: owner must be an instance"}
+			}
+			handleID, ok := extractSocketInt(args[1])
+			if !ok {
+				return &object.Error{Message: "sync_bind: handle must be an integer"}
+			}
+			if _, exists := getSyncHandle(handleID); !exists {
+				return &object.Error{Message: "sync_bind: invalid handle"}
+			}
+			// Handles are never reused, so freeing one that close() already
+			// removed is harmless
+			runtime.AddCleanup(owner, func(id int64) { removeSyncHandle(id) }, handleID)
+			return &object.None{}
+		},
+	},
+
+	"mutex_new": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return &object.Error{Message: "mutex_new takes no arguments"}
+			}
+			return &object.Integer{Value: storeSyncHandle(&syncMutex{})}
+		},
+	},
+
+	"mutex_lock": {
+		Fn: func(args ...object.Object) object.Object {
+			m, errObj := lookupSync[*syncMutex]("mutex_lock", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			m.lock()
+			return &object.None{}
+		},
+	},
+
+	"mutex_try_lock": {
+		Fn: func(args ...object.Object) object.Object {
+			m, errObj := lookupSync[*syncMutex]("mutex_try_lock", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			return syncBool(m.tryLock())
+		},
+	},
+
+	"mutex_unlock": {
+		Fn: func(args ...object.Object) object.Object {
+			m, errObj := lookupSync[*syncMutex]("mutex_unlock", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			if !m.unlock() {
+				return &object.Error{Message: "mutex_unlock: mutex is not locked"}
+			}
+			return &object.None{}
+		},
+	},
+
+	"mutex_is_locked": {
+		Fn: func(args ...object.Object) object.Object {
+			m, errObj := lookupSync[*syncMutex]("mutex_is_locked", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			return syncBool(m.locked.Load())
+		},
+	},
+
+	"rwlock_new": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return &object.Error{Message: "rwlock_new takes no arguments"}
+			}
+			return &object.Integer{Value: storeSyncHandle(&syncRWLock{})}
+		},
+	},
+
+	"rwlock_lock": {
+		Fn: func(args ...object.Object) object.Object {
+			l, errObj := lookupSync[*syncRWLock]("rwlock_lock", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			l.mu.Lock()
+			l.writer.Store(true)
+			return &object.None{}
+		},
+	},
+
+	"rwlock_unlock": {
+		Fn: func(args ...object.Object) object.Object {
+			l, errObj := lookupSync[*syncRWLock]("rwlock_unlock", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			if !l.unlock() {
+				return &object.Error{Message: "rwlock_unlock: lock is not held for writing"}
+			}
+			return &object.None{}
+		},
+	},
+
+	"rwlock_read_lock": {
+		Fn: func(args ...object.Object) object.Object {
+			l, errObj := lookupSync[*syncRWLock]("rwlock_read_lock", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			l.mu.RLock()
+			l.readers.Add(1)
+			return &object.None{}
+		},
+	},
+
+	"rwlock_read_unlock": {
+		Fn: func(args ...object.Object) object.Object {
+			l, errObj := lookupSync[*syncRWLock]("rwlock_read_unlock", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			if !l.readUnlock() {
+				return &object.Error{Message: "rwlock_read_unlock: lock is not held for reading"}
+			}
+			return &object.None{}
+		},
+	},
+
+	"waitgroup_new": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return &object.Error{Message: "waitgroup_new takes no arguments"}
+			}
+			return &object.Integer{Value: storeSyncHandle(newSyncWaitGroup())}
+		},
+	},
+
+	"waitgroup_add": {
+		Fn: func(args ...object.Object) object.Object {
+			w, errObj := lookupSync[*syncWaitGroup]("waitgroup_add", args, 2)
+			if errObj != nil {
+				return errObj
+			}
+			delta, ok := extractSocketInt(args[1])
+			if !ok {
+				return &object.Error{Message: "waitgroup_add: delta must be an integer"}
+			}
+			if !w.add(delta) {
+				return &object.Error{Message: "waitgroup_add: negative WaitGroup counter"}
+			}
+			return &object.None{}
+		},
+	},
+
+	"waitgroup_wait": {
+		Fn: func(args ...object.Object) object.Object {
+			w, errObj := lookupSync[*syncWaitGroup]("waitgroup_wait", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			w.wait()
+			return &object.None{}
+		},
+	},
+
+	"waitgroup_count": {
+		Fn: func(args ...object.Object) object.Object {
+			w, errObj := lookupSync[*syncWaitGroup]("waitgroup_count", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			return &object.Integer{Value: w.counter}
+		},
+	},
+
+	"semaphore_new": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return &object.Error{Message: "semaphore_new requires 1 argument: permits"}
+			}
+			permits, ok := extractSocketInt(args[0])
+			if !ok || permits < 1 {
+				return &object.Error{Message: "semaphore_new: permits must be a positive integer"}
+			}
+			return &object.Integer{Value: storeSyncHandle(&syncSemaphore{slots: make(chan struct{}, permits)})}
+		},
+	},
+
+	"semaphore_acquire": {
+		Fn: func(args ...object.Object) object.Object {
+			s, errObj := lookupSync[*syncSemaphore]("semaphore_acquire", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			s.slots <- struct{}{}
+			return &object.None{}
+		},
+	},
+
+	"semaphore_try_acquire": {
+		Fn: func(args ...object.Object) object.Object {
+			s, errObj := lookupSync[*syncSemaphore]("semaphore_try_acquire", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			select {
+			case s.slots <- struct{}{}:
+				return syncBool(true)
+			default:
+				return syncBool(false)
+			}
+		},
+	},
+
+	"semaphore_release": {
+		Fn: func(args ...object.Object) object.Object {
+			s, errObj := lookupSync[*syncSemaphore]("semaphore_release", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			if !s.release() {
+				return &object.Error{Message: "semaphore_release: no permits are held"}
+			}
+			return &object.None{}
+		},
+	},
+
+	"semaphore_available": {
+		Fn: func(args ...object.Object) object.Object {
+			s, errObj := lookupSync[*syncSemaphore]("semaphore_available", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			return &object.Integer{Value: int64(cap(s.slots) - len(s.slots))}
+		},
+	},
+
+	"atomic_new": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return &object.Error{Message: "atomic_new requires 1 argument: initial value"}
+			}
+			initial, ok := extractSocketInt(args[0])
+			if !ok {
+				return &object.Error{Message: "atomic_new: initial value must be an integer"}
+			}
+			counter := &atomic.Int64{}
+			counter.Store(initial)
+			return &object.Integer{Value: storeSyncHandle(counter)}
+		},
+	},
+
+	"atomic_get": {
+		Fn: func(args ...object.Object) object.Object {
+			counter, errObj := lookupSync[*atomic.Int64]("atomic_get", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			return &object.Integer{Value: counter.Load()}
+		},
+	},
+
+	"atomic_set": {
+		Fn: func(args ...object.Object) object.Object {
+			counter, errObj := lookupSync[*atomic.Int64]("atomic_set", args, 2)
+			if errObj != nil {
+				return errObj
+			}
+			value, ok := extractSocketInt(args[1])
+			if !ok {
+				return &object.Error{Message: "atomic_set: value must be an integer"}
+			}
+			return &object.Integer{Value: counter.Swap(value)}
+		},
+	},
+
+	"atomic_add": {
+		Fn: func(args ...object.Object) object.Object {
+			counter, errObj := lookupSync[*atomic.Int64]("atomic_add", args, 2)
+			if errObj != nil {
+				return errObj
+			}
+			delta, ok := extractSocketInt(args[1])
+			if !ok {
+				return &object.Error{Message: "atomic_add: delta must be an integer"}
+			}
+			return &object.Integer{Value: counter.Add(delta)}
+		},
+	},
+
+	"atomic_compare_and_swap": {
+		Fn: func(args ...object.Object) object.Object {
+			counter, errObj := lookupSync[*atomic.Int64]("atomic_compare_and_swap", args, 3)
+			if errObj != nil {
+				return errObj
+			}
+			old, ok := extractSocketInt(args[1])
+			if !ok {
+				return &object.Error{Message: "atomic_compare_and_swap: old must be an integer"}
+			}
+			value, ok := extractSocketInt(args[2])
+			if !ok {
+				return &object.Error{Message: "atomic_compare_and_swap: new must be an integer"}
+			}
+			return syncBool(counter.CompareAndSwap(old, value))
+		},
+	},
+}
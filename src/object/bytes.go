@@ -0,0 +1,66 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+)
+
+// Bytes is an immutable sequence of bytes, written b"..." in source.
+type Bytes struct {
+	Value []byte
+}
+
+func (b *Bytes) Type() ObjectType { return BYTES_OBJ }
+func (b *Bytes) Inspect() string  { return BytesRepr(b.Value) }
+
+func (b *Bytes) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write(b.Value)
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+// ByteArray is the mutable counterpart of Bytes. It is not hashable.
+type ByteArray struct {
+	Value []byte
+}
+
+func (b *ByteArray) Type() ObjectType { return BYTE_ARRAY_OBJ }
+func (b *ByteArray) Inspect() string  { return "bytearray(" + BytesRepr(b.Value) + ")" }
+
+// ByteData returns the contents of a Bytes or ByteArray.
+func ByteData(obj Object) ([]byte, bool) {
+	switch v := obj.(type) {
+	case *Bytes:
+		return v.Value, true
+	case *ByteArray:
+		return v.Value, true
+	}
+	return nil, false
+}
+
+// BytesRepr renders data as a b"..." literal, escaping anything that is
+// not printable ASCII.
+func BytesRepr(data []byte) string {
+	var out bytes.Buffer
+	out.WriteString(`b"`)
+	for _, c := range data {
+		switch {
+		case c == '"' || c == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case c == '\n':
+			out.WriteString(`\n`)
+		case c == '\r':
+			out.WriteString(`\r`)
+		case c == '\t':
+			out.WriteString(`\t`)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&out, `\x%02x`, c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
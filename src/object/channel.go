@@ -0,0 +1,151 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrChannelClosed is returned when sending on or closing a closed channel.
+var ErrChannelClosed = errors.New("channel is closed")
+
+// ErrInterrupted is returned when a blocked send or receive is abandoned
+// because its stop channel was closed.
+var ErrInterrupted = errors.New("channel operation interrupted")
+
+// Channel passes values between goroutines started with diverge. With a
+// capacity of 0 each send waits for a matching receive.
+//
+// The underlying Go channel is never closed; closing is signalled through
+// done instead, so a sender blocked on a channel that gets closed returns an
+// error rather than panicking.
+type Channel struct {
+	Capacity int
+
+	values chan Object
+	done   chan struct{}
+	once   sync.Once
+
+	// closeMu orders sends that need no waiting against Close, so a send
+	// that starts after Close always fails
+	closeMu sync.RWMutex
+}
+
+func NewChannel(capacity int) *Channel {
+	return &Channel{
+		Capacity: capacity,
+		values:   make(chan Object, capacity),
+		done:     make(chan struct{}),
+	}
+}
+
+func (c *Channel) Type() ObjectType { return CHANNEL_OBJ }
+func (c *Channel) Inspect() string {
+	if c.IsClosed() {
+		return fmt.Sprintf("channel(capacity=%d, closed)", c.Capacity)
+	}
+	return fmt.Sprintf("channel(capacity=%d)", c.Capacity)
+}
+
+// Send blocks until value is received or buffered.
+func (c *Channel) Send(value Object) error {
+	return c.SendUntil(value, nil)
+}
+
+// SendUntil is Send, giving up with ErrInterrupted once stop is closed. A
+// nil stop never fires.
+func (c *Channel) SendUntil(value Object, stop <-chan struct{}) error {
+	if sent, err := c.TrySend(value); sent || err != nil {
+		return err
+	}
+	// Go picks at random between ready cases, so a send that has to wait
+	// can still succeed if Close happens while it waits
+	select {
+	case c.values <- value:
+		return nil
+	case <-c.done:
+		return ErrChannelClosed
+	case <-stop:
+		return ErrInterrupted
+	}
+}
+
+// TrySend sends value if it can be received or buffered without waiting.
+// It fails with ErrChannelClosed once the channel is closed, even if the
+// buffer has room.
+func (c *Channel) TrySend(value Object) (bool, error) {
+	c.closeMu.RLock()
+	defer c.closeMu.RUnlock()
+	if c.IsClosed() {
+		return false, ErrChannelClosed
+	}
+	select {
+	case c.values <- value:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Receive blocks until a value is available. ok is false once the channel
+// is closed and every buffered value has been received.
+func (c *Channel) Receive() (value Object, ok bool) {
+	value, ok, _ = c.ReceiveUntil(nil)
+	return value, ok
+}
+
+// ReceiveUntil is Receive, giving up with ErrInterrupted once stop is
+// closed. A nil stop never fires.
+func (c *Channel) ReceiveUntil(stop <-chan struct{}) (Object, bool, error) {
+	select {
+	case value := <-c.values:
+		return value, true, nil
+	case <-c.done:
+		value, ok := c.Drain()
+		return value, ok, nil
+	case <-stop:
+		return nil, false, ErrInterrupted
+	}
+}
+
+// Drain returns a buffered value without blocking, for use after the
+// channel has been closed.
+func (c *Channel) Drain() (Object, bool) {
+	select {
+	case value := <-c.values:
+		return value, true
+	default:
+		return nil, false
+	}
+}
+
+// Close stops further sends. Values already buffered can still be received.
+func (c *Channel) Close() error {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+	closed := false
+	c.once.Do(func() {
+		close(c.done)
+		closed = true
+	})
+	if !closed {
+		return ErrChannelClosed
+	}
+	return nil
+}
+
+func (c *Channel) IsClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Len returns the number of buffered values.
+func (c *Channel) Len() int { return len(c.values) }
+
+// Values and Done expose the underlying channels for select statements.
+func (c *Channel) Values() chan Object   { return c.values }
+func (c *Channel) Done() <-chan struct{} { return c.done }
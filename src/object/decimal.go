@@ -0,0 +1,384 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Rounding modes understood by Decimal.Round and the decimal context.
+const (
+	RoundHalfEven = "half_even"
+	RoundHalfUp   = "half_up"
+	RoundHalfDown = "half_down"
+	RoundDown     = "down"
+	RoundUp       = "up"
+	RoundFloor    = "floor"
+	RoundCeiling  = "ceiling"
+)
+
+// DefaultDecimalPrecision is the number of significant digits kept by
+// inexact decimal operations until the context is changed.
+const DefaultDecimalPrecision = 28
+
+var ErrDecimalDivisionByZero = errors.New("decimal division by zero")
+
+// Decimal is an exact base-10 number: Coef * 10^-Scale. Addition,
+// subtraction and multiplication are exact; division and negative powers
+// round to the precision and rounding mode of the decimal context.
+type Decimal struct {
+	Coef  *big.Int
+	Scale int
+}
+
+func (d *Decimal) Type() ObjectType { return DECIMAL_OBJ }
+func (d *Decimal) Inspect() string  { return d.String() }
+
+var decimalContext = struct {
+	sync.RWMutex
+	precision int
+	rounding  string
+}{precision: DefaultDecimalPrecision, rounding: RoundHalfEven}
+
+// DecimalContext returns the current precision and rounding mode.
+func DecimalContext() (int, string) {
+	decimalContext.RLock()
+	defer decimalContext.RUnlock()
+	return decimalContext.precision, decimalContext.rounding
+}
+
+// SetDecimalContext changes the precision and rounding mode used by
+// inexact decimal operations.
+func SetDecimalContext(precision int, rounding string) error {
+	if precision < 1 {
+		return fmt.Errorf("decimal precision must be at least 1, got %d", precision)
+	}
+	if !IsRoundingMode(rounding) {
+		return fmt.Errorf("unknown rounding mode: %s", rounding)
+	}
+	decimalContext.Lock()
+	defer decimalContext.Unlock()
+	decimalContext.precision = precision
+	decimalContext.rounding = rounding
+	return nil
+}
+
+// IsRoundingMode reports whether mode names a supported rounding mode.
+func IsRoundingMode(mode string) bool {
+	switch mode {
+	case RoundHalfEven, RoundHalfUp, RoundHalfDown, RoundDown, RoundUp, RoundFloor, RoundCeiling:
+		return true
+	}
+	return false
+}
+
+// NewDecimal returns the decimal value coef * 10^-scale.
+func NewDecimal(coef *big.Int, scale int) *Decimal {
+	if scale < 0 {
+		coef = new(big.Int).Mul(coef, pow10(-scale))
+		scale = 0
+	}
+	return &Decimal{Coef: coef, Scale: scale}
+}
+
+// ParseDecimal parses a decimal string such as "19.99", "-0.5" or "1e-3".
+// The scale of the result follows the digits as written, so "1.50" keeps
+// two decimal places.
+func ParseDecimal(s string) (*Decimal, bool) {
+	s = strings.TrimSpace(s)
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return nil, false
+		}
+		exp = e
+		s = s[:i]
+	}
+	sign := ""
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		sign, s = s[:1], s[1:]
+	}
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return nil, false
+	}
+	digits := intPart + fracPart
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return nil, false
+		}
+	}
+	coef, ok := new(big.Int).SetString(sign+digits, 10)
+	if !ok {
+		return nil, false
+	}
+	return NewDecimal(coef, len(fracPart)-exp), true
+}
+
+// DecimalFromFloat converts a float using its shortest decimal
+// representation, so 0.1 becomes exactly 0.1.
+func DecimalFromFloat(f float64) (*Decimal, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// ToDecimal converts an Integer, BigInteger, Float or Decimal to a Decimal.
+func ToDecimal(obj Object) (*Decimal, bool) {
+	switch v := obj.(type) {
+	case *Decimal:
+		return v, true
+	case *Integer:
+		return NewDecimal(big.NewInt(v.Value), 0), true
+	case *BigInteger:
+		return NewDecimal(v.Value, 0), true
+	case *Float:
+		return DecimalFromFloat(v.Value)
+	}
+	return nil, false
+}
+
+// String renders the decimal in plain notation, keeping its scale.
+func (d *Decimal) String() string {
+	digits := new(big.Int).Abs(d.Coef).String()
+	if d.Scale > 0 {
+		if len(digits) <= d.Scale {
+			digits = strings.Repeat("0", d.Scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.Scale] + "." + digits[len(digits)-d.Scale:]
+	}
+	if d.Coef.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Float64 returns the nearest float64 to d.
+func (d *Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// Int returns the integer part of d, truncated toward zero.
+func (d *Decimal) Int() *big.Int {
+	return new(big.Int).Quo(d.Coef, pow10(d.Scale))
+}
+
+func (d *Decimal) Sign() int { return d.Coef.Sign() }
+
+func (d *Decimal) IsInteger() bool {
+	return new(big.Int).Rem(d.Coef, pow10(d.Scale)).Sign() == 0
+}
+
+func (d *Decimal) Neg() *Decimal {
+	return &Decimal{Coef: new(big.Int).Neg(d.Coef), Scale: d.Scale}
+}
+
+func (d *Decimal) Abs() *Decimal {
+	return &Decimal{Coef: new(big.Int).Abs(d.Coef), Scale: d.Scale}
+}
+
+func (d *Decimal) Add(other *Decimal) *Decimal {
+	a, b, scale := alignDecimals(d, other)
+	return &Decimal{Coef: a.Add(a, b), Scale: scale}
+}
+
+func (d *Decimal) Sub(other *Decimal) *Decimal {
+	a, b, scale := alignDecimals(d, other)
+	return &Decimal{Coef: a.Sub(a, b), Scale: scale}
+}
+
+func (d *Decimal) Mul(other *Decimal) *Decimal {
+	return &Decimal{Coef: new(big.Int).Mul(d.Coef, other.Coef), Scale: d.Scale + other.Scale}
+}
+
+// Cmp compares d and other, returning -1, 0 or +1.
+func (d *Decimal) Cmp(other *Decimal) int {
+	a, b, _ := alignDecimals(d, other)
+	return a.Cmp(b)
+}
+
+// Quo divides d by other, rounding the quotient to the context precision.
+// Exact quotients drop trailing zeros beyond the scale of the operands.
+func (d *Decimal) Quo(other *Decimal) (*Decimal, error) {
+	if other.Sign() == 0 {
+		return nil, ErrDecimalDivisionByZero
+	}
+	precision, rounding := DecimalContext()
+	shift := precision + numDigits(other.Coef) - numDigits(d.Coef) + 2
+	if shift < 0 {
+		shift = 0
+	}
+	num := new(big.Int).Mul(d.Coef, pow10(shift))
+	quo, rem := new(big.Int).QuoRem(num, other.Coef, new(big.Int))
+	scale := d.Scale + shift - other.Scale
+	if rem.Sign() != 0 {
+		// Append a sticky digit so the single rounding below sees that the
+		// discarded part is neither zero nor exactly half.
+		quo.Mul(quo, big.NewInt(10))
+		quo.Add(quo, big.NewInt(int64(num.Sign()*other.Coef.Sign())))
+		scale++
+		return roundSignificant(quo, scale, precision, rounding), nil
+	}
+	result := roundSignificant(quo, scale, precision, rounding)
+	ideal := d.Scale - other.Scale
+	if ideal < 0 {
+		ideal = 0
+	}
+	return result.trimTo(ideal), nil
+}
+
+// QuoInt divides d by other, truncating toward zero like integer division.
+func (d *Decimal) QuoInt(other *Decimal) (*Decimal, error) {
+	if other.Sign() == 0 {
+		return nil, ErrDecimalDivisionByZero
+	}
+	a, b, _ := alignDecimals(d, other)
+	return &Decimal{Coef: a.Quo(a, b), Scale: 0}, nil
+}
+
+// Rem returns d - other*QuoInt(d, other); the result has the sign of d.
+func (d *Decimal) Rem(other *Decimal) (*Decimal, error) {
+	if other.Sign() == 0 {
+		return nil, ErrDecimalDivisionByZero
+	}
+	a, b, scale := alignDecimals(d, other)
+	return &Decimal{Coef: a.Rem(a, b), Scale: scale}, nil
+}
+
+// Pow raises d to an integer power. Negative powers divide and so round to
+// the context precision.
+func (d *Decimal) Pow(exp int64) (*Decimal, error) {
+	n := exp
+	if n < 0 {
+		n = -n
+	}
+	result := &Decimal{
+		Coef:  new(big.Int).Exp(d.Coef, big.NewInt(n), nil),
+		Scale: d.Scale * int(n),
+	}
+	if exp < 0 {
+		return NewDecimal(big.NewInt(1), 0).Quo(result)
+	}
+	return result, nil
+}
+
+// Round rounds d to the given number of decimal places, padding with zeros
+// when d has fewer places.
+func (d *Decimal) Round(places int, mode string) *Decimal {
+	if d.Scale <= places {
+		return NewDecimal(new(big.Int).Mul(d.Coef, pow10(places-d.Scale)), places)
+	}
+	coef := roundQuo(d.Coef, pow10(d.Scale-places), mode)
+	return NewDecimal(coef, places)
+}
+
+// Normalize strips trailing zeros from the fractional part.
+func (d *Decimal) Normalize() *Decimal {
+	return d.trimTo(0)
+}
+
+func (d *Decimal) trimTo(minScale int) *Decimal {
+	coef, scale := new(big.Int).Set(d.Coef), d.Scale
+	ten := big.NewInt(10)
+	rem := new(big.Int)
+	for scale > minScale && coef.Sign() != 0 {
+		q, r := new(big.Int).QuoRem(coef, ten, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		coef, scale = q, scale-1
+	}
+	if coef.Sign() == 0 && scale > minScale {
+		scale = minScale
+	}
+	return &Decimal{Coef: coef, Scale: scale}
+}
+
+func (d *Decimal) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(d.Normalize().String()))
+	return HashKey{Type: d.Type(), Value: h.Sum64()}
+}
+
+func alignDecimals(x, y *Decimal) (*big.Int, *big.Int, int) {
+	a, b := new(big.Int).Set(x.Coef), new(big.Int).Set(y.Coef)
+	switch {
+	case x.Scale < y.Scale:
+		a.Mul(a, pow10(y.Scale-x.Scale))
+		return a, b, y.Scale
+	case y.Scale < x.Scale:
+		b.Mul(b, pow10(x.Scale-y.Scale))
+	}
+	return a, b, x.Scale
+}
+
+// roundSignificant rounds coef * 10^-scale to at most precision
+// significant digits.
+func roundSignificant(coef *big.Int, scale, precision int, mode string) *Decimal {
+	drop := numDigits(coef) - precision
+	if drop <= 0 {
+		return NewDecimal(coef, scale)
+	}
+	return NewDecimal(roundQuo(coef, pow10(drop), mode), scale-drop)
+}
+
+// roundQuo divides n by the positive divisor d, rounding the quotient to an
+// integer according to mode.
+func roundQuo(n, d *big.Int, mode string) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	negative := n.Sign() < 0
+	half := new(big.Int).Abs(r)
+	half.Mul(half, big.NewInt(2))
+	cmp := half.Cmp(d)
+
+	var away bool
+	switch mode {
+	case RoundUp:
+		away = true
+	case RoundDown:
+		away = false
+	case RoundFloor:
+		away = negative
+	case RoundCeiling:
+		away = !negative
+	case RoundHalfUp:
+		away = cmp >= 0
+	case RoundHalfDown:
+		away = cmp > 0
+	default:
+		away = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+	}
+	if away {
+		if negative {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func numDigits(n *big.Int) int {
+	if n.Sign() == 0 {
+		return 1
+	}
+	return len(new(big.Int).Abs(n).String())
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -1,9 +1,17 @@
 package object
 
-import "github.com/javanhut/TheCarrionLanguage/src/debug"
+import (
+	"sync"
+
+	"github.com/javanhut/TheCarrionLanguage/src/debug"
+)
 
 // environment.go
+//
+// An Environment may be shared between goroutines started with diverge, so
+// every access to store and globalVars goes through mu.
 type Environment struct {
+	mu          sync.RWMutex
 	store       map[string]Object
 	outer       *Environment
 	debugConfig *debug.Config
@@ -23,19 +31,27 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
+	for env := e; env != nil; env = env.outer {
+		env.mu.RLock()
+		obj, ok := env.store[name]
+		env.mu.RUnlock()
+		if ok {
+			return obj, true
+		}
 	}
-	return obj, ok
+	return nil, false
 }
 
 func (e *Environment) Set(name string, val Object) Object {
+	e.mu.Lock()
 	e.store[name] = val
+	e.mu.Unlock()
 	return val
 }
 
 func (e *Environment) GetNames() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 	names := make([]string, 0)
 	for name := range e.store {
 		names = append(names, name)
@@ -50,7 +66,8 @@ func (e *Environment) GetOuter() *Environment {
 // Clone creates a deep copy of the environment to prevent shared references
 func (e *Environment) Clone() *Environment {
 	clone := NewEnvironment()
-	
+
+	e.mu.RLock()
 	// Copy all variables from this environment
 	for name, obj := range e.store {
 		clone.store[name] = obj
@@ -60,7 +77,8 @@ func (e *Environment) Clone() *Environment {
 	for name, isGlobal := range e.globalVars {
 		clone.globalVars[name] = isGlobal
 	}
-	
+	e.mu.RUnlock()
+
 	// Recursively clone the outer environment if it exists
 	if e.outer != nil {
 		clone.outer = e.outer.Clone()
@@ -92,6 +110,8 @@ func (e *Environment) GetDebugConfig() *debug.Config {
 
 // MarkGlobal marks a variable as global in the current environment
 func (e *Environment) MarkGlobal(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	if e.globalVars == nil {
 		e.globalVars = make(map[string]bool)
 	}
@@ -100,6 +120,8 @@ func (e *Environment) MarkGlobal(name string) {
 
 // IsGlobal checks if a variable is marked as global in this environment
 func (e *Environment) IsGlobal(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 	return e.globalVars[name]
 }
 
@@ -110,7 +132,9 @@ func (e *Environment) SetGlobal(name string, val Object) Object {
 	for globalEnv.outer != nil {
 		globalEnv = globalEnv.outer
 	}
+	globalEnv.mu.Lock()
 	globalEnv.store[name] = val
+	globalEnv.mu.Unlock()
 	return val
 }
 
@@ -124,6 +148,8 @@ func (e *Environment) SetWithGlobalCheck(name string, val Object) Object {
 
 // GetStore returns a copy of the environment's store for external access
 func (e *Environment) GetStore() map[string]Object {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 	result := make(map[string]Object)
 	for name, obj := range e.store {
 		result[name] = obj
@@ -0,0 +1,96 @@
+package object
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// InstanceHashKey and InstanceEqual let grimoire instances act as hash keys
+// through their __hash__ and __eq__ methods. Calling methods needs the
+// interpreter, so the evaluator installs them. InstanceHashKey reports
+// false for instances that are not hashable, and InstanceEqual returns the
+// error __eq__ raised, if any.
+var (
+	InstanceHashKey func(inst *Instance) (HashKey, bool)
+	InstanceEqual   func(a, b *Instance) (bool, Object)
+)
+
+// HashKeyOf returns the HashKey of obj, reporting false if obj cannot be
+// used as a key. Tuples are hashable when all their elements are.
+func HashKeyOf(obj Object) (HashKey, bool) {
+	switch o := obj.(type) {
+	case *Tuple:
+		h := fnv.New64a()
+		var buf [8]byte
+		for _, elem := range o.Elements {
+			key, ok := HashKeyOf(elem)
+			if !ok {
+				return HashKey{}, false
+			}
+			h.Write([]byte(key.Type))
+			binary.LittleEndian.PutUint64(buf[:], key.Value)
+			h.Write(buf[:])
+		}
+		return HashKey{Type: o.Type(), Value: h.Sum64()}, true
+	case *Instance:
+		if InstanceHashKey == nil {
+			return HashKey{}, false
+		}
+		return InstanceHashKey(o)
+	case Hashable:
+		return o.HashKey(), true
+	}
+	return HashKey{}, false
+}
+
+// KeysEqual reports whether a and b are the same hash key. Equal HashKeys
+// only mean the keys might be equal; this settles it. The error is one
+// raised by an instance key's __eq__.
+func KeysEqual(a, b Object) (bool, Object) {
+	if a == b {
+		return true, nil
+	}
+	switch av := a.(type) {
+	case *Integer:
+		bv, ok := b.(*Integer)
+		return ok && av.Value == bv.Value, nil
+	case *BigInteger:
+		bv, ok := b.(*BigInteger)
+		return ok && av.Value.Cmp(bv.Value) == 0, nil
+	case *Float:
+		bv, ok := b.(*Float)
+		return ok && (av.Value == bv.Value || math.IsNaN(av.Value) && math.IsNaN(bv.Value)), nil
+	case *String:
+		bv, ok := b.(*String)
+		return ok && av.Value == bv.Value, nil
+	case *Boolean:
+		bv, ok := b.(*Boolean)
+		return ok && av.Value == bv.Value, nil
+	case *Decimal:
+		bv, ok := b.(*Decimal)
+		return ok && av.Cmp(bv) == 0, nil
+	case *Bytes:
+		bv, ok := b.(*Bytes)
+		return ok && bytes.Equal(av.Value, bv.Value), nil
+	case *Tuple:
+		bv, ok := b.(*Tuple)
+		if !ok || len(av.Elements) != len(bv.Elements) {
+			return false, nil
+		}
+		for i := range av.Elements {
+			if equal, err := KeysEqual(av.Elements[i], bv.Elements[i]); !equal || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case *Instance:
+		bv, ok := b.(*Instance)
+		if !ok || InstanceEqual == nil {
+			return false, nil
+		}
+		return InstanceEqual(av, bv)
+	}
+	return a.Type() == b.Type() && a.Inspect() == b.Inspect(), nil
+}
@@ -2,8 +2,14 @@ package object
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -42,6 +48,13 @@ const (
 	SUPER_OBJ             = "SUPER"
 	TIME_OBJ              = "TIME"
 	DURATION_OBJ          = "DURATION"
+	GENERATOR_OBJ         = "GENERATOR"
+	BIG_INTEGER_OBJ       = "BIG_INTEGER"
+	DECIMAL_OBJ           = "DECIMAL"
+	SET_OBJ               = "SET"
+	BYTES_OBJ             = "BYTES"
+	BYTE_ARRAY_OBJ        = "BYTE_ARRAY"
+	CHANNEL_OBJ           = "CHANNEL"
 )
 
 var NONE = &None{}
@@ -73,6 +86,58 @@ func NewInteger(value int64) *Integer {
 	return &Integer{Value: value}
 }
 
+// BigInteger holds integers that do not fit in an int64. Integer
+// arithmetic promotes to BigInteger on overflow and results that fit again
+// are demoted, so a BigInteger is always outside the int64 range.
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (b *BigInteger) Type() ObjectType { return BIG_INTEGER_OBJ }
+func (b *BigInteger) Inspect() string  { return b.Value.String() }
+
+// NewBigInteger returns value as an Integer when it fits in an int64 and
+// as a BigInteger otherwise.
+func NewBigInteger(value *big.Int) Object {
+	if value.IsInt64() {
+		return NewInteger(value.Int64())
+	}
+	return &BigInteger{Value: value}
+}
+
+// ParseInteger parses an integer string in the given base (0 detects a
+// prefix), promoting to a BigInteger when it overflows an int64.
+func ParseInteger(s string, base int) (Object, bool) {
+	if value, err := strconv.ParseInt(s, base, 64); err == nil {
+		return NewInteger(value), true
+	}
+	value, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return nil, false
+	}
+	return NewBigInteger(value), true
+}
+
+// FromJSONNumber converts a decoded JSON number, keeping integers exact.
+func FromJSONNumber(n json.Number) Object {
+	if value, ok := ParseInteger(n.String(), 10); ok {
+		return value
+	}
+	f, _ := n.Float64()
+	return &Float{Value: f}
+}
+
+// ToBigInt returns the value of an Integer or BigInteger as a big.Int.
+func ToBigInt(obj Object) (*big.Int, bool) {
+	switch v := obj.(type) {
+	case *Integer:
+		return big.NewInt(v.Value), true
+	case *BigInteger:
+		return v.Value, true
+	}
+	return nil, false
+}
+
 type Float struct {
 	Value float64
 }
@@ -135,6 +200,13 @@ type Function struct {
 	IsAbstract  bool
 	IsPrivate   bool
 	IsProtected bool
+	// Wrapper is set on a decorated grimoire method to the wrapper its
+	// decorators returned. When the wrapper calls back into the method,
+	// the method runs on the wrapper's self.
+	Wrapper *Function
+	// IsGenerator marks spells whose body contains yield; calling one
+	// returns a Generator instead of running the body.
+	IsGenerator bool
 }
 
 func (f *Function) Inspect() string {
@@ -170,15 +242,34 @@ type Builtin struct {
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
+// Array is an ordered list of values that goroutines may share. Code that
+// writes Elements holds the embedded write lock; code that reads it holds
+// the read lock or works on a Snapshot.
 type Array struct {
 	Elements []Object
+	sync.RWMutex
+}
+
+// Snapshot returns a copy of the elements.
+func (ao *Array) Snapshot() []Object {
+	ao.RLock()
+	defer ao.RUnlock()
+	return append([]Object(nil), ao.Elements...)
+}
+
+// Len returns the number of elements.
+func (ao *Array) Len() int {
+	ao.RLock()
+	defer ao.RUnlock()
+	return len(ao.Elements)
 }
 
 func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
 func (ao *Array) Inspect() string {
 	var out bytes.Buffer
-	elems := make([]string, 0, len(ao.Elements))
-	for _, e := range ao.Elements {
+	elements := ao.Snapshot()
+	elems := make([]string, 0, len(elements))
+	for _, e := range elements {
 		elems = append(elems, e.Inspect())
 	}
 	out.WriteString("[")
@@ -206,6 +297,12 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+func (b *BigInteger) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -213,24 +310,269 @@ func (s *String) HashKey() HashKey {
 }
 
 func (f *Float) HashKey() HashKey {
-	// Convert float to its bit representation for hashing
-	bits := uint64(f.Value * 1000000) // Multiply by 1M to preserve 6 decimal places
-	return HashKey{Type: f.Type(), Value: bits}
+	value := f.Value
+	if value == 0 {
+		value = 0 // -0.0 and 0.0 are the same key
+	} else if math.IsNaN(value) {
+		value = math.NaN()
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(value)}
 }
 
 type HashPair struct {
 	Key   Object
 	Value Object
 }
+
+// Hash maps keys to values. pairs holds the first key stored under each
+// HashKey and gives constant-time lookup. Keys whose HashKey collides with
+// a different key go to overflow and are found by comparing with
+// KeysEqual. order records insertion order so iteration, printing and
+// serialization are stable; each stored slot knows its position in it, so
+// Delete leaves a tombstone there instead of shifting the rest, and the
+// tombstones are compacted away once they outnumber the live entries.
+// mu lets goroutines share a hash; each method is atomic, but a sequence
+// of calls is not.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	mu          sync.RWMutex
+	pairs       map[HashKey]hashSlot
+	overflow    map[HashKey][]hashSlot
+	overflowLen int
+	order       []hashEntry
+	tombstones  int
+}
+
+// hashSlot is a stored pair and its index in order.
+type hashSlot struct {
+	pair HashPair
+	pos  int
+}
+
+// hashEntry identifies one stored key for ordering. A nil key marks a
+// deleted entry.
+type hashEntry struct {
+	hash HashKey
+	key  Object
+}
+
+func NewHash() *Hash {
+	return &Hash{pairs: make(map[HashKey]hashSlot)}
+}
+
+// Set stores pair under hash, which must be the HashKey of pair.Key.
+// Replacing an existing key keeps its original key object and position.
+// It returns the error raised while comparing keys, if any, and then
+// leaves the hash unchanged.
+func (h *Hash) Set(hash HashKey, pair HashPair) Object {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pairs == nil {
+		h.pairs = make(map[HashKey]hashSlot)
+	}
+	existing, exists := h.pairs[hash]
+	if !exists {
+		h.pairs[hash] = hashSlot{pair, h.appendEntry(hash, pair.Key)}
+		return nil
+	}
+	equal, err := KeysEqual(existing.pair.Key, pair.Key)
+	if err != nil {
+		return err
+	}
+	if equal {
+		existing.pair.Value = pair.Value
+		h.pairs[hash] = existing
+		return nil
+	}
+
+	bucket := h.overflow[hash]
+	for i, other := range bucket {
+		equal, err := KeysEqual(other.pair.Key, pair.Key)
+		if err != nil {
+			return err
+		}
+		if equal {
+			bucket[i].pair.Value = pair.Value
+			return nil
+		}
+	}
+	if h.overflow == nil {
+		h.overflow = make(map[HashKey][]hashSlot)
+	}
+	h.overflow[hash] = append(bucket, hashSlot{pair, h.appendEntry(hash, pair.Key)})
+	h.overflowLen++
+	return nil
+}
+
+func (h *Hash) appendEntry(hash HashKey, key Object) int {
+	h.order = append(h.order, hashEntry{hash, key})
+	return len(h.order) - 1
+}
+
+// Put stores value under key, returning false if key is not hashable. It
+// is meant for keys without __eq__, so a comparison error is ignored.
+func (h *Hash) Put(key, value Object) bool {
+	hash, ok := HashKeyOf(key)
+	if !ok {
+		return false
+	}
+	h.Set(hash, HashPair{Key: key, Value: value})
+	return true
+}
+
+// Lookup finds the pair for key, whose HashKey is hash. The error is one
+// raised while comparing keys.
+func (h *Hash) Lookup(hash HashKey, key Object) (HashPair, bool, Object) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	slot, exists := h.pairs[hash]
+	if !exists {
+		return HashPair{}, false, nil
+	}
+	if equal, err := KeysEqual(slot.pair.Key, key); equal || err != nil {
+		return slot.pair, equal, err
+	}
+	for _, other := range h.overflow[hash] {
+		if equal, err := KeysEqual(other.pair.Key, key); equal || err != nil {
+			return other.pair, equal, err
+		}
+	}
+	return HashPair{}, false, nil
+}
+
+// Get finds the pair for key. It reports false for unhashable keys and,
+// like Put, is meant for keys without __eq__.
+func (h *Hash) Get(key Object) (HashPair, bool) {
+	hash, ok := HashKeyOf(key)
+	if !ok {
+		return HashPair{}, false
+	}
+	pair, found, _ := h.Lookup(hash, key)
+	return pair, found
+}
+
+// Delete removes key, returning false if it was not present. The error is
+// one raised while comparing keys.
+func (h *Hash) Delete(key Object) (bool, Object) {
+	hash, ok := HashKeyOf(key)
+	if !ok {
+		return false, nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	primary, exists := h.pairs[hash]
+	if !exists {
+		return false, nil
+	}
+
+	var removed hashSlot
+	bucket := h.overflow[hash]
+	equal, err := KeysEqual(primary.pair.Key, key)
+	if err != nil {
+		return false, err
+	}
+	if equal {
+		removed = primary
+		if len(bucket) > 0 {
+			h.pairs[hash] = bucket[0]
+			bucket = bucket[1:]
+			h.overflowLen--
+		} else {
+			delete(h.pairs, hash)
+		}
+	} else {
+		i := 0
+		for ; i < len(bucket); i++ {
+			equal, err := KeysEqual(bucket[i].pair.Key, key)
+			if err != nil {
+				return false, err
+			}
+			if equal {
+				break
+			}
+		}
+		if i == len(bucket) {
+			return false, nil
+		}
+		removed = bucket[i]
+		bucket = append(bucket[:i:i], bucket[i+1:]...)
+		h.overflowLen--
+	}
+	if len(bucket) == 0 {
+		delete(h.overflow, hash)
+	} else {
+		h.overflow[hash] = bucket
+	}
+
+	h.order[removed.pos] = hashEntry{}
+	h.tombstones++
+	if h.tombstones > len(h.order)/2 {
+		h.compact()
+	}
+	return true, nil
+}
+
+// compact drops the tombstones from order and renumbers the slots.
+func (h *Hash) compact() {
+	order := make([]hashEntry, 0, h.size())
+	for _, entry := range h.order {
+		if entry.key == nil {
+			continue
+		}
+		pos := len(order)
+		order = append(order, entry)
+		if slot := h.pairs[entry.hash]; slot.pair.Key == entry.key {
+			slot.pos = pos
+			h.pairs[entry.hash] = slot
+			continue
+		}
+		bucket := h.overflow[entry.hash]
+		for i := range bucket {
+			if bucket[i].pair.Key == entry.key {
+				bucket[i].pos = pos
+				break
+			}
+		}
+	}
+	h.order = order
+	h.tombstones = 0
+}
+
+func (h *Hash) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.size()
+}
+
+func (h *Hash) size() int { return len(h.pairs) + h.overflowLen }
+
+// Ordered returns the pairs in insertion order.
+func (h *Hash) Ordered() []HashPair {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	result := make([]HashPair, 0, h.size())
+	for _, entry := range h.order {
+		if entry.key == nil {
+			continue
+		}
+		if slot := h.pairs[entry.hash]; slot.pair.Key == entry.key {
+			result = append(result, slot.pair)
+			continue
+		}
+		for _, other := range h.overflow[entry.hash] {
+			if other.pair.Key == entry.key {
+				result = append(result, other.pair)
+				break
+			}
+		}
+	}
+	return result
 }
 
 func (h *Hash) Type() ObjectType { return MAP_OBJ }
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Ordered() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -257,6 +599,9 @@ func (t *Tuple) Inspect() string {
 	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(elems, ", "))
+	if len(elems) == 1 {
+		out.WriteString(",")
+	}
 	out.WriteString(")")
 	return out.String()
 }
@@ -269,6 +614,32 @@ type Grimoire struct {
 	Inherits   *Grimoire
 	Env        *Environment // Add environment to store the grimoire's scope
 	IsArcane   bool
+
+	// Enum grimoires inherit from Enum. Their members are instances with
+	// name and value attributes, created once in declaration order.
+	IsEnum      bool
+	EnumMembers []*Instance
+
+	// Computed attributes declared with @property, including inherited ones
+	Properties map[string]*Property
+}
+
+// Property is a computed attribute of a grimoire. Reading the attribute
+// calls Getter and assigning it calls Setter; without a Setter the
+// property is read-only.
+type Property struct {
+	Getter *Function
+	Setter *Function
+}
+
+// EnumMember returns the member of an enum grimoire with the given name.
+func (s *Grimoire) EnumMember(name string) (*Instance, bool) {
+	for _, member := range s.EnumMembers {
+		if memberName, ok := member.Env.Get("name"); ok && memberName.(*String).Value == name {
+			return member, true
+		}
+	}
+	return nil, false
 }
 
 func (s *Grimoire) Type() ObjectType { return GRIMOIRE_OBJ }
@@ -335,6 +706,12 @@ func (ce *CaughtError) GetMessage() string {
 	return ce.OriginalError.Inspect()
 }
 func (i *Instance) Inspect() string {
+	if i.Grimoire.IsEnum {
+		if name, ok := i.Env.Get("name"); ok {
+			return i.Grimoire.Name + "." + name.(*String).Value
+		}
+	}
+
 	// Special handling for primitive wrapper instances
 	switch i.Grimoire.Name {
 	case "Integer", "Float", "String", "Boolean":
@@ -382,16 +759,130 @@ var (
 	SKIP = &Skip{}
 )
 
-// Goroutine represents a running goroutine in Carrion
+// Generator is the suspended execution of a generator spell. The body runs
+// on its own goroutine and hands control back and forth with the consumer
+// over unbuffered channels, so only one side ever runs at a time.
+//
+// The body goroutine only holds the GeneratorBody, never the Generator, so
+// a generator that is dropped part way through can still be collected.
+// A cleanup then closes the body on a background goroutine, which unwinds
+// at its yield as if close() had been called. Bodies whose environment
+// still refers to the generator, such as one assigned to a global, stay
+// suspended until the program exits.
+type Generator struct {
+	Name string
+	*GeneratorBody
+}
+
+// GeneratorBody is the state shared by a generator and the goroutine
+// running its body. mu serializes Next and Close, so goroutines can share
+// a generator and each value goes to exactly one of them.
+type GeneratorBody struct {
+	Err      Object // Error raised by the body, set once the generator finishes
+	run      func(b *GeneratorBody) Object
+	resume   chan bool
+	yielded  chan Object
+	mu       sync.Mutex
+	started  bool
+	finished bool
+}
+
+// NewGenerator creates a generator that evaluates run on first use. run
+// returns an error object if the body failed, or nil.
+func NewGenerator(name string, run func(b *GeneratorBody) Object) *Generator {
+	return &Generator{
+		Name: name,
+		GeneratorBody: &GeneratorBody{
+			run:     run,
+			resume:  make(chan bool),
+			yielded: make(chan Object),
+		},
+	}
+}
+
+func (g *Generator) Type() ObjectType { return GENERATOR_OBJ }
+func (g *Generator) Inspect() string {
+	if g.Name != "" {
+		return fmt.Sprintf("generator(%s)", g.Name)
+	}
+	return "generator(anonymous)"
+}
+
+// Next resumes the body until its next yield. It returns false once the
+// generator is exhausted; Err then holds any error the body raised.
+func (g *Generator) Next() (Object, bool) {
+	b := g.GeneratorBody
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.finished {
+		return nil, false
+	}
+	if !b.started {
+		b.started = true
+		go func() {
+			b.Err = b.run(b)
+			close(b.yielded)
+		}()
+		// Close on its own goroutine, since the body may run resolve
+		// blocks while it unwinds
+		runtime.AddCleanup(g, func(b *GeneratorBody) { go b.Close() }, b)
+	} else {
+		b.resume <- true
+	}
+
+	value, ok := <-b.yielded
+	if !ok {
+		b.finished = true
+		return nil, false
+	}
+	return value, true
+}
+
+// Yield is called by the generator body. It blocks until the consumer asks
+// for the next value and returns false if the generator was closed instead.
+func (b *GeneratorBody) Yield(value Object) bool {
+	b.yielded <- value
+	return <-b.resume
+}
+
+// Close abandons a suspended generator, letting its body unwind.
+func (b *GeneratorBody) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.finished {
+		return
+	}
+	if b.started {
+		for {
+			b.resume <- false
+			if _, ok := <-b.yielded; !ok {
+				break
+			}
+		}
+	}
+	b.finished = true
+}
+
+// Goroutine represents a running goroutine in Carrion. Done is closed by
+// Finish when the goroutine ends, so any number of waiters can receive from
+// it; Result and Error are only safe to read after that.
 type Goroutine struct {
-	Name      string
-	Done      chan bool
-	Result    Object
-	Error     Object
-	IsRunning bool
+	Name   string
+	Done   chan bool
+	Result Object
+	Error  Object
+
+	mu        sync.Mutex
+	finished  bool
+	cancelled bool
+	cancel    chan struct{}
 	cleaned   bool // Track if cleanup has been performed
 }
 
+func NewGoroutine(name string) *Goroutine {
+	return &Goroutine{Name: name, Done: make(chan bool), cancel: make(chan struct{})}
+}
+
 func (g *Goroutine) Type() ObjectType { return GOROUTINE_OBJ }
 func (g *Goroutine) Inspect() string {
 	if g.Name != "" {
@@ -400,29 +891,68 @@ func (g *Goroutine) Inspect() string {
 	return "goroutine(anonymous)"
 }
 
-// Cleanup closes channels and releases resources when a goroutine finishes
-func (g *Goroutine) Cleanup() {
-	if g.cleaned {
-		return // Already cleaned up
+// Finish records the outcome of the goroutine and closes Done. Only the
+// goroutine itself calls it; later calls are ignored. It reports whether a
+// cancellation had been requested.
+func (g *Goroutine) Finish(result, err Object) (cancelled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.finished {
+		return false
 	}
-	g.cleaned = true
-
-	// Close the Done channel if it's not already closed
-	if g.Done != nil {
-		select {
-		case <-g.Done:
-			// Channel already closed or has a value
-		default:
-			close(g.Done)
-		}
+	g.finished = true
+	g.Result = result
+	g.Error = err
+	close(g.Done)
+	return g.cancelled
+}
+
+// Cancel asks the goroutine to stop. Cancellation is cooperative: the
+// goroutine notices it at its next loop iteration or blocking channel
+// operation. Cancel reports false if the goroutine had already finished or
+// been cancelled.
+func (g *Goroutine) Cancel() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.finished || g.cancelled {
+		return false
 	}
+	g.cancelled = true
+	close(g.cancel)
+	return true
+}
 
-	// Mark as not running
-	g.IsRunning = false
+func (g *Goroutine) IsCancelled() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.cancelled
+}
 
-	// Clear references to help GC
-	g.Result = nil
-	g.Error = nil
+// Cancelled returns a channel that is closed once Cancel succeeds.
+func (g *Goroutine) Cancelled() <-chan struct{} {
+	return g.cancel
+}
+
+// Status is one of "running", "completed", "failed" or "cancelled".
+func (g *Goroutine) Status() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case !g.finished:
+		return "running"
+	case g.Error == nil:
+		return "completed"
+	case g.cancelled:
+		return "cancelled"
+	default:
+		return "failed"
+	}
+}
+
+// Cleanup marks the goroutine as released by its manager. Done belongs to
+// the running goroutine and is left for Finish to close.
+func (g *Goroutine) Cleanup() {
+	g.cleaned = true
 }
 
 // IsCompleted checks if the goroutine has finished execution
@@ -439,6 +969,11 @@ func (g *Goroutine) IsCompleted() bool {
 	}
 }
 
+// IsRunning reports whether the goroutine is still executing.
+func (g *Goroutine) IsRunning() bool {
+	return !g.IsCompleted()
+}
+
 // GoroutineManager manages all active goroutines
 type GoroutineManager struct {
 	mu               sync.RWMutex
@@ -577,16 +1112,10 @@ func (gm *GoroutineManager) CleanupCompletedGoroutines() {
 	gm.cleanupCompletedLocked()
 }
 
-// cleanupCompletedLocked performs cleanup without acquiring the lock (internal use)
+// cleanupCompletedLocked performs cleanup without acquiring the lock (internal use).
+// Named goroutines are kept until they are converged so that their result
+// can still be collected.
 func (gm *GoroutineManager) cleanupCompletedLocked() {
-	// Clean up completed named goroutines
-	for name, goroutine := range gm.Goroutines {
-		if goroutine.IsCompleted() {
-			goroutine.Cleanup()
-			delete(gm.Goroutines, name)
-		}
-	}
-
 	// Clean up completed anonymous goroutines
 	newAnonymous := make([]*Goroutine, 0)
 	for _, goroutine := range gm.Anonymous {
@@ -630,6 +1159,36 @@ func (gm *GoroutineManager) GetCompletedCount() (namedCompleted, anonymousComple
 	return namedCompleted, anonymousCompleted
 }
 
+// StatusCounts returns how many tracked goroutines are in each state
+// reported by Goroutine.Status.
+func (gm *GoroutineManager) StatusCounts() map[string]int {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+
+	counts := map[string]int{"running": 0, "completed": 0, "failed": 0, "cancelled": 0}
+	for _, goroutine := range gm.Goroutines {
+		counts[goroutine.Status()]++
+	}
+	for _, goroutine := range gm.Anonymous {
+		counts[goroutine.Status()]++
+	}
+	return counts
+}
+
+// NamedGoroutineNames returns the names of the tracked named goroutines in
+// sorted order.
+func (gm *GoroutineManager) NamedGoroutineNames() []string {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+
+	names := make([]string, 0, len(gm.Goroutines))
+	for name := range gm.Goroutines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // SetMaxLimits updates the maximum size limits for both collections
 func (gm *GoroutineManager) SetMaxLimits(maxNamed, maxAnonymous int) {
 	gm.mu.Lock()
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +21,211 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestHashOrder(t *testing.T) {
+	h := NewHash()
+	for _, name := range []string{"c", "a", "b"} {
+		key := &String{Value: name}
+		h.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: 1}})
+	}
+	a := &String{Value: "a"}
+	h.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 2}})
+	if got := h.Inspect(); got != "{c: 1, a: 2, b: 1}" {
+		t.Errorf("expected {c: 1, a: 2, b: 1}, got %s", got)
+	}
+
+	if first, _ := h.Delete(a); !first {
+		t.Errorf("Delete should succeed once")
+	}
+	if again, _ := h.Delete(a); again {
+		t.Errorf("Delete should succeed once")
+	}
+	h.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 3}})
+	if got := h.Inspect(); got != "{c: 1, b: 1, a: 3}" {
+		t.Errorf("expected {c: 1, b: 1, a: 3}, got %s", got)
+	}
+}
+
+func TestHashDeleteKeepsOrder(t *testing.T) {
+	h := NewHash()
+	keys := make([]*Integer, 100)
+	for i := range keys {
+		keys[i] = &Integer{Value: int64(i)}
+		h.Put(keys[i], keys[i])
+	}
+	// Removing every key but the multiples of ten compacts the tombstones
+	// several times along the way
+	for i, key := range keys {
+		if i%10 == 0 {
+			continue
+		}
+		if deleted, _ := h.Delete(key); !deleted {
+			t.Fatalf("Delete(%d) failed", i)
+		}
+	}
+	if got := h.Inspect(); got != "{0: 0, 10: 10, 20: 20, 30: 30, 40: 40, 50: 50, 60: 60, 70: 70, 80: 80, 90: 90}" {
+		t.Errorf("unexpected order after deletes: %s", got)
+	}
+	h.Put(keys[5], keys[5])
+	h.Delete(keys[0])
+	if got := h.Inspect(); got != "{10: 10, 20: 20, 30: 30, 40: 40, 50: 50, 60: 60, 70: 70, 80: 80, 90: 90, 5: 5}" {
+		t.Errorf("unexpected order after re-adding: %s", got)
+	}
+	if h.Len() != 10 {
+		t.Errorf("expected 10 keys, got %d", h.Len())
+	}
+}
+
+func TestHashCollisions(t *testing.T) {
+	h := NewHash()
+	collide := HashKey{Type: STRING_OBJ, Value: 42}
+	a, b := &String{Value: "a"}, &String{Value: "b"}
+	h.Set(collide, HashPair{Key: a, Value: &Integer{Value: 1}})
+	h.Set(collide, HashPair{Key: b, Value: &Integer{Value: 2}})
+	h.Set(collide, HashPair{Key: &String{Value: "b"}, Value: &Integer{Value: 3}})
+
+	if h.Len() != 2 {
+		t.Fatalf("expected 2 keys, got %d", h.Len())
+	}
+	if pair, ok, _ := h.Lookup(collide, a); !ok || pair.Value.Inspect() != "1" {
+		t.Errorf("lookup of a: got %v, %v", pair.Value, ok)
+	}
+	if pair, ok, _ := h.Lookup(collide, b); !ok || pair.Value.Inspect() != "3" {
+		t.Errorf("lookup of b: got %v, %v", pair.Value, ok)
+	}
+	if _, ok, _ := h.Lookup(collide, &String{Value: "c"}); ok {
+		t.Errorf("lookup of a missing colliding key succeeded")
+	}
+	if got := h.Inspect(); got != "{a: 1, b: 3}" {
+		t.Errorf("expected {a: 1, b: 3}, got %s", got)
+	}
+}
+
+func TestHashKeyOf(t *testing.T) {
+	distinct := [][2]Object{
+		{&Float{Value: 0.1234567}, &Float{Value: 0.1234568}},
+		{&Float{Value: 1e-9}, &Float{Value: 2e-9}},
+		{&Tuple{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}, &Tuple{Elements: []Object{&Integer{Value: 2}, &Integer{Value: 1}}}},
+	}
+	for _, pair := range distinct {
+		k1, _ := HashKeyOf(pair[0])
+		k2, _ := HashKeyOf(pair[1])
+		if equal, _ := KeysEqual(pair[0], pair[1]); k1 == k2 || equal {
+			t.Errorf("%s and %s should be different keys", pair[0].Inspect(), pair[1].Inspect())
+		}
+	}
+
+	k1, _ := HashKeyOf(&Float{Value: 0})
+	k2, _ := HashKeyOf(&Float{Value: math.Copysign(0, -1)})
+	if k1 != k2 {
+		t.Errorf("0.0 and -0.0 should share a key")
+	}
+	if _, ok := HashKeyOf(&Tuple{Elements: []Object{&Array{}}}); ok {
+		t.Errorf("tuple holding an array should not be hashable")
+	}
+}
+
+func TestTupleInspect(t *testing.T) {
+	tests := []struct {
+		tuple    *Tuple
+		expected string
+	}{
+		{&Tuple{}, "()"},
+		{&Tuple{Elements: []Object{&Integer{Value: 1}}}, "(1,)"},
+		{&Tuple{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}, "(1, 2)"},
+	}
+	for _, tt := range tests {
+		if got := tt.tuple.Inspect(); got != tt.expected {
+			t.Errorf("got=%q, want=%q", got, tt.expected)
+		}
+	}
+}
+
+func TestNewBigIntegerDemotes(t *testing.T) {
+	small := NewBigInteger(big.NewInt(42))
+	if i, ok := small.(*Integer); !ok || i.Value != 42 {
+		t.Errorf("expected Integer 42, got %T (%s)", small, small.Inspect())
+	}
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if _, ok := NewBigInteger(huge).(*BigInteger); !ok {
+		t.Errorf("expected BigInteger for value outside int64 range")
+	}
+
+	parsed, ok := ParseInteger("123456789012345678901234567890", 10)
+	if !ok || parsed.(*BigInteger).HashKey() != (&BigInteger{Value: huge}).HashKey() {
+		t.Errorf("parsed big integer does not hash like an equal value")
+	}
+}
+
+func TestDecimalRounding(t *testing.T) {
+	tests := []struct {
+		value    string
+		mode     string
+		expected string
+	}{
+		{"2.5", RoundHalfEven, "2"},
+		{"3.5", RoundHalfEven, "4"},
+		{"2.5", RoundHalfUp, "3"},
+		{"2.5", RoundHalfDown, "2"},
+		{"2.51", RoundHalfDown, "3"},
+		{"-2.5", RoundHalfUp, "-3"},
+		{"2.1", RoundUp, "3"},
+		{"2.9", RoundDown, "2"},
+		{"-2.1", RoundFloor, "-3"},
+		{"-2.9", RoundCeiling, "-2"},
+	}
+
+	for _, tt := range tests {
+		d, ok := ParseDecimal(tt.value)
+		if !ok {
+			t.Fatalf("could not parse %q", tt.value)
+		}
+		if got := d.Round(0, tt.mode).String(); got != tt.expected {
+			t.Errorf("%s rounded %s: expected %s, got %s", tt.value, tt.mode, tt.expected, got)
+		}
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := map[string]string{
+		"19.99": "19.99",
+		"-0.05": "-0.05",
+		"1.50":  "1.50",
+		"1e-3":  "0.001",
+		"2.5E2": "250",
+		".5":    "0.5",
+		"+7":    "7",
+	}
+	for input, expected := range tests {
+		d, ok := ParseDecimal(input)
+		if !ok {
+			t.Errorf("could not parse %q", input)
+			continue
+		}
+		if d.String() != expected {
+			t.Errorf("ParseDecimal(%q): expected %s, got %s", input, expected, d.String())
+		}
+	}
+	for _, bad := range []string{"", "abc", "1.2.3", "1e"} {
+		if _, ok := ParseDecimal(bad); ok {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestSendOnClosedBufferedChannel(t *testing.T) {
+	ch := NewChannel(4)
+	if err := ch.Send(&Integer{Value: 1}); err != nil {
+		t.Fatalf("send on open channel failed: %v", err)
+	}
+	ch.Close()
+	for i := 0; i < 50; i++ {
+		if err := ch.Send(&Integer{Value: 2}); err != ErrChannelClosed {
+			t.Fatalf("attempt %d: expected ErrChannelClosed, got %v", i, err)
+		}
+	}
+	if ch.Len() != 1 {
+		t.Errorf("expected only the value sent before close to be buffered, got %d", ch.Len())
+	}
+}
@@ -0,0 +1,160 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Set is an unordered collection of unique hashable values. Elements are
+// kept in a Hash, so they iterate and print in insertion order and share its
+// collision handling.
+type Set struct {
+	items *Hash
+}
+
+func NewSet() *Set {
+	return &Set{items: NewHash()}
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+func (s *Set) Inspect() string {
+	if s.Len() == 0 {
+		return "set()"
+	}
+	var out bytes.Buffer
+	elems := []string{}
+	for _, elem := range s.Items() {
+		elems = append(elems, elem.Inspect())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(elems, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// Add inserts obj, returning false if it is not hashable. The error is
+// one raised while comparing obj with the elements.
+func (s *Set) Add(obj Object) (bool, Object) {
+	hash, ok := HashKeyOf(obj)
+	if !ok {
+		return false, nil
+	}
+	_, exists, err := s.items.Lookup(hash, obj)
+	if err != nil {
+		return true, err
+	}
+	if !exists {
+		err = s.items.Set(hash, HashPair{Key: obj, Value: obj})
+	}
+	return true, err
+}
+
+// Contains reports whether obj is in the set.
+func (s *Set) Contains(obj Object) (bool, Object) {
+	hash, ok := HashKeyOf(obj)
+	if !ok {
+		return false, nil
+	}
+	_, exists, err := s.items.Lookup(hash, obj)
+	return exists, err
+}
+
+// Remove deletes obj, returning false if it was not present.
+func (s *Set) Remove(obj Object) (bool, Object) {
+	return s.items.Delete(obj)
+}
+
+func (s *Set) Clear() {
+	s.items = NewHash()
+}
+
+func (s *Set) Len() int { return s.items.Len() }
+
+// Items returns the elements in insertion order.
+func (s *Set) Items() []Object {
+	pairs := s.items.Ordered()
+	items := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		items[i] = pair.Key
+	}
+	return items
+}
+
+func (s *Set) Copy() *Set {
+	result := NewSet()
+	for _, elem := range s.Items() {
+		result.Add(elem)
+	}
+	return result
+}
+
+// The set operations below return the first error raised while comparing
+// elements.
+
+func (s *Set) Union(other *Set) (*Set, Object) {
+	result := s.Copy()
+	for _, elem := range other.Items() {
+		if _, err := result.Add(elem); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *Set) Intersection(other *Set) (*Set, Object) {
+	return s.filter(other, true)
+}
+
+func (s *Set) Difference(other *Set) (*Set, Object) {
+	return s.filter(other, false)
+}
+
+// filter returns the elements of s that are, or are not, in other.
+func (s *Set) filter(other *Set, keep bool) (*Set, Object) {
+	result := NewSet()
+	for _, elem := range s.Items() {
+		found, err := other.Contains(elem)
+		if err != nil {
+			return nil, err
+		}
+		if found == keep {
+			result.Add(elem)
+		}
+	}
+	return result, nil
+}
+
+func (s *Set) SymmetricDifference(other *Set) (*Set, Object) {
+	result, err := s.Difference(other)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := other.Difference(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, elem := range rest.Items() {
+		result.Add(elem)
+	}
+	return result, nil
+}
+
+// IsSubset reports whether every element of s is also in other.
+func (s *Set) IsSubset(other *Set) (bool, Object) {
+	if s.Len() > other.Len() {
+		return false, nil
+	}
+	for _, elem := range s.Items() {
+		if found, err := other.Contains(elem); !found || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (s *Set) Equals(other *Set) (bool, Object) {
+	if s.Len() != other.Len() {
+		return false, nil
+	}
+	return s.IsSubset(other)
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/javanhut/TheCarrionLanguage/src/ast"
@@ -42,6 +43,98 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestNumericLiteralForms(t *testing.T) {
+	intTests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF", 255},
+		{"0x1d", 29},
+		{"0o755", 493},
+		{"0755", 493},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0x_dead_beef", 0xdeadbeef},
+	}
+	for _, tt := range intTests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("%s: expected *ast.IntegerLiteral, got %T", tt.input, stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("%s: expected %d, got %d", tt.input, tt.expected, literal.Value)
+		}
+	}
+
+	floatTests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1e-9", 1e-9},
+		{"2.5E3", 2500},
+		{"1e+2", 100},
+		{"1_000.000_5", 1000.0005},
+	}
+	for _, tt := range floatTests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("%s: expected *ast.FloatLiteral, got %T", tt.input, stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("%s: expected %g, got %g", tt.input, tt.expected, literal.Value)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"0xZZ", `at line 1, column 1: invalid digit 'Z' in hexadecimal literal "0xZZ"`},
+		{"0b102", `at line 1, column 1: invalid digit '2' in binary literal "0b102"`},
+		{"0o", `at line 1, column 1: octal literal "0o" has no digits`},
+		{"1__000", `at line 1, column 1: underscores in "1__000" must separate digits`},
+		{"1_.5", `at line 1, column 1: underscores in "1_.5" must separate digits`},
+		{"1e", `at line 1, column 1: exponent in "1e" has no digits`},
+		{"12abc", `at line 1, column 1: invalid character 'a' in numeric literal "12abc"`},
+		{"09", `at line 1, column 1: invalid digit '9' in octal literal "09" (a leading zero means octal)`},
+	}
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestIllegalTokenErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`x = "\u12"`, `illegal token: truncated \u escape: expected 4 hex digits`},
+		{`x = r"open`, "illegal token: unterminated raw string literal"},
+		{"x = 1 → 2", "illegal token: →"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
@@ -349,6 +442,18 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a if b or c else d",
+			"(a if (b or c) else d)",
+		},
+		{
+			"a + 1 if x else b if y else c",
+			"((a + 1) if x else (b if y else c))",
+		},
+		{
+			"add(a if b else c, d)",
+			"add((a if b else c), d)",
+		},
 	}
 
 	for i, tt := range tests {
@@ -831,3 +936,384 @@ resolve:
 			len(attemptStmt.ResolveBlock.Statements))
 	}
 }
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `f = spell(x, y): x + y`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.AssignStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.AssignStatement. got=%T", program.Statements[0])
+	}
+
+	lit, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+
+	if len(lit.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d", len(lit.Parameters))
+	}
+	testLiteralExpression(t, lit.Parameters[0], "x")
+	testLiteralExpression(t, lit.Parameters[1], "y")
+
+	if len(lit.Body.Statements) != 1 {
+		t.Fatalf("function literal body does not contain 1 statement. got=%d", len(lit.Body.Statements))
+	}
+
+	ret, ok := lit.Body.Statements[0].(*ast.ReturnStatement)
+	if !ok {
+		t.Fatalf("body statement is not ast.ReturnStatement. got=%T", lit.Body.Statements[0])
+	}
+	testInfixExpression(t, ret.ReturnValue, "x", "+", "y")
+}
+
+func TestComprehensionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[x * 2 for x in xs if x > 0]", "[(x * 2) for x in xs if (x > 0)]"},
+		{"[x for x in xs for y in ys]", "[x for x in xs for y in ys]"},
+		{"{k: v for k, v in pairs(h)}", "{k: v for (k, v) in pairs(h)}"},
+		{"{x % 3 for x in xs}", "{(x % 3) for x in xs}"},
+		{"{1, 2, 3}", "{1, 2, 3}"},
+		{"{a, b,}", "{a, b}"},
+		{"{1}", "{1}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestDecoratorParsing(t *testing.T) {
+	input := `
+@cache
+@route("GET", "/users")
+spell users():
+    return 1
+`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	fn, ok := program.Statements[0].(*ast.FunctionDefinition)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FunctionDefinition. got=%T", program.Statements[0])
+	}
+	if fn.Name.Value != "users" {
+		t.Errorf("function name wrong. want 'users', got=%q", fn.Name.Value)
+	}
+	if len(fn.Decorators) != 2 {
+		t.Fatalf("expected 2 decorators, got=%d", len(fn.Decorators))
+	}
+	testIdentifier(t, fn.Decorators[0], "cache")
+	if _, ok := fn.Decorators[1].(*ast.CallExpression); !ok {
+		t.Errorf("second decorator is not ast.CallExpression. got=%T", fn.Decorators[1])
+	}
+}
+
+func TestMatchPatternParsing(t *testing.T) {
+	input := `
+match value:
+    case [first, *rest] if first > 0:
+        1
+    case Point(x, _) | None:
+        2
+    case _:
+        3
+`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.MatchStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.MatchStatement. got=%T", program.Statements[0])
+	}
+	if len(stmt.Cases) != 2 {
+		t.Fatalf("expected 2 cases, got=%d", len(stmt.Cases))
+	}
+	if stmt.Default == nil {
+		t.Fatalf("expected a default case")
+	}
+
+	if got := stmt.Cases[0].Condition.String(); got != "[first, *rest]" {
+		t.Errorf("case 0 pattern wrong. got=%q", got)
+	}
+	if stmt.Cases[0].Guard == nil {
+		t.Errorf("case 0 is missing its guard")
+	}
+	if got := stmt.Cases[1].Condition.String(); got != "(Point(x, _) | None)" {
+		t.Errorf("case 1 pattern wrong. got=%q", got)
+	}
+}
+
+func TestGeneratorParsing(t *testing.T) {
+	input := `
+spell gen(n):
+    if n > 0:
+        yield n
+    yield
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fn, ok := program.Statements[0].(*ast.FunctionDefinition)
+	if !ok {
+		t.Fatalf("statement is not *ast.FunctionDefinition. got=%T", program.Statements[0])
+	}
+	if !fn.IsGenerator {
+		t.Errorf("spell containing yield not marked as generator")
+	}
+
+	p = New(lexer.New("yield 1\n"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected error for yield outside of a spell")
+	}
+}
+
+func TestVariadicParameterParsing(t *testing.T) {
+	input := `
+spell f(a, *rest, key=1, **opts):
+    return g(*rest, **opts)
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fn, ok := program.Statements[0].(*ast.FunctionDefinition)
+	if !ok {
+		t.Fatalf("statement is not *ast.FunctionDefinition. got=%T", program.Statements[0])
+	}
+	params := []string{}
+	for _, param := range fn.Parameters {
+		params = append(params, param.String())
+	}
+	if got := strings.Join(params, ", "); got != "a, *rest, key = 1, **opts" {
+		t.Errorf("parameters wrong. got=%q", got)
+	}
+	ret := fn.Body.Statements[0].(*ast.ReturnStatement)
+	if got := ret.ReturnValue.String(); got != "g(*rest, **opts)" {
+		t.Errorf("call wrong. got=%q", got)
+	}
+
+	invalid := []string{
+		"spell f(**opts, a):\n    return a\n",
+		"spell f(*a, *b):\n    return a\n",
+		"spell f(*a = 1):\n    return a\n",
+	}
+	for _, src := range invalid {
+		p := New(lexer.New(src))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parse error for %q", src)
+		}
+	}
+}
+
+func TestSliceExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"xs[1:3]", "(xs[1:3])"},
+		{"xs[:3]", "(xs[:3])"},
+		{"xs[1:]", "(xs[1:])"},
+		{"xs[:]", "(xs[:])"},
+		{"xs[::2]", "(xs[::2])"},
+		{"xs[::-1]", "(xs[::(-1)])"},
+		{"xs[1:-1:2]", "(xs[1:(-1):2])"},
+		{"xs[a + 1::b]", "(xs[(a + 1)::b])"},
+		{"xs[1::]", "(xs[1:])"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestEnumMemberParsing(t *testing.T) {
+	input := "grim Color(Enum):\n    RED\n    GREEN = 5\n\n    spell label():\n        return self.name\n"
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+	}
+	grim, ok := program.Statements[0].(*ast.GrimoireDefinition)
+	if !ok {
+		t.Fatalf("expected *ast.GrimoireDefinition, got %T", program.Statements[0])
+	}
+	if len(grim.Members) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(grim.Members))
+	}
+	if grim.Members[0].Name.Value != "RED" || grim.Members[0].Value != nil {
+		t.Errorf("unexpected first member: %s", grim.Members[0].String())
+	}
+	if got := grim.Members[1].String(); got != "GREEN = 5" {
+		t.Errorf("expected second member %q, got %q", "GREEN = 5", got)
+	}
+	if len(grim.Methods) != 1 || grim.Methods[0].Name.Value != "label" {
+		t.Errorf("expected method label, got %d methods", len(grim.Methods))
+	}
+}
+
+func TestSelectStatementParsing(t *testing.T) {
+	input := `select:
+    case v = inbox.receive():
+        print(v)
+    case outbox.send(1): print("sent")
+    case timeout(0.5):
+        print("slow")
+    case _:
+        print("idle")
+`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.SelectStatement)
+	if !ok {
+		t.Fatalf("expected *ast.SelectStatement, got %T", program.Statements[0])
+	}
+	if len(stmt.Cases) != 3 {
+		t.Fatalf("expected 3 cases, got %d", len(stmt.Cases))
+	}
+	kinds := []string{ast.SelectReceive, ast.SelectSend, ast.SelectTimeout}
+	for i, kind := range kinds {
+		if stmt.Cases[i].Kind != kind {
+			t.Errorf("case %d: expected kind %s, got %s", i, kind, stmt.Cases[i].Kind)
+		}
+	}
+	if stmt.Cases[0].Target.String() != "v" || stmt.Cases[0].Channel.String() != "inbox" {
+		t.Errorf("unexpected receive case: %s", stmt.Cases[0].String())
+	}
+	if stmt.Default == nil {
+		t.Errorf("expected a default case")
+	}
+
+	for _, bad := range []string{
+		"select:\n    case print(1):\n        ignore\n",
+		"select:\n    case x = ch.send(1):\n        ignore\n",
+		"select:\n    case a.b = ch.receive():\n        ignore\n",
+	} {
+		p := New(lexer.New(bad))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parse error for %q", bad)
+		}
+	}
+}
+
+func TestWithStatementParsing(t *testing.T) {
+	tests := []struct {
+		input      string
+		expression string
+		variable   string
+	}{
+		{"autoclose open(\"a.txt\") as f:\n    f.read()\n", "open(a.txt)", "f"},
+		{"autoclose self.lock.lock():\n    x = 1\n", "((self.lock).lock)()", ""},
+		{"autoclose locks[0].lock() as guard:\n    x = 1\n", "((locks[0]).lock)()", "guard"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.WithStatement)
+		if !ok {
+			t.Fatalf("expected *ast.WithStatement, got %T", program.Statements[0])
+		}
+		if got := stmt.Expression.String(); got != tt.expression {
+			t.Errorf("expected expression %q, got %q", tt.expression, got)
+		}
+		variable := ""
+		if stmt.Variable != nil {
+			variable = stmt.Variable.Value
+		}
+		if variable != tt.variable {
+			t.Errorf("expected variable %q, got %q", tt.variable, variable)
+		}
+	}
+}
+
+func TestConvergeParsing(t *testing.T) {
+	tests := []struct {
+		input        string
+		expected     string
+		asExpression bool
+	}{
+		{"converge\n", "converge", false},
+		{"converge timeout 2\n", "converge timeout 2", false},
+		{"converge a, b timeout 0.5\n", "converge a, b timeout 0.5", false},
+		{"converge a timeout limit * 2\n", "converge a timeout (limit * 2)", false},
+		{"x = converge a\n", "converge a", true},
+		{"print(converge a, b timeout 1)\n", "converge a, b timeout 1", true},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		var converge *ast.ConvergeStatement
+		switch stmt := program.Statements[0].(type) {
+		case *ast.ConvergeStatement:
+			converge = stmt
+		case *ast.AssignStatement:
+			converge, _ = stmt.Value.(*ast.ConvergeStatement)
+		case *ast.ExpressionStatement:
+			if call, ok := stmt.Expression.(*ast.CallExpression); ok && len(call.Arguments) == 1 {
+				converge, _ = call.Arguments[0].(*ast.ConvergeStatement)
+			}
+		}
+		if converge == nil {
+			t.Fatalf("input %q: no converge found in %s", tt.input, program.String())
+		}
+		if got := converge.String(); got != tt.expected {
+			t.Errorf("input %q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+		if converge.AsExpression != tt.asExpression {
+			t.Errorf("input %q: expected AsExpression %v", tt.input, tt.asExpression)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -14,6 +15,7 @@ const (
 	_      int = iota
 	LOWEST int = iota
 	ASSIGN
+	CONDITIONAL
 	LOGICAL_OR
 	LOGICAL_AND
 	EQUALS
@@ -56,11 +58,13 @@ var precedences = map[token.TokenType]int{
 	token.IN:              COMPARSION,
 	token.NOT_IN:          COMPARSION,
 
-	token.LSHIFT:    6,
-	token.RSHIFT:    6,
-	token.AMPERSAND: 5,
-	token.XOR:       4,
-	token.PIPE:      3,
+	token.IF: CONDITIONAL,
+
+	token.LSHIFT:    7,
+	token.RSHIFT:    7,
+	token.AMPERSAND: 6,
+	token.XOR:       5,
+	token.PIPE:      4,
 	token.UNPACK:    ASSIGN, // Same precedence as assignment
 }
 
@@ -78,6 +82,8 @@ type Parser struct {
 	contextStack      []string
 	indentStack       []int
 	parsingParameters bool
+	parsingPattern    bool
+	generatorScopes   []bool // one entry per spell body being parsed; true once it yields
 	inTypeHintContext bool
 	prefixParseFns    map[token.TokenType]prefixParseFn
 	infixParseFns     map[token.TokenType]infixParseFn
@@ -122,6 +128,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
 	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.DECIMAL, p.parseDecimalLiteral)
+	p.registerPrefix(token.BYTES, p.parseBytesLiteral)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.NOT, p.parsePrefixExpression)
@@ -132,7 +140,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.COLON, func() ast.Expression { return nil })
 	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
-	p.registerPrefix(token.UNDERSCORE, func() ast.Expression { return nil })
+	p.registerPrefix(token.UNDERSCORE, p.parseUnderscore)
+	p.registerPrefix(token.ASTERISK, p.parseStarPattern)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACK, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
@@ -145,14 +154,15 @@ func New(l *lexer.Lexer) *Parser {
 	// OTHERWISE is handled as part of if-statement parsing, not as a prefix expression
 	p.registerPrefix(token.ENSNARE, func() ast.Expression { return nil })
 	p.registerPrefix(token.AS, func() ast.Expression { return nil })
-	p.registerPrefix(token.AT, func() ast.Expression { return nil })
 	p.registerPrefix(token.ARCANESPELL, func() ast.Expression { return nil })
 	p.registerPrefix(token.LPAREN, p.parseParenExpression)
 	p.registerPrefix(token.SELF, p.parseSelf)
+	p.registerPrefix(token.CONVERGE, p.parseConvergeExpression)
 	p.registerPrefix(token.SUPER, p.parseSuperExpression)
 	p.registerPrefix(token.FSTRING, p.parseFStringLiteral)
 	p.registerPrefix(token.INTERP, p.parseStringInterpolationLiteral)
 	p.registerPrefix(token.DOCSTRING, p.parseDocStringLiteral)
+	p.registerPrefix(token.SPELL, p.parseFunctionLiteral)
 	p.registerPrefix(token.INIT, func() ast.Expression {
 		return &ast.Identifier{
 			Token: token.Token{Type: token.INIT, Literal: "init"},
@@ -190,6 +200,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.PIPE, p.parseInfixExpression)
 	p.registerInfix(token.IN, p.parseInfixExpression)
 	p.registerInfix(token.NOT_IN, p.parseInfixExpression)
+	p.registerInfix(token.IF, p.parseConditionalExpression)
 
 	p.registerPostfix(token.PLUS_INCREMENT, p.parsePostfixExpression)
 	p.registerPostfix(token.MINUS_DECREMENT, p.parsePostfixExpression)
@@ -210,9 +221,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerStatement(token.SKIP, p.parseSkipStatement)
 	p.registerStatement(token.DIVERGE, p.parseDivergeStatement)
 	p.registerStatement(token.CONVERGE, p.parseConvergeStatement)
+	p.registerStatement(token.SELECT, p.parseSelectStatement)
 	p.registerStatement(token.CHECK, p.parseCheckStatement)
 	p.registerStatement(token.GLOBAL, p.parseGlobalStatement)
 	p.registerStatement(token.AUTOCLOSE, p.parseWithStatement)
+	p.registerStatement(token.YIELD, p.parseYieldStatement)
 
 	return p
 }
@@ -301,7 +314,7 @@ func (p *Parser) isAtEnd() bool {
 
 func (p *Parser) canStartExpression(tokenType token.TokenType) bool {
 	switch tokenType {
-	case token.IDENT, token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.NONE,
+	case token.IDENT, token.INT, token.FLOAT, token.DECIMAL, token.STRING, token.BYTES, token.TRUE, token.FALSE, token.NONE,
 		token.LPAREN, token.LBRACK, token.LBRACE, token.SPELL, token.IF, token.SELF:
 		return true
 	default:
@@ -388,10 +401,28 @@ func (p *Parser) parseDivergeStatement() ast.Statement {
 }
 
 func (p *Parser) parseConvergeStatement() ast.Statement {
+	if stmt := p.parseConverge(); stmt != nil {
+		return stmt
+	}
+	return nil
+}
+
+// parseConvergeExpression parses converge on the right-hand side of an
+// assignment or as an argument, where it evaluates to the goroutines' results.
+func (p *Parser) parseConvergeExpression() ast.Expression {
+	stmt := p.parseConverge()
+	if stmt == nil {
+		return nil
+	}
+	stmt.AsExpression = true
+	return stmt
+}
+
+func (p *Parser) parseConverge() *ast.ConvergeStatement {
 	stmt := &ast.ConvergeStatement{Token: p.currToken}
 
 	// Check if there are names to wait for
-	if p.peekTokenIs(token.IDENT) {
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal != "timeout" {
 		p.nextToken()
 		stmt.Names = append(stmt.Names, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
 
@@ -408,24 +439,146 @@ func (p *Parser) parseConvergeStatement() ast.Statement {
 	// Check for optional timeout keyword
 	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "timeout" {
 		p.nextToken() // consume "timeout" identifier
+		p.nextToken()
 
-		// Parse the timeout value expression
-		if !p.expectPeek(token.INT) {
+		// The timeout is a number of seconds, checked when evaluated
+		stmt.Timeout = p.parseExpression(LOWEST)
+		if stmt.Timeout == nil {
 			return nil
 		}
+	}
 
-		// Convert string to int64
-		timeoutValue, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
-		if err != nil {
-			return nil
+	return stmt
+}
+
+func (p *Parser) parseSelectStatement() ast.Statement {
+	currentIndent := p.getCurrentIndent()
+	p.controlStack = append(p.controlStack, struct {
+		Type        string
+		IndentLevel int
+		HasElse     bool
+		Token       token.Token
+	}{
+		Type:        "select",
+		IndentLevel: currentIndent,
+		HasElse:     false,
+		Token:       p.currToken,
+	})
+	defer func() {
+		p.controlStack = p.controlStack[:len(p.controlStack)-1]
+	}()
+
+	stmt := &ast.SelectStatement{Token: p.currToken}
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.skipNewlines()
+	if p.peekTokenIs(token.INDENT) {
+		p.nextToken()
+	}
+
+	for {
+		p.skipNewlines()
+		if !p.peekTokenIs(token.CASE) {
+			break
 		}
+		p.nextToken()
+		selectCase := &ast.SelectCase{Token: p.currToken}
+		p.nextToken()
 
-		stmt.Timeout = &ast.IntegerLiteral{Token: p.currToken, Value: timeoutValue}
+		if p.currTokenIs(token.UNDERSCORE) && p.peekTokenIs(token.COLON) {
+			if stmt.Default != nil {
+				p.addError("select has more than one default case")
+				return nil
+			}
+			p.nextToken()
+			stmt.Default = p.parseCaseBody()
+			continue
+		}
+
+		if !p.parseSelectOperation(selectCase) {
+			return nil
+		}
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		selectCase.Body = p.parseCaseBody()
+		stmt.Cases = append(stmt.Cases, selectCase)
 	}
 
+	if len(stmt.Cases) == 0 && stmt.Default == nil {
+		p.addErrorWithToken("select needs at least one case", stmt.Token)
+		return nil
+	}
 	return stmt
 }
 
+// parseSelectOperation reads the channel operation of a select case:
+// `ch.receive()`, `target = ch.receive()`, `ch.send(value)` or
+// `timeout(seconds)`.
+func (p *Parser) parseSelectOperation(sc *ast.SelectCase) bool {
+	expr := p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.ASSIGN) {
+		if _, ok := expr.(*ast.Identifier); !ok {
+			p.addErrorWithToken("select can only bind a received value to a name", sc.Token)
+			return false
+		}
+		sc.Target = expr
+		p.nextToken()
+		p.nextToken()
+		expr = p.parseExpression(LOWEST)
+	}
+
+	if call, ok := expr.(*ast.CallExpression); ok {
+		switch fn := call.Function.(type) {
+		case *ast.DotExpression:
+			switch {
+			case fn.Right.Value == "receive" && len(call.Arguments) == 0:
+				sc.Kind = ast.SelectReceive
+				sc.Channel = fn.Left
+				return true
+			case fn.Right.Value == "send" && len(call.Arguments) == 1 && sc.Target == nil:
+				sc.Kind = ast.SelectSend
+				sc.Channel = fn.Left
+				sc.Value = call.Arguments[0]
+				return true
+			}
+		case *ast.Identifier:
+			if fn.Value == "timeout" && len(call.Arguments) == 1 && sc.Target == nil {
+				sc.Kind = ast.SelectTimeout
+				sc.Value = call.Arguments[0]
+				return true
+			}
+		}
+	}
+	p.addErrorWithToken(
+		"select case must be ch.receive(), name = ch.receive(), ch.send(value) or timeout(seconds)",
+		sc.Token)
+	return false
+}
+
+// parseCaseBody parses the body after the colon of a case, either an
+// indented block or a single statement on the same line.
+func (p *Parser) parseCaseBody() *ast.BlockStatement {
+	if p.peekTokenIs(token.NEWLINE) {
+		p.nextToken()
+		if p.peekTokenIs(token.INDENT) {
+			p.nextToken()
+			return p.parseBlockStatement()
+		}
+		return &ast.BlockStatement{
+			Token:      p.currToken,
+			Statements: []ast.Statement{p.parseStatement()},
+		}
+	}
+	p.nextToken()
+	return &ast.BlockStatement{
+		Token:      p.currToken,
+		Statements: []ast.Statement{p.parseStatement()},
+	}
+}
+
 func (p *Parser) parseCheckStatement() ast.Statement {
 	stmt := &ast.CheckStatement{Token: p.currToken}
 
@@ -489,9 +642,15 @@ func (p *Parser) parseFStringLiteral() ast.Expression {
 				return fslit
 			}
 			exprStr := raw[i+1 : end]
+			part := &ast.FStringExpr{}
+			if colon := findFormatSpecColon(exprStr); colon >= 0 {
+				part.Format = &ast.StringExpr{}
+				parseFormatSpec(part.Format, exprStr[colon+1:])
+				exprStr = exprStr[:colon]
+			}
 
-			expr := p.parseFStringExpression(exprStr)
-			fslit.Parts = append(fslit.Parts, &ast.FStringExpr{Expr: expr})
+			part.Expr = p.parseFStringExpression(exprStr)
+			fslit.Parts = append(fslit.Parts, part)
 
 			i = end + 1
 		} else {
@@ -516,6 +675,31 @@ func findMatchingBrace(s string, start int) int {
 	return -1
 }
 
+// findFormatSpecColon returns the index of the ':' that starts a format
+// specifier, ignoring colons inside brackets and string literals.
+func findFormatSpecColon(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			depth--
+		case ch == ':' && depth == 0:
+			return i
+		}
+	}
+	return -1
+}
+
 func (p *Parser) parseFStringExpression(exprStr string) ast.Expression {
 	l := lexer.New(exprStr)
 	subParser := New(l)
@@ -817,7 +1001,7 @@ func (p *Parser) parseMatchStatement() ast.Statement {
 		p.nextToken()
 
 		// Check if this is a wildcard case (case _:)
-		if p.currTokenIs(token.UNDERSCORE) {
+		if p.currTokenIs(token.UNDERSCORE) && p.peekTokenIs(token.COLON) {
 			// This is a wildcard case, treat it as default
 			caseClause.Condition = &ast.WildcardExpression{Token: p.currToken}
 
@@ -849,7 +1033,16 @@ func (p *Parser) parseMatchStatement() ast.Statement {
 			continue
 		}
 
-		caseClause.Condition = p.parseExpression(LOWEST)
+		caseClause.Condition = p.parsePattern()
+		if caseClause.Condition == nil {
+			return nil
+		}
+
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			caseClause.Guard = p.parseExpression(LOWEST)
+		}
 
 		if !p.expectPeek(token.COLON) {
 			return nil
@@ -920,6 +1113,39 @@ func (p *Parser) parseMatchStatement() ast.Statement {
 	return stmt
 }
 
+// parsePattern parses a case pattern. Patterns reuse the expression grammar
+// with two additions: `_` is a wildcard and `*name` captures the rest of a
+// sequence. Parsing stops before a trailing `if` so it can be read as a guard.
+func (p *Parser) parsePattern() ast.Expression {
+	p.parsingPattern = true
+	defer func() { p.parsingPattern = false }()
+	return p.parseExpression(CONDITIONAL)
+}
+
+func (p *Parser) parseUnderscore() ast.Expression {
+	if !p.parsingPattern {
+		return nil
+	}
+	return &ast.WildcardExpression{Token: p.currToken}
+}
+
+func (p *Parser) parseStarPattern() ast.Expression {
+	if !p.parsingPattern {
+		p.noPrefixParseFnError(p.currToken.Type)
+		return nil
+	}
+	rest := &ast.RestPattern{Token: p.currToken}
+	if p.peekTokenIs(token.UNDERSCORE) {
+		p.nextToken()
+		return rest
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	rest.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	return rest
+}
+
 func (p *Parser) skipNewlines() {
 	for p.peekTokenIs(token.NEWLINE) {
 		p.nextToken()
@@ -987,6 +1213,9 @@ func (p *Parser) parseParenExpression() ast.Expression {
 
 		for p.peekTokenIs(token.COMMA) {
 			p.nextToken()
+			if p.peekTokenIs(token.RPAREN) {
+				break // trailing comma, as in the one-element tuple (x,)
+			}
 			p.nextToken()
 			nextExpr := p.parseExpression(LOWEST)
 			if nextExpr != nil {
@@ -1050,6 +1279,11 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 			p.infixParseFns[token.COMMA] = commaFn
 		}
 
+		// `{a, b}` and `{x for ...}` are sets rather than hashes
+		if len(hash.Pairs) == 0 && !p.peekTokenIs(token.COLON) {
+			return p.parseSetLiteral(hash.Token, key)
+		}
+
 		if !p.expectPeek(token.COLON) {
 			return nil
 		}
@@ -1063,7 +1297,13 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 			p.infixParseFns[token.COMMA] = commaFn
 		}
 
+		// `{k: v for ...}` turns the literal into a comprehension
+		if len(hash.Pairs) == 0 && p.peekTokenIs(token.FOR) {
+			return p.parseHashComprehension(hash.Token, key, value)
+		}
+
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		// Check what comes after the value
 		if p.peekTokenIs(token.COMMA) {
@@ -1085,6 +1325,56 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	return hash
 }
 
+// parseSetLiteral parses the rest of a set literal or set comprehension
+// whose first element has already been parsed.
+func (p *Parser) parseSetLiteral(tok token.Token, first ast.Expression) ast.Expression {
+	if p.peekTokenIs(token.FOR) {
+		comp := &ast.SetComprehension{Token: tok, Element: first}
+		comp.Clauses = p.parseComprehensionClauses()
+		if comp.Clauses == nil {
+			return nil
+		}
+		p.skipNewlines()
+		if !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+		return comp
+	}
+
+	set := &ast.SetLiteral{Token: tok, Elements: []ast.Expression{first}}
+	commaFn := p.infixParseFns[token.COMMA]
+	delete(p.infixParseFns, token.COMMA)
+	defer func() {
+		if commaFn != nil {
+			p.infixParseFns[token.COMMA] = commaFn
+		}
+	}()
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.skipNewlines()
+		if p.peekTokenIs(token.DEDENT) {
+			p.nextToken()
+			p.skipNewlines()
+		}
+		if p.peekTokenIs(token.RBRACE) {
+			break
+		}
+		p.nextToken()
+		set.Elements = append(set.Elements, p.parseExpression(LOWEST))
+	}
+
+	p.skipNewlines()
+	if p.peekTokenIs(token.DEDENT) {
+		p.nextToken()
+		p.skipNewlines()
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return set
+}
+
 func (p *Parser) parseTupleLiteral() ast.Expression {
 	tuple := &ast.TupleLiteral{Token: p.currToken}
 	tuple.Elements = p.parseExpressionList(token.RPAREN)
@@ -1093,7 +1383,11 @@ func (p *Parser) parseTupleLiteral() ast.Expression {
 
 func (p *Parser) parseFloatLiteral() ast.Expression {
 	lit := &ast.FloatLiteral{Token: p.currToken}
-	value, err := strconv.ParseFloat(p.currToken.Literal, 64)
+	if msg := checkNumberLiteral(p.currToken.Literal, false); msg != "" {
+		p.addErrorWithToken(msg, p.currToken)
+		return nil
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(p.currToken.Literal, "_", ""), 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as float", p.currToken.Literal)
 		p.errors = append(p.errors, msg)
@@ -1103,51 +1397,53 @@ func (p *Parser) parseFloatLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseDecimalLiteral() ast.Expression {
+	if msg := checkNumberLiteral(p.currToken.Literal, false); msg != "" {
+		p.addErrorWithToken(msg, p.currToken)
+		return nil
+	}
+	return &ast.DecimalLiteral{Token: p.currToken, Value: strings.ReplaceAll(p.currToken.Literal, "_", "")}
+}
+
+func (p *Parser) parseBytesLiteral() ast.Expression {
+	return &ast.BytesLiteral{Token: p.currToken, Value: []byte(p.currToken.Literal)}
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	tok := p.currToken
 	p.nextToken()
 
-	// Check if this is a slice expression by looking for colon
-	if p.currTokenIs(token.COLON) {
-		// This is a slice starting with :
-		sliceExp := &ast.SliceExpression{Token: tok, Left: left, Start: nil}
-		p.nextToken()
-		if !p.currTokenIs(token.RBRACK) {
-			sliceExp.End = p.parseExpression(LOWEST)
-		}
-		if !p.currTokenIs(token.RBRACK) {
+	// A slice may omit its start, as in xs[:2] and xs[::-1]
+	var start ast.Expression
+	if !p.currTokenIs(token.COLON) {
+		start = p.parseExpression(LOWEST)
+		if !p.peekTokenIs(token.COLON) {
+			// Regular index expression
+			exp := &ast.IndexExpression{Token: tok, Left: left, Index: start}
 			if !p.expectPeek(token.RBRACK) {
 				return nil
 			}
+			return exp
 		}
-		return sliceExp
+		p.nextToken() // move onto the colon
 	}
 
-	// Parse the first expression
-	firstExp := p.parseExpression(LOWEST)
-
-	// Check if there's a colon after the first expression (slice)
+	sliceExp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+	if !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.RBRACK) {
+		p.nextToken()
+		sliceExp.End = p.parseExpression(LOWEST)
+	}
 	if p.peekTokenIs(token.COLON) {
-		sliceExp := &ast.SliceExpression{Token: tok, Left: left, Start: firstExp}
-		p.nextToken() // consume the colon
-		p.nextToken() // move to next token after colon
-		if !p.currTokenIs(token.RBRACK) {
-			sliceExp.End = p.parseExpression(LOWEST)
-		}
-		if !p.currTokenIs(token.RBRACK) {
-			if !p.expectPeek(token.RBRACK) {
-				return nil
-			}
+		p.nextToken()
+		if !p.peekTokenIs(token.RBRACK) {
+			p.nextToken()
+			sliceExp.Step = p.parseExpression(LOWEST)
 		}
-		return sliceExp
 	}
-
-	// Regular index expression
-	exp := &ast.IndexExpression{Token: tok, Left: left, Index: firstExp}
 	if !p.expectPeek(token.RBRACK) {
 		return nil
 	}
-	return exp
+	return sliceExp
 }
 
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
@@ -1160,6 +1456,9 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list = append(list, p.parseExpression(LOWEST))
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
+		if p.peekTokenIs(end) {
+			break // trailing comma
+		}
 		p.nextToken()
 		list = append(list, p.parseExpression(LOWEST))
 	}
@@ -1171,10 +1470,121 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.currToken}
-	array.Elements = p.parseExpressionList(token.RBRACK)
+	if p.peekTokenIs(token.RBRACK) {
+		p.nextToken()
+		array.Elements = []ast.Expression{}
+		return array
+	}
+
+	p.nextToken()
+	first := p.parseExpression(LOWEST)
+
+	// `[expr for ...]` is a list comprehension
+	if p.peekTokenIs(token.FOR) {
+		comp := &ast.ListComprehension{Token: array.Token, Element: first}
+		comp.Clauses = p.parseComprehensionClauses()
+		if comp.Clauses == nil || !p.expectPeek(token.RBRACK) {
+			return nil
+		}
+		return comp
+	}
+
+	array.Elements = []ast.Expression{first}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if p.peekTokenIs(token.RBRACK) {
+			break // trailing comma
+		}
+		p.nextToken()
+		array.Elements = append(array.Elements, p.parseExpression(LOWEST))
+	}
+	if !p.expectPeek(token.RBRACK) {
+		return nil
+	}
 	return array
 }
 
+func (p *Parser) parseHashComprehension(tok token.Token, key, value ast.Expression) ast.Expression {
+	comp := &ast.HashComprehension{Token: tok, Key: key, Value: value}
+	comp.Clauses = p.parseComprehensionClauses()
+	if comp.Clauses == nil {
+		return nil
+	}
+	p.skipNewlines()
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return comp
+}
+
+// parseComprehensionClauses parses one or more `for target in iterable`
+// clauses, each optionally followed by `if` filters. The current token is
+// the last token of the element expression.
+func (p *Parser) parseComprehensionClauses() []*ast.ComprehensionClause {
+	clauses := []*ast.ComprehensionClause{}
+
+	for p.peekTokenIs(token.FOR) {
+		p.nextToken()
+		clause := &ast.ComprehensionClause{Token: p.currToken}
+
+		clause.Variable = p.parseComprehensionTarget()
+		if clause.Variable == nil {
+			return nil
+		}
+
+		if !p.expectPeek(token.IN) {
+			return nil
+		}
+		// Parse above CONDITIONAL so a trailing `if` is read as a filter
+		p.nextToken()
+		clause.Iterable = p.parseExpression(CONDITIONAL)
+		if clause.Iterable == nil {
+			return nil
+		}
+
+		for p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			cond := p.parseExpression(CONDITIONAL)
+			if cond == nil {
+				return nil
+			}
+			clause.Conditions = append(clause.Conditions, cond)
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	return clauses
+}
+
+// parseComprehensionTarget parses the loop variable(s) of a comprehension
+// clause, mirroring the targets accepted by a for statement.
+func (p *Parser) parseComprehensionTarget() ast.Expression {
+	if !p.peekTokenIs(token.IDENT) && !p.peekTokenIs(token.DOTDOT) {
+		p.peekError(token.IDENT)
+		return nil
+	}
+	p.nextToken()
+	first := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.peekTokenIs(token.COMMA) {
+		return first
+	}
+
+	variables := []ast.Expression{first}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.peekTokenIs(token.IDENT) && !p.peekTokenIs(token.DOTDOT) {
+			p.peekError(token.IDENT)
+			return nil
+		}
+		p.nextToken()
+		variables = append(variables, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+	}
+	return &ast.TupleLiteral{Token: first.Token, Elements: variables}
+}
+
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
@@ -1283,8 +1693,13 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseWhileStatement()
 	case token.GRIMOIRE:
 		return p.parseGrimoireDefinition()
+	case token.AT:
+		return p.parseDecoratedDefinition()
 	case token.SPELL, token.INIT:
-		return p.parseFunctionDefinition()
+		// `spell(` starts an anonymous spell expression, not a definition
+		if !(p.currTokenIs(token.SPELL) && p.peekTokenIs(token.LPAREN)) {
+			return p.parseFunctionDefinition()
+		}
 	case token.FOR:
 		return p.parseForStatement()
 	case token.RETURN:
@@ -1313,12 +1728,16 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseDivergeStatement()
 	case token.CONVERGE:
 		return p.parseConvergeStatement()
+	case token.SELECT:
+		return p.parseSelectStatement()
 	case token.CHECK:
 		return p.parseCheckStatement()
 	case token.GLOBAL:
 		return p.parseGlobalStatement()
 	case token.AUTOCLOSE:
 		return p.parseWithStatement()
+	case token.YIELD:
+		return p.parseYieldStatement()
 	}
 	leftExpr := p.parseAssignmentLHS()
 	if leftExpr == nil {
@@ -1520,7 +1939,9 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	}
 
 	leftExp := prefix()
-	for !p.peekTokenIs(token.NEWLINE) &&
+	// A block-bodied expression (e.g. an anonymous spell) ends at its DEDENT
+	for !p.currTokenIs(token.DEDENT) &&
+		!p.peekTokenIs(token.NEWLINE) &&
 		!p.peekTokenIs(token.SEMICOLON) &&
 		!p.peekTokenIs(token.EOF) &&
 		!p.peekTokenIs(token.COMMA) &&
@@ -1557,6 +1978,11 @@ func (p *Parser) currPrecedence() int {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
+	// The lexer describes malformed literals in the ILLEGAL token itself
+	if t == token.ILLEGAL && p.currToken.Literal != "" {
+		p.errors = append(p.errors, fmt.Sprintf("illegal token: %s", p.currToken.Literal))
+		return
+	}
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
 	p.errors = append(p.errors, msg)
 }
@@ -1570,9 +1996,20 @@ func (p *Parser) parseIdentifier() ast.Expression {
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.currToken}
+	if msg := checkNumberLiteral(p.currToken.Literal, true); msg != "" {
+		p.addErrorWithToken(msg, p.currToken)
+		return nil
+	}
 
-	value, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
+	// Base 0 understands the 0x, 0o and 0b prefixes as well as a plain
+	// leading zero, which still means octal for compatibility: 0755.
+	literal := strings.ReplaceAll(p.currToken.Literal, "_", "")
+	value, err := strconv.ParseInt(literal, 0, 64)
 	if err != nil {
+		if bigValue, ok := new(big.Int).SetString(literal, 0); ok {
+			lit.Big = bigValue
+			return lit
+		}
 		msg := fmt.Sprintf("could not parse %q as integer", p.currToken.Literal)
 		p.errors = append(p.errors, msg)
 		return nil
@@ -1582,6 +2019,93 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+// checkNumberLiteral describes what is wrong with a malformed numeric
+// literal, or returns "" if lit is well formed. Underscores may only
+// separate digits, or follow a base prefix. allowLegacyOctal accepts a
+// leading zero as octal, as integers do.
+func checkNumberLiteral(lit string, allowLegacyOctal bool) string {
+	if len(lit) > 1 && lit[0] == '0' {
+		base, name := 0, ""
+		switch lit[1] {
+		case 'x', 'X':
+			base, name = 16, "hexadecimal"
+		case 'o', 'O':
+			base, name = 8, "octal"
+		case 'b', 'B':
+			base, name = 2, "binary"
+		}
+		if base != 0 {
+			digits := lit[2:]
+			if strings.Trim(digits, "_") == "" {
+				return fmt.Sprintf("%s literal %q has no digits", name, lit)
+			}
+			for _, ch := range digits {
+				if ch != '_' && digitValue(ch) >= base {
+					return fmt.Sprintf("invalid digit %q in %s literal %q", ch, name, lit)
+				}
+			}
+			if strings.Contains(digits, "__") || strings.HasSuffix(digits, "_") {
+				return fmt.Sprintf("underscores in %q must separate digits", lit)
+			}
+			return ""
+		}
+	}
+
+	mantissa, exponent, hasExponent := lit, "", false
+	if i := strings.IndexAny(lit, "eE"); i >= 0 {
+		mantissa, exponent, hasExponent = lit[:i], lit[i+1:], true
+	}
+	for _, ch := range mantissa {
+		if ch != '_' && ch != '.' && (ch < '0' || ch > '9') {
+			return fmt.Sprintf("invalid character %q in numeric literal %q", ch, lit)
+		}
+	}
+	if hasExponent {
+		exponent = strings.TrimLeft(exponent, "+-")
+		if strings.Trim(exponent, "_") == "" {
+			return fmt.Sprintf("exponent in %q has no digits", lit)
+		}
+		for _, ch := range exponent {
+			if ch != '_' && (ch < '0' || ch > '9') {
+				return fmt.Sprintf("invalid character %q in exponent of %q", ch, lit)
+			}
+		}
+	}
+	for _, part := range []string{mantissa, exponent} {
+		for i := 0; i < len(part); i++ {
+			if part[i] == '_' && (i == 0 || i == len(part)-1 || !isASCIIDigit(part[i-1]) || !isASCIIDigit(part[i+1])) {
+				return fmt.Sprintf("underscores in %q must separate digits", lit)
+			}
+		}
+	}
+
+	if allowLegacyOctal && len(mantissa) > 1 && mantissa[0] == '0' && !hasExponent && !strings.Contains(mantissa, ".") {
+		for _, ch := range mantissa {
+			if ch > '7' {
+				return fmt.Sprintf("invalid digit %q in octal literal %q (a leading zero means octal)", ch, lit)
+			}
+		}
+	}
+	return ""
+}
+
+// digitValue returns the value of ch as a digit in bases up to 36.
+func digitValue(ch rune) int {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return int(ch - '0')
+	case ch >= 'a' && ch <= 'z':
+		return int(ch-'a') + 10
+	case ch >= 'A' && ch <= 'Z':
+		return int(ch-'A') + 10
+	}
+	return 36
+}
+
+func isASCIIDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.currToken,
@@ -1612,6 +2136,24 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// parseConditionalExpression parses `consequence if condition else alternative`.
+// It binds looser than `or`, and chains to the right so that
+// `a if x else b if y else c` reads as `a if x else (b if y else c)`.
+func (p *Parser) parseConditionalExpression(consequence ast.Expression) ast.Expression {
+	expr := &ast.ConditionalExpression{Token: p.currToken, Consequence: consequence}
+
+	p.nextToken()
+	expr.Condition = p.parseExpression(CONDITIONAL)
+
+	if !p.expectPeek(token.ELSE) {
+		return nil
+	}
+	p.nextToken()
+	expr.Alternative = p.parseExpression(ASSIGN)
+
+	return expr
+}
+
 func (p *Parser) parsePostfixExpression(left ast.Expression) ast.Expression {
 	return &ast.PostfixExpression{
 		Token:    p.currToken,
@@ -1841,6 +2383,9 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
+		if p.peekTokenIs(token.RPAREN) {
+			break // trailing comma
+		}
 		p.nextToken()
 		args = append(args, p.parseCallArgument())
 	}
@@ -1854,8 +2399,18 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 
 // parseCallArgument parses a single call argument, which can be either:
 // - A named argument: `name=value`
+// - A spread argument: `*iterable` or `**hash`
 // - A positional argument: any expression
 func (p *Parser) parseCallArgument() ast.Expression {
+	if p.currTokenIs(token.ASTERISK) || p.currTokenIs(token.EXPONENT) {
+		spread := &ast.SpreadArgument{
+			Token:   p.currToken,
+			Keyword: p.currTokenIs(token.EXPONENT),
+		}
+		p.nextToken()
+		spread.Value = p.parseExpression(LOWEST)
+		return spread
+	}
 	// Check: IDENT followed by ASSIGN = named argument
 	if p.currTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
 		nameToken := p.currToken
@@ -2022,6 +2577,8 @@ func (p *Parser) parseFunctionDefinition() ast.Statement {
 	// Keep track of the line where the colon is (before advancing)
 	colonLine := p.currToken.Line
 
+	p.generatorScopes = append(p.generatorScopes, false)
+
 	p.nextToken()
 
 	// Parse function body - handle both single-line and multi-line functions
@@ -2072,6 +2629,8 @@ func (p *Parser) parseFunctionDefinition() ast.Statement {
 		}
 	}
 
+	stmt.IsGenerator = p.popGeneratorScope()
+
 	if len(stmt.Body.Statements) > 0 {
 		if exprStmt, ok := stmt.Body.Statements[0].(*ast.ExpressionStatement); ok {
 			if strLit, ok := exprStmt.Expression.(*ast.StringLiteral); ok {
@@ -2089,6 +2648,74 @@ func (p *Parser) parseFunctionDefinition() ast.Statement {
 	return stmt
 }
 
+// popGeneratorScope ends the innermost spell body and reports whether it
+// contained a yield statement.
+func (p *Parser) popGeneratorScope() bool {
+	last := len(p.generatorScopes) - 1
+	isGenerator := p.generatorScopes[last]
+	p.generatorScopes = p.generatorScopes[:last]
+	return isGenerator
+}
+
+func (p *Parser) parseYieldStatement() ast.Statement {
+	stmt := &ast.YieldStatement{Token: p.currToken}
+
+	if len(p.generatorScopes) == 0 {
+		p.addError("'yield' outside of a spell")
+	} else {
+		p.generatorScopes[len(p.generatorScopes)-1] = true
+	}
+
+	if p.peekTokenIs(token.NEWLINE) || p.peekTokenIs(token.EOF) ||
+		p.peekTokenIs(token.DEDENT) || p.peekTokenIs(token.SEMICOLON) {
+		return stmt
+	}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.NEWLINE) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseDecoratedDefinition parses one or more `@decorator` lines followed
+// by the spell definition they apply to. Each decorator is an arbitrary
+// expression, so both `@memoize` and `@route("GET", "/users")` are valid.
+func (p *Parser) parseDecoratedDefinition() ast.Statement {
+	decorators := []ast.Expression{}
+
+	for p.currTokenIs(token.AT) {
+		p.nextToken()
+		decorator := p.parseExpression(LOWEST)
+		if decorator == nil {
+			return nil
+		}
+		decorators = append(decorators, decorator)
+
+		if !p.expectPeek(token.NEWLINE) {
+			return nil
+		}
+		for p.peekTokenIs(token.NEWLINE) {
+			p.nextToken()
+		}
+		p.nextToken()
+	}
+
+	if !p.currTokenIs(token.SPELL) && !p.currTokenIs(token.INIT) {
+		p.addError(fmt.Sprintf("decorators must be followed by a spell definition, got %s", p.currToken.Type))
+		return nil
+	}
+
+	stmt, ok := p.parseFunctionDefinition().(*ast.FunctionDefinition)
+	if !ok || stmt == nil {
+		return nil
+	}
+	stmt.Decorators = decorators
+	return stmt
+}
+
 func (p *Parser) parseFunctionParameters() []ast.Expression {
 	p.parsingParameters = true
 	defer func() {
@@ -2104,15 +2731,56 @@ func (p *Parser) parseFunctionParameters() []ast.Expression {
 
 	p.nextToken()
 
-	param := &ast.Parameter{
-		Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal},
+	param := p.parseFunctionParameter()
+	if param == nil {
+		return []ast.Expression{}
+	}
+	parameters = append(parameters, param)
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if p.peekTokenIs(token.RPAREN) {
+			break // trailing comma
+		}
+		p.nextToken()
+
+		param := p.parseFunctionParameter()
+		if param == nil {
+			return []ast.Expression{}
+		}
+		parameters = append(parameters, param)
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		// Error already added by expectPeek, return empty slice
+		return []ast.Expression{}
 	}
 
+	if !p.checkVariadicParameters(parameters) {
+		return []ast.Expression{}
+	}
+
+	return parameters
+}
+
+// parseFunctionParameter parses one parameter: `name`, `name: type`,
+// `name = default`, `*args` or `**kwargs`.
+func (p *Parser) parseFunctionParameter() ast.Expression {
+	param := &ast.Parameter{}
+	if p.currTokenIs(token.ASTERISK) || p.currTokenIs(token.EXPONENT) {
+		param.Variadic = p.currTokenIs(token.ASTERISK)
+		param.KwVariadic = !param.Variadic
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+	}
+	param.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken()
 		if !p.expectPeek(token.IDENT) {
-			// Error already added by expectPeek, return empty slice
-			return []ast.Expression{}
+			// Error already added by expectPeek
+			return nil
 		}
 		param.TypeHint = &ast.Identifier{
 			Token: p.currToken,
@@ -2121,58 +2789,100 @@ func (p *Parser) parseFunctionParameters() []ast.Expression {
 	}
 
 	if p.peekTokenIs(token.ASSIGN) {
+		if param.Variadic || param.KwVariadic {
+			p.addError(fmt.Sprintf("variadic parameter '%s' cannot have a default value", param.Name.Value))
+			return nil
+		}
 		p.nextToken()
 		p.nextToken()
 		param.DefaultValue = p.parseExpression(LOWEST)
 	}
 
-	// Append identifier for simple params, otherwise full Parameter node
-	if param.TypeHint == nil && param.DefaultValue == nil {
-		parameters = append(parameters, param.Name)
-	} else {
-		parameters = append(parameters, param)
+	// Identifier for simple params, otherwise full Parameter node
+	if param.TypeHint == nil && param.DefaultValue == nil && !param.Variadic && !param.KwVariadic {
+		return param.Name
 	}
+	return param
+}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
-
-		param := &ast.Parameter{
-			Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal},
+// checkVariadicParameters allows at most one *args and one **kwargs, with
+// **kwargs always last. Parameters between them are keyword-only.
+func (p *Parser) checkVariadicParameters(parameters []ast.Expression) bool {
+	seenVariadic := false
+	for i, pExpr := range parameters {
+		param, ok := pExpr.(*ast.Parameter)
+		if !ok {
+			continue
 		}
-
-		if p.peekTokenIs(token.COLON) {
-			p.nextToken()
-			if !p.expectPeek(token.IDENT) {
-				// Error already added by expectPeek, return empty slice
-				return []ast.Expression{}
-			}
-			param.TypeHint = &ast.Identifier{
-				Token: p.currToken,
-				Value: p.currToken.Literal,
+		if param.Variadic {
+			if seenVariadic {
+				p.addError("only one *args parameter is allowed")
+				return false
 			}
+			seenVariadic = true
 		}
-
-		if p.peekTokenIs(token.ASSIGN) {
-			p.nextToken()
-			p.nextToken()
-			param.DefaultValue = p.parseExpression(LOWEST)
+		if param.KwVariadic && i != len(parameters)-1 {
+			p.addError(fmt.Sprintf("**%s must be the last parameter", param.Name.Value))
+			return false
 		}
+	}
+	return true
+}
 
-		// Append identifier for simple params, otherwise full Parameter node
-		if param.TypeHint == nil && param.DefaultValue == nil {
-			parameters = append(parameters, param.Name)
-		} else {
-			parameters = append(parameters, param)
-		}
+// parseFunctionLiteral parses an anonymous spell expression. The body is
+// either a single expression on the same line, which is implicitly
+// returned, or an indented block:
+//
+//	double = spell(x): x * 2
+//	handler = spell(req):
+//	    return req.path
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.currToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		// Error already added by expectPeek, return empty slice
-		return []ast.Expression{}
+	lit.Parameters = p.parseFunctionParameters()
+	p.parsingParameters = false
+
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		p.nextToken()
+		lit.ReturnType = p.parseExpression(LOWEST)
 	}
 
-	return parameters
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.NEWLINE) {
+		p.nextToken()
+		if !p.peekTokenIs(token.INDENT) {
+			p.addError("expected indented block or expression after anonymous spell")
+			return nil
+		}
+		p.nextToken() // Move to INDENT token
+		p.nextToken() // Move past INDENT to first statement token
+		p.generatorScopes = append(p.generatorScopes, false)
+		lit.Body = p.parseBlockStatement()
+		lit.IsGenerator = p.popGeneratorScope()
+		return lit
+	}
+
+	p.nextToken()
+	bodyToken := p.currToken
+	value := p.parseExpression(LOWEST)
+	if value == nil {
+		return nil
+	}
+	lit.Body = &ast.BlockStatement{
+		Token: bodyToken,
+		Statements: []ast.Statement{
+			&ast.ReturnStatement{Token: bodyToken, ReturnValue: value},
+		},
+	}
+	return lit
 }
 
 func (p *Parser) parseWhileStatement() ast.Statement {
@@ -2291,14 +3001,23 @@ func (p *Parser) parseGrimoireDefinition() ast.Statement {
 					}
 				}
 
-				if fnDef, ok := s.(*ast.FunctionDefinition); ok {
-					if fnDef.Name.Value == "init" {
-						stmt.InitMethod = fnDef
+				switch s := s.(type) {
+				case *ast.FunctionDefinition:
+					if s.Name.Value == "init" {
+						stmt.InitMethod = s
 					} else {
-						stmt.Methods = append(stmt.Methods, fnDef)
+						stmt.Methods = append(stmt.Methods, s)
+					}
+				case *ast.AssignStatement:
+					// Enum members: NAME = value
+					if name, ok := s.Name.(*ast.Identifier); ok && s.Operator == "=" && s.TypeHint == nil {
+						stmt.Members = append(stmt.Members, &ast.EnumMember{Name: name, Value: s.Value})
+					}
+				case *ast.ExpressionStatement:
+					// Enum members: a bare NAME
+					if name, ok := s.Expression.(*ast.Identifier); ok {
+						stmt.Members = append(stmt.Members, &ast.EnumMember{Name: name})
 					}
-				} else {
-					continue
 				}
 			}
 		}
@@ -2619,24 +3338,23 @@ func (p *Parser) parseGlobalStatement() ast.Statement {
 func (p *Parser) parseWithStatement() ast.Statement {
 	stmt := &ast.WithStatement{Token: p.currToken}
 
-	// autoclose <expression> as <variable>:
-	if !p.expectPeek(token.IDENT) && !p.currTokenIs(token.LPAREN) {
-		return nil
-	}
+	// autoclose <expression> [as <variable>]:
+	p.nextToken()
 
-	// Parse the expression (e.g., open("file.txt", "r"))
+	// Parse the expression (e.g., open("file.txt", "r") or self.lock.lock())
 	stmt.Expression = p.parseExpression(LOWEST)
-
-	// Expect 'as'
-	if !p.expectPeek(token.AS) {
+	if stmt.Expression == nil {
 		return nil
 	}
 
-	// Parse the variable name
-	if !p.expectPeek(token.IDENT) {
-		return nil
+	// The 'as' binding is optional, e.g. for lock guards
+	if p.peekTokenIs(token.AS) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Variable = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 	}
-	stmt.Variable = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 
 	// Expect colon
 	if !p.expectPeek(token.COLON) {
@@ -117,7 +117,7 @@ func Start(in io.Reader, out io.Writer, env *object.Environment) {
 				"year", "month", "day", "hour", "minute", "second", "weekday", "yearday",
 			},
 			"socket": {
-				"new_socket", "client", "server", "socket_send", "socket_receive", "socket_close", 
+				"new_socket", "client", "server", "socket_send", "socket_receive", "socket_receive_bytes", "socket_close", 
 				"socket_listen", "socket_accept", "socket_set_timeout", "socket_get_info",
 				"socket_send_to", "socket_receive_from",
 			},
@@ -23,6 +23,8 @@ const (
 	IDENT     TokenType = "IDENT"
 	INT       TokenType = "INT"
 	FLOAT     TokenType = "FLOAT"
+	DECIMAL   TokenType = "DECIMAL"
+	BYTES     TokenType = "BYTES"
 	STRING    TokenType = "STRING"
 	DOCSTRING TokenType = "DOCSTRING"
 
@@ -117,6 +119,8 @@ const (
 	AUTOCLOSE   TokenType = "AUTOCLOSE"
 	DIVERGE     TokenType = "DIVERGE"
 	CONVERGE    TokenType = "CONVERGE"
+	SELECT      TokenType = "SELECT"
+	YIELD       TokenType = "YIELD"
 )
 
 var keywords = map[string]TokenType{
@@ -158,6 +162,8 @@ var keywords = map[string]TokenType{
 	"autoclose":   AUTOCLOSE,
 	"diverge":     DIVERGE,
 	"converge":    CONVERGE,
+	"select":      SELECT,
+	"yield":       YIELD,
 	//"range":     RANGE,
 	"None": NONE,
 }